@@ -0,0 +1,68 @@
+package announcement
+
+import (
+	"context"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// SegmentFilter matches the active users in segment, whether or not they opted in to announcements
+func SegmentFilter(segment models.AnnouncementSegment) bson.M {
+	var (
+		defaults   = models.DefaultPreferences()
+		conditions = []bson.M{{
+			"deleted_at":  bson.M{"$exists": false},
+			"disabled_at": bson.M{"$exists": false},
+		}}
+	)
+	if len(segment.CardIDs) > 0 {
+		conditions = append(conditions, bson.M{"cards._id": bson.M{"$in": segment.CardIDs}})
+	}
+	if segment.Verified != nil {
+		conditions = append(conditions, bson.M{"verified": *segment.Verified})
+	}
+	if len(segment.Timezones) > 0 {
+		conditions = append(conditions, withDefault(bson.M{"preferences.timezone": bson.M{"$in": segment.Timezones}},
+			slices.Contains(segment.Timezones, defaults.Timezone)))
+	}
+	return bson.M{"$and": conditions}
+}
+
+// RecipientFilter matches the users in segment an announcement is delivered to: those who opted in to
+// product updates, or everyone in the segment for a service notice
+func RecipientFilter(segment models.AnnouncementSegment, serviceNotice bool) bson.M {
+	filter := SegmentFilter(segment)
+	if serviceNotice {
+		return filter
+	}
+	filter["$and"] = append(filter["$and"].([]bson.M), withDefault(
+		bson.M{"preferences.notifications.product_updates": true},
+		models.DefaultPreferences().Notifications.ProductUpdates))
+	return filter
+}
+
+// Preview counts the users in segment, and how many of them an announcement, or a service notice, would be
+// delivered to
+func Preview(ctx context.Context, users *mongo.Collection, segment models.AnnouncementSegment,
+	serviceNotice bool) (matching, recipients int64, err error) {
+	if matching, err = users.CountDocuments(ctx, SegmentFilter(segment)); err != nil {
+		return 0, 0, err
+	}
+	if recipients, err = users.CountDocuments(ctx, RecipientFilter(segment, serviceNotice)); err != nil {
+		return 0, 0, err
+	}
+	return matching, recipients, nil
+}
+
+// withDefault extends condition on a preference to the users who never saved preferences, if the
+// default preferences meet it
+func withDefault(condition bson.M, defaultMatches bool) bson.M {
+	if !defaultMatches {
+		return condition
+	}
+	return bson.M{"$or": []bson.M{condition, {"preferences": bson.M{"$exists": false}}}}
+}
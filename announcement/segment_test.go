@@ -0,0 +1,105 @@
+package announcement
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/models"
+)
+
+var (
+	optIn         = bson.M{"preferences.notifications.product_updates": true}
+	noPreferences = bson.M{"preferences": bson.M{"$exists": false}}
+)
+
+func TestRecipientFilterOptIn(t *testing.T) {
+	segment := models.AnnouncementSegment{CardIDs: []string{"card-1"}}
+	cardHolders := bson.M{"cards._id": bson.M{"$in": []string{"card-1"}}}
+
+	// product updates are off by default, so users who never saved preferences get no announcements
+	clauses := and(t, RecipientFilter(segment, false))
+	if !slices.ContainsFunc(clauses, equal(optIn)) {
+		t.Errorf("announcement filter %v does not require the product updates opt-in", clauses)
+	}
+	if slices.ContainsFunc(clauses, equal(bson.M{"$or": []bson.M{optIn, noPreferences}})) {
+		t.Errorf("announcement filter %v includes users who never saved preferences", clauses)
+	}
+	if !slices.ContainsFunc(clauses, equal(cardHolders)) {
+		t.Errorf("announcement filter %v does not keep the segment", clauses)
+	}
+
+	clauses = and(t, RecipientFilter(segment, true))
+	if !reflect.DeepEqual(clauses, and(t, SegmentFilter(segment))) {
+		t.Errorf("service notice filter %v is not the segment filter", clauses)
+	}
+	if !slices.ContainsFunc(clauses, equal(cardHolders)) {
+		t.Errorf("service notice filter %v does not keep the segment", clauses)
+	}
+}
+
+func TestSegmentFilterTimezones(t *testing.T) {
+	var (
+		defaultTimezone = models.DefaultPreferences().Timezone
+		inDefault       = []string{defaultTimezone, "Asia/Kuala_Lumpur"}
+		elsewhere       = []string{"Europe/London"}
+	)
+	for _, tc := range []struct {
+		timezones []string
+		want      bson.M
+	}{
+		// users who never saved preferences are in the default timezone's region only
+		{timezones: inDefault, want: bson.M{"$or": []bson.M{
+			{"preferences.timezone": bson.M{"$in": inDefault}}, noPreferences,
+		}}},
+		{timezones: elsewhere, want: bson.M{"preferences.timezone": bson.M{"$in": elsewhere}}},
+	} {
+		clauses := and(t, SegmentFilter(models.AnnouncementSegment{Timezones: tc.timezones}))
+		if !slices.ContainsFunc(clauses, equal(tc.want)) {
+			t.Errorf("timezones %v: filter %v has no clause %v", tc.timezones, clauses, tc.want)
+		}
+	}
+}
+
+func TestWithDefault(t *testing.T) {
+	condition := bson.M{"preferences.locale": "en"}
+	if got := withDefault(condition, false); !reflect.DeepEqual(got, condition) {
+		t.Errorf("withDefault when the default does not match = %v, want only users with saved preferences", got)
+	}
+	want := bson.M{"$or": []bson.M{condition, noPreferences}}
+	if got := withDefault(condition, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("withDefault when the default matches = %v, want %v", got, want)
+	}
+}
+
+// and returns the clauses of a filter that is a single $and
+func and(t *testing.T, filter bson.M) []bson.M {
+	t.Helper()
+	clauses, ok := filter["$and"].([]bson.M)
+	if !ok || len(filter) != 1 {
+		t.Fatalf("filter %v is not a single $and", filter)
+	}
+	return clauses
+}
+
+func equal(want bson.M) func(bson.M) bool {
+	return func(clause bson.M) bool {
+		return reflect.DeepEqual(clause, want)
+	}
+}
+
+func TestServiceNoticeIgnoresProductUpdatesOptOut(t *testing.T) {
+	prefs := models.DefaultPreferences().Notifications
+	if prefs.ProductUpdates {
+		t.Fatal("product updates are expected to be off by default")
+	}
+	channels := prefs.ChannelsFor(models.NotificationServiceNotice.Category())
+	if !channels.InApp && !channels.Email && !channels.Push {
+		t.Error("a service notice is not delivered to a user who did not opt in to product updates")
+	}
+	if channels := prefs.ChannelsFor(models.NotificationAnnouncement.Category()); channels.InApp || channels.Email {
+		t.Error("an announcement is delivered to a user who did not opt in to product updates")
+	}
+}
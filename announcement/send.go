@@ -0,0 +1,179 @@
+package announcement
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+)
+
+// lease is how long an instance has to send a batch before another may take over
+const lease = 5 * time.Minute
+
+// SendDue sends the next batch of config.AnnouncementBatchSize users of every announcement that is due.
+// Each announcement is leased while its batch is sent, so instances running SendDue at once share the
+// announcements between them rather than notifying anyone twice.
+func SendDue(ctx context.Context, collections *data.Collections, notifier *notify.Notifier) {
+	var (
+		log   = slog.With("func", "SendDue")
+		owner = uuid.New().String()
+		done  []string // batches sent this run, the next ones wait for the next run
+	)
+	for {
+		announcement, err := claim(ctx, collections.Announcements, owner, done)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Error("Failed to claim announcement", "err", err)
+			return
+		}
+		done = append(done, announcement.ID)
+
+		if err = sendBatch(ctx, collections, notifier, owner, announcement); err != nil {
+			log.Error("Failed to send announcement batch", "err", err, "announcementID", announcement.ID)
+		}
+	}
+}
+
+// claim leases the next announcement that is due and not leased by another instance, except those in skip
+func claim(ctx context.Context, announcements *mongo.Collection, owner string,
+	skip []string) (models.Announcement, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":     bson.M{"$nin": skip},
+		"status":  bson.M{"$in": []models.AnnouncementStatus{models.AnnouncementScheduled, models.AnnouncementSending}},
+		"send_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"lease_expires_at": bson.M{"$exists": false}},
+			{"lease_expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           models.AnnouncementSending,
+			"lease_owner":      owner,
+			"lease_expires_at": now.Add(lease),
+		},
+		"$min": bson.M{"started_at": now},
+	}
+	var announcement models.Announcement
+	err := announcements.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "send_at", Value: 1}}).SetReturnDocument(options.After)).
+		Decode(&announcement)
+	return announcement, err
+}
+
+// sendBatch notifies the next users of announcement and records the progress, completing the announcement
+// once its segment is exhausted. A user whose notification fails is retried with the next batch, the
+// announcement's DedupKey keeps those notified before them from being notified again.
+func sendBatch(ctx context.Context, collections *data.Collections, notifier *notify.Notifier, owner string,
+	announcement models.Announcement) error {
+	log := slog.With("func", "SendDue", "announcementID", announcement.ID)
+
+	var (
+		set    = bson.M{}
+		filter = RecipientFilter(announcement.Segment, announcement.ServiceNotice)
+	)
+	if announcement.Processed == 0 && announcement.Cursor == "" {
+		recipients, err := collections.Users.CountDocuments(ctx, filter)
+		if err != nil {
+			return release(ctx, collections.Announcements, owner, announcement, set, 0, err)
+		}
+		set["recipients"] = recipients
+	}
+
+	if announcement.Cursor != "" {
+		filter["$and"] = append(filter["$and"].([]bson.M), bson.M{"_id": bson.M{"$gt": announcement.Cursor}})
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(config.AnnouncementBatchSize)).
+		SetProjection(bson.M{"first_name": 1, "email": 1, "preferences": 1})
+	cursor, err := collections.Users.Find(ctx, filter, opts)
+	if err != nil {
+		return release(ctx, collections.Announcements, owner, announcement, set, 0, err)
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return release(ctx, collections.Announcements, owner, announcement, set, 0, err)
+	}
+
+	processed := 0
+	for _, user := range users {
+		if err = notifier.Notify(ctx, user, message(announcement, user)); err != nil {
+			return release(ctx, collections.Announcements, owner, announcement, set, processed, err)
+		}
+		set["cursor"] = user.ID
+		processed++
+	}
+
+	if len(users) < config.AnnouncementBatchSize {
+		set["status"] = models.AnnouncementSent
+		set["completed_at"] = time.Now()
+		log.Info("Announcement sent", "processed", announcement.Processed+processed)
+	}
+	return release(ctx, collections.Announcements, owner, announcement, set, processed, nil)
+}
+
+// release records the progress of a batch and hands the announcement back, returning cause. The update
+// only applies while the announcement is still leased to owner and sending, so a cancellation sticks.
+func release(ctx context.Context, announcements *mongo.Collection, owner string, announcement models.Announcement,
+	set bson.M, processed int, cause error) error {
+	update := bson.M{
+		"$inc":   bson.M{"processed": processed},
+		"$unset": bson.M{"lease_owner": "", "lease_expires_at": ""},
+	}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	filter := bson.M{"_id": announcement.ID, "lease_owner": owner, "status": models.AnnouncementSending}
+	if _, err := announcements.UpdateOne(ctx, filter, update); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// message is the notification of announcement for user
+func message(announcement models.Announcement, user models.User) notify.Message {
+	notificationType := models.NotificationAnnouncement
+	if announcement.ServiceNotice {
+		notificationType = models.NotificationServiceNotice
+	}
+	return notify.Message{
+		Type:     notificationType,
+		Title:    announcement.Title,
+		Body:     announcement.Body,
+		Data:     map[string]string{"announcement_id": announcement.ID},
+		Template: email.TemplateAnnouncement,
+		TemplateData: email.AnnouncementData{
+			Name:       user.FirstName,
+			Title:      announcement.Title,
+			Paragraphs: paragraphs(announcement.Body),
+		},
+		DedupKey: "announcement:" + announcement.ID,
+	}
+}
+
+// paragraphs splits body at blank lines, dropping empty paragraphs
+func paragraphs(body string) []string {
+	var results []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			results = append(results, paragraph)
+		}
+	}
+	return results
+}
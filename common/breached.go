@@ -0,0 +1,79 @@
+package common
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// hashPrefixLength is the number of leading hex characters used to bucket hashes,
+// matching the k-anonymity range API of Have I Been Pwned
+const hashPrefixLength = 5
+
+// BreachedPasswords is an in-memory set of SHA-1 password hashes, bucketed by hash prefix
+type BreachedPasswords struct {
+	buckets map[string][]string // prefix -> sorted suffixes
+	count   int
+}
+
+// LoadBreachedPasswords loads a breached password list from a local file.
+// Each line holds an uppercase or lowercase SHA-1 hex hash, optionally followed by ":count"
+// as in the Pwned Passwords downloads. Blank lines and lines starting with # are ignored.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &BreachedPasswords{buckets: make(map[string][]string)}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err = hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 hash %q", lineNo, hash)
+		}
+		b.add(hash)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for prefix := range b.buckets {
+		sort.Strings(b.buckets[prefix])
+	}
+	return b, nil
+}
+
+func (b *BreachedPasswords) add(hash string) {
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+	b.buckets[prefix] = append(b.buckets[prefix], suffix)
+	b.count++
+}
+
+// Len returns the number of hashes loaded
+func (b *BreachedPasswords) Len() int {
+	return b.count
+}
+
+// Contains reports whether password appears in the breached list
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+
+	bucket := b.buckets[prefix]
+	i := sort.SearchStrings(bucket, suffix)
+	return i < len(bucket) && bucket[i] == suffix
+}
@@ -5,9 +5,26 @@ const (
 	Username = "username"
 	Password = "password"
 	Email    = "email"
+	Currency = "currency"
+	Timezone = "timezone"
+	Locale   = "locale"
+
+	WebhookEvent = "webhook_event"
 
 	Issuer = "www.moneyfly.io"
 
 	UserID    = "userID"
 	RequestID = "requestID"
+
+	// ImpersonatorID is set on the request context to the admin's ID when a session token is an impersonation
+	ImpersonatorID = "impersonatorID"
+
+	// ImpersonatedByHeader marks every response to an impersonation session with the admin's ID
+	ImpersonatedByHeader = "X-Impersonated-By"
+
+	// Household is set on the request context to the models.Household loaded by middleware.HouseholdRole
+	Household = "household"
+
+	// DevUserHeader carries a user ID to act as when common.DevAuthEnabled
+	DevUserHeader = "X-Dev-User"
 )
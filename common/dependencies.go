@@ -21,6 +21,13 @@ func SetDependencies() {
 	log.Info("Setting dependencies first..")
 	config.InitEnvVar()
 	config.LoadECDSAKey()
+	config.LoadVAPIDKey()
+	checkDevAuth()
+	checkMailDriver()
+	checkWebhookInsecure()
+	checkPushInsecure()
+	initPasswordHasher()
+	initPasswordPolicy()
 	initValidators()
 	initLogger()
 }
@@ -49,3 +56,72 @@ func GetMode() string {
 	log.Warn("undefined env, defaulting to development")
 	return Development
 }
+
+// DevAuthEnabled reports whether development identities are accepted by middleware.Auth.
+// It requires MODE to be explicitly set to development, an unset MODE never enables it.
+func DevAuthEnabled() bool {
+	return os.Getenv("MODE") == Development && config.DevAuth
+}
+
+// DevMailboxEnabled reports whether emails are captured for the /dev/mailbox API instead of being sent.
+// Like DevAuthEnabled, it requires MODE to be explicitly set to development.
+func DevMailboxEnabled() bool {
+	return os.Getenv("MODE") == Development && config.MailDriver == config.MailDriverMailbox
+}
+
+// checkMailDriver refuses to start the service unless emails are really sent: outside of development the
+// log, file and mailbox drivers are not allowed, so SMTP must be configured
+func checkMailDriver() {
+	if config.MailDriver == config.MailDriverSMTP {
+		if config.SMTPHost == "" {
+			log.Error("SMTP_HOST is required for MAIL_DRIVER=smtp")
+			panic("smtp mail driver selected without SMTP_HOST")
+		}
+		return
+	}
+	if mode := os.Getenv("MODE"); mode != Development {
+		log.Error("MAIL_DRIVER must be smtp unless MODE=development", "driver", config.MailDriver, "mode", mode)
+		panic("development mail driver enabled outside of development")
+	}
+	if config.MailDriver == config.MailDriverMailbox {
+		log.Warn("Emails are captured in the development mailbox instead of being sent")
+		return
+	}
+	log.Warn("Emails are not sent", "driver", config.MailDriver)
+}
+
+// checkWebhookInsecure refuses to start the service if WEBHOOK_ALLOW_INSECURE is set outside of development
+func checkWebhookInsecure() {
+	if !config.WebhookAllowInsecure {
+		return
+	}
+	if mode := os.Getenv("MODE"); mode != Development {
+		log.Error("WEBHOOK_ALLOW_INSECURE is only allowed when MODE=development", "mode", mode)
+		panic("insecure webhooks enabled outside of development")
+	}
+	log.Warn("Webhooks may use http:// endpoints and private network addresses")
+}
+
+// checkPushInsecure refuses to start the service if PUSH_ALLOW_INSECURE is set outside of development
+func checkPushInsecure() {
+	if !config.PushAllowInsecure {
+		return
+	}
+	if mode := os.Getenv("MODE"); mode != Development {
+		log.Error("PUSH_ALLOW_INSECURE is only allowed when MODE=development", "mode", mode)
+		panic("insecure push endpoints enabled outside of development")
+	}
+	log.Warn("Push subscriptions may use http:// endpoints and private network addresses")
+}
+
+// checkDevAuth refuses to start the service if DEV_AUTH is set outside of development
+func checkDevAuth() {
+	if !config.DevAuth {
+		return
+	}
+	if mode := os.Getenv("MODE"); mode != Development {
+		log.Error("DEV_AUTH is only allowed when MODE=development", "mode", mode)
+		panic("DEV_AUTH enabled outside of development")
+	}
+	log.Warn("DEV_AUTH enabled, requests may authenticate with the " + DevUserHeader + " header")
+}
@@ -0,0 +1,251 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	log "log/slog"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// Password hash algorithms supported by PasswordHasher
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	// argon2MaxMemoryKiB bounds the memory of configured and stored parameters alike, so neither a typo
+	// nor a tampered hash can make a single login allocate without limit
+	argon2MaxMemoryKiB = 4 * 1024 * 1024 // 4 GiB
+)
+
+// ErrUnknownPasswordHash is returned when a stored hash is in a format no PasswordHasher recognises
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher creates and verifies password hashes for one algorithm.
+// Hashes are self-describing: the encoded string carries the algorithm, version and parameters,
+// so hashes created with older settings keep verifying after the configuration changes.
+type PasswordHasher interface {
+	// Hash returns an encoded hash of password using the hasher's current parameters
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash
+	Verify(encoded, password string) (bool, error)
+	// Owns reports whether encoded was produced by this hasher's algorithm
+	Owns(encoded string) bool
+	// NeedsRehash reports whether encoded was produced with parameters other than the current ones
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	passwordHashers = []PasswordHasher{
+		&Argon2idHasher{MemoryKiB: 64 * 1024, Iterations: 3, Parallelism: 2},
+		&BcryptHasher{Cost: bcrypt.DefaultCost},
+	}
+	currentHasher = passwordHashers[0]
+)
+
+// initPasswordHasher refuses to start the service with hashing parameters that are out of range, which would
+// otherwise wrap around, panic on every hash or silently fall back to other parameters
+func initPasswordHasher() {
+	cfg := config.PasswordHash
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		log.Error("BCRYPT_COST is out of range", "cost", cfg.BcryptCost, "min", bcrypt.MinCost,
+			"max", bcrypt.MaxCost)
+		panic("invalid BCRYPT_COST")
+	}
+	if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > math.MaxUint8 ||
+		cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > math.MaxUint32 ||
+		cfg.Argon2MemoryKiB < 1 || cfg.Argon2MemoryKiB > argon2MaxMemoryKiB {
+		log.Error("argon2id parameters are out of range", "memoryKiB", cfg.Argon2MemoryKiB,
+			"iterations", cfg.Argon2Iterations, "parallelism", cfg.Argon2Parallelism)
+		panic("invalid ARGON2_MEMORY_KIB, ARGON2_ITERATIONS or ARGON2_PARALLELISM")
+	}
+	argon2idHasher := &Argon2idHasher{
+		MemoryKiB:   uint32(cfg.Argon2MemoryKiB),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+	}
+	if err := argon2idHasher.validate(); err != nil {
+		log.Error("argon2id parameters are out of range", "err", err)
+		panic("invalid ARGON2_MEMORY_KIB, ARGON2_ITERATIONS or ARGON2_PARALLELISM")
+	}
+	bcryptHasher := &BcryptHasher{Cost: cfg.BcryptCost}
+	passwordHashers = []PasswordHasher{argon2idHasher, bcryptHasher}
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		currentHasher = bcryptHasher
+	case AlgorithmArgon2id:
+		currentHasher = argon2idHasher
+	default:
+		log.Warn("unknown PASSWORD_HASH_ALGORITHM, defaulting to argon2id", "algorithm", cfg.Algorithm)
+		currentHasher = argon2idHasher
+	}
+}
+
+// HashPassword hashes password with the currently configured algorithm
+func HashPassword(password string) (string, error) {
+	return currentHasher.Hash(password)
+}
+
+// VerifyPassword checks password against a stored hash of any supported algorithm.
+// needsRehash is true when the password matched but the hash is not in the current algorithm
+// or parameters, in which case the caller should store a fresh HashPassword result.
+func VerifyPassword(encoded, password string) (ok bool, needsRehash bool, err error) {
+	for _, hasher := range passwordHashers {
+		if !hasher.Owns(encoded) {
+			continue
+		}
+		if ok, err = hasher.Verify(encoded, password); err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher != currentHasher || hasher.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+// passwordMaxBytes returns the longest password in bytes the current algorithm can hash, 0 if unbounded
+func passwordMaxBytes() int {
+	if _, ok := currentHasher.(*BcryptHasher); ok {
+		return bcryptMaxBytes
+	}
+	return 0
+}
+
+// BcryptHasher hashes passwords with bcrypt, e.g. $2a$12$<salt+hash>
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash implements PasswordHasher
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(hash), err
+}
+
+// Verify implements PasswordHasher
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Owns implements PasswordHasher
+func (h *BcryptHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash implements PasswordHasher
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id in the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Hash implements PasswordHasher
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.MemoryKiB, h.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.MemoryKiB, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify implements PasswordHasher
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism,
+		uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Owns implements PasswordHasher
+func (h *Argon2idHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash implements PasswordHasher
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || *params != *h
+}
+
+// validate checks the parameters are ones argon2.IDKey accepts as given, and within argon2MaxMemoryKiB
+func (h *Argon2idHasher) validate() error {
+	switch {
+	case h.Parallelism < 1:
+		return errors.New("argon2id parallelism must be at least 1")
+	case h.Iterations < 1:
+		return errors.New("argon2id iterations must be at least 1")
+	case h.MemoryKiB < 8*uint32(h.Parallelism):
+		return fmt.Errorf("argon2id memory must be at least %d KiB for parallelism %d",
+			8*uint32(h.Parallelism), h.Parallelism)
+	case h.MemoryKiB > argon2MaxMemoryKiB:
+		return fmt.Errorf("argon2id memory must be at most %d KiB", argon2MaxMemoryKiB)
+	}
+	return nil
+}
+
+func decodeArgon2id(encoded string) (params *Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, fmt.Errorf("argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	params = &Argon2idHasher{}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d",
+		&params.MemoryKiB, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("argon2id params: %w", err)
+	}
+	if err = params.validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, nil, nil, fmt.Errorf("argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, nil, nil, fmt.Errorf("argon2id key: %w", err)
+	}
+	if len(salt) == 0 || len(key) == 0 {
+		return nil, nil, nil, errors.New("argon2id salt and key must not be empty")
+	}
+	return params, salt, key, nil
+}
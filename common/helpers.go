@@ -3,6 +3,9 @@ package common
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
 )
 
 // GenerateOTP generates a random verification token
@@ -13,3 +16,19 @@ func GenerateOTP() string {
 	// Ensure the OTP is exactly 8 digits long
 	return fmt.Sprintf("%08d", otp)
 }
+
+// PaginationParams reads ?page= (from 1) and ?limit=, falling back to page 1 and defaultLimit, capped at maxLimit
+func PaginationParams(c *gin.Context, defaultLimit, maxLimit int) (page, limit int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned when a host resolves to an address on a private network
+var ErrPrivateAddress = errors.New("host resolves to a private network address")
+
+// RefusePrivateAddresses is a net.Dialer Control function for requests to URLs that users supply, such as
+// webhooks and push endpoints. It refuses to connect to loopback, private, link-local and multicast addresses.
+func RefusePrivateAddresses(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
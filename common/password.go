@@ -0,0 +1,210 @@
+package common
+
+import (
+	"fmt"
+	log "log/slog"
+	"math"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// bcryptMaxBytes is the number of bytes bcrypt considers; anything longer is rejected by the hasher
+const bcryptMaxBytes = 72
+
+// Password policy rule names, returned to the client alongside each violation message
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleMaxBytes  = "max_bytes"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleStrength  = "strength"
+	RuleBreached  = "breached"
+)
+
+// PasswordViolation is a single password policy rule that a password failed
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy is a configurable set of rules that new passwords must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	MaxBytes      int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	MinScore      int
+	Breached      *BreachedPasswords
+}
+
+var passwordPolicy = &PasswordPolicy{MinLength: 8, MaxLength: 128, MinScore: 2}
+
+func initPasswordPolicy() {
+	cfg := config.PasswordPolicy
+	passwordPolicy = &PasswordPolicy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		MaxBytes:      passwordMaxBytes(),
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		MinScore:      cfg.MinScore,
+	}
+
+	if cfg.BreachedPasswordsFile == "" {
+		log.Warn("BREACHED_PASSWORDS_FILE not set, skipping breached password screening")
+		return
+	}
+	// an operator who configured a list expects screening, so starting without it would fail open
+	breached, err := LoadBreachedPasswords(cfg.BreachedPasswordsFile)
+	if err != nil {
+		log.Error("failed to load breached passwords file", "err", err, "file", cfg.BreachedPasswordsFile)
+		panic("breached passwords file could not be loaded")
+	}
+	passwordPolicy.Breached = breached
+	log.Info("Loaded breached passwords list", "hashes", breached.Len())
+}
+
+// CheckPassword checks password against the configured policy and returns every rule it fails
+func CheckPassword(password string) []PasswordViolation {
+	return passwordPolicy.Check(password)
+}
+
+// Check returns every rule in the policy that password fails, or nil if it passes
+func (p *PasswordPolicy) Check(password string) []PasswordViolation {
+	var (
+		violations []PasswordViolation
+		length     = utf8.RuneCountInString(password)
+	)
+
+	if length < p.MinLength {
+		violations = append(violations, PasswordViolation{RuleMinLength,
+			fmt.Sprintf("Password must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, PasswordViolation{RuleMaxLength,
+			fmt.Sprintf("Password must be at most %d characters long", p.MaxLength)})
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, PasswordViolation{RuleMaxBytes,
+			fmt.Sprintf("Password must be at most %d bytes long", p.MaxBytes)})
+	}
+
+	classes := passwordClasses(password)
+	if p.RequireUpper && !classes.upper {
+		violations = append(violations, PasswordViolation{RuleUpper,
+			"Password must contain an uppercase letter"})
+	}
+	if p.RequireLower && !classes.lower {
+		violations = append(violations, PasswordViolation{RuleLower,
+			"Password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !classes.digit {
+		violations = append(violations, PasswordViolation{RuleDigit,
+			"Password must contain a digit"})
+	}
+	if p.RequireSymbol && !classes.symbol {
+		violations = append(violations, PasswordViolation{RuleSymbol,
+			"Password must contain a symbol"})
+	}
+
+	if PasswordScore(password) < p.MinScore {
+		violations = append(violations, PasswordViolation{RuleStrength,
+			"Password is too easy to guess, try a longer passphrase or mix in more character types"})
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, PasswordViolation{RuleBreached,
+			"Password has appeared in a data breach, please choose a different one"})
+	}
+
+	return violations
+}
+
+// PasswordScore estimates password strength on a 0 (very weak) to 4 (very strong) scale
+func PasswordScore(password string) int {
+	bits := passwordEntropy(password)
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type charClasses struct {
+	upper, lower, digit, symbol, other bool
+}
+
+func passwordClasses(password string) charClasses {
+	var c charClasses
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII:
+			c.other = true
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		default:
+			c.symbol = true
+		}
+	}
+	return c
+}
+
+// passwordEntropy is a rough entropy estimate in bits: the character pool size of the classes used,
+// raised to the password length, where repeated and sequential characters (aaa, abc, 321) count for half
+func passwordEntropy(password string) float64 {
+	classes := passwordClasses(password)
+	pool := 0
+	if classes.upper {
+		pool += 26
+	}
+	if classes.lower {
+		pool += 26
+	}
+	if classes.digit {
+		pool += 10
+	}
+	if classes.symbol {
+		pool += 33
+	}
+	if classes.other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	var (
+		effectiveLength float64
+		prev            rune = -1
+	)
+	for _, r := range password {
+		if diff := r - prev; prev >= 0 && (diff == 0 || diff == 1 || diff == -1) {
+			effectiveLength += 0.5
+		} else {
+			effectiveLength++
+		}
+		prev = r
+	}
+
+	return effectiveLength * math.Log2(float64(pool))
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// Token purposes, a purpose token is only accepted by the flow it was issued for
+const (
+	PurposeMagicLink       = "magic_link"
+	PurposeExportDownload  = "export_download"
+	PurposePasswordReset   = "password_reset"
+	PurposeHouseholdInvite = "household_invite"
+)
+
+// ErrInvalidPurposeToken is returned when a purpose token is malformed, expired or issued for another purpose
+var ErrInvalidPurposeToken = errors.New("invalid or expired token")
+
+// PurposeClaims are the claims of a short-lived, single-purpose token such as a sign-in link.
+// Session tokens never carry a purpose, and middleware.Auth rejects tokens that do.
+type PurposeClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// SignPurposeToken signs a token for subject that is only valid for purpose and expires after ttl.
+// The returned id is the token's jti, which callers store to make the token single-use.
+func SignPurposeToken(purpose, subject string, ttl time.Duration) (tokenString, id string, err error) {
+	now := time.Now()
+	id = uuid.New().String()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, PurposeClaims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    Issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+	tokenString, err = token.SignedString(config.ECDSAKey)
+	return tokenString, id, err
+}
+
+// ParsePurposeToken verifies tokenString and checks it was issued for purpose
+func ParsePurposeToken(tokenString, purpose string) (*PurposeClaims, error) {
+	claims := &PurposeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return &config.ECDSAKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}), jwt.WithIssuer(Issuer))
+	if err != nil || !token.Valid || claims.Purpose != purpose || claims.ID == "" || claims.Subject == "" {
+		return nil, ErrInvalidPurposeToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrUsernameFormat is returned for usernames that are not 4-16 letters, digits, underscores or hyphens
+	ErrUsernameFormat = errors.New("username must be 4-16 letters, digits, underscores or hyphens")
+	// ErrUsernameReserved is returned for usernames that could pass for staff or the service itself
+	ErrUsernameReserved = errors.New("username is reserved")
+
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{4,16}$`)
+
+	reservedUsernames = map[string]bool{
+		"about": true, "account": true, "admin": true, "api": true, "billing": true, "contact": true,
+		"help": true, "info": true, "login": true, "logout": true, "mail": true, "moderator": true,
+		"noreply": true, "no-reply": true, "null": true, "official": true, "register": true, "root": true,
+		"security": true, "settings": true, "signin": true, "signup": true, "staff": true, "support": true,
+		"system": true, "undefined": true, "user": true, "users": true, "webmaster": true, "www": true,
+	}
+	// reservedUsernameParts may not appear anywhere in a username, e.g. "moneyfly_help" or "the_admin"
+	reservedUsernameParts = []string{"admin", "moneyfly", "support"}
+)
+
+// NormalizeUsername returns the form usernames are compared and stored for uniqueness in, case-insensitively
+func NormalizeUsername(username string) string {
+	return strings.ToLower(username)
+}
+
+// CheckUsername returns ErrUsernameFormat or ErrUsernameReserved if username cannot be registered
+func CheckUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrUsernameFormat
+	}
+	normalized := NormalizeUsername(username)
+	if reservedUsernames[normalized] {
+		return ErrUsernameReserved
+	}
+	for _, part := range reservedUsernameParts {
+		if strings.Contains(normalized, part) {
+			return ErrUsernameReserved
+		}
+	}
+	return nil
+}
@@ -2,10 +2,14 @@ package common
 
 import (
 	"net/mail"
-	"regexp"
+	"time"
 
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+
+	"github.com/harisnkr/expense/models"
 )
 
 func initValidators() {
@@ -14,24 +18,19 @@ func initValidators() {
 		_ = v.RegisterValidation(Username, validateUsername)
 		_ = v.RegisterValidation(Password, validatePassword)
 		_ = v.RegisterValidation(Email, validateEmail)
+		_ = v.RegisterValidation(Currency, validateCurrency)
+		_ = v.RegisterValidation(Timezone, validateTimezone)
+		_ = v.RegisterValidation(Locale, validateLocale)
+		_ = v.RegisterValidation(WebhookEvent, validateWebhookEvent)
 	}
 }
 
 func validatePassword(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
-	if len(password) < 8 || len(password) > 20 {
-		return false
-	}
-	// TODO: more password validation logic here
-	// For example, checking for special characters, uppercase, lowercase, etc.
-	return true
+	return len(CheckPassword(fl.Field().String())) == 0
 }
 
 func validateUsername(fl validator.FieldLevel) bool {
-	username := fl.Field().String()
-	regex := regexp.MustCompile(`^[a-zA-Z0-9_-]{4,16}$`)
-	// TOOD: blacklist words
-	return regex.MatchString(username)
+	return CheckUsername(fl.Field().String()) == nil
 }
 
 func validateEmail(fl validator.FieldLevel) bool {
@@ -44,3 +43,28 @@ func validateName(fl validator.FieldLevel) bool {
 	name := fl.Field().String()
 	return !(len(name) > 20)
 }
+
+func validateCurrency(fl validator.FieldLevel) bool {
+	_, err := currency.ParseISO(fl.Field().String())
+	return err == nil
+}
+
+func validateTimezone(fl validator.FieldLevel) bool {
+	tz := fl.Field().String()
+	// LoadLocation accepts "" and "Local" as the server's zone, which is never what a user means
+	if tz == "" || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+func validateLocale(fl validator.FieldLevel) bool {
+	_, err := language.Parse(fl.Field().String())
+	return err == nil
+}
+
+func validateWebhookEvent(fl validator.FieldLevel) bool {
+	_, ok := models.ParseWebhookEvent(fl.Field().String())
+	return ok
+}
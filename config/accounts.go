@@ -0,0 +1,43 @@
+package config
+
+import (
+	log "log/slog"
+	"time"
+)
+
+const (
+	deletionGracePeriodEnvVar = "ACCOUNT_DELETION_GRACE_PERIOD"
+	purgeIntervalEnvVar       = "ACCOUNT_PURGE_INTERVAL"
+	unverifiedReminderEnvVar  = "UNVERIFIED_ACCOUNT_REMINDER_AFTER"
+	unverifiedTTLEnvVar       = "UNVERIFIED_ACCOUNT_TTL"
+
+	defaultDeletionGracePeriod = 30 * 24 * time.Hour
+	defaultPurgeInterval       = time.Hour
+	defaultUnverifiedReminder  = 24 * time.Hour
+	defaultUnverifiedTTL       = 7 * 24 * time.Hour
+)
+
+var (
+	// DeletionGracePeriod is how long a deleted account can still be restored before it is purged
+	DeletionGracePeriod time.Duration
+
+	// PurgeInterval is how often the purge job looks for accounts past their grace period
+	PurgeInterval time.Duration
+
+	// UnverifiedReminderAfter is how long after sign-up an unverified user is reminded to verify their email
+	UnverifiedReminderAfter time.Duration
+
+	// UnverifiedAccountTTL is how long after sign-up an account that never verified its email is deleted
+	UnverifiedAccountTTL time.Duration
+)
+
+func setAccountsConfig() {
+	DeletionGracePeriod = getEnvDuration(deletionGracePeriodEnvVar, defaultDeletionGracePeriod)
+	PurgeInterval = getEnvDuration(purgeIntervalEnvVar, defaultPurgeInterval)
+	UnverifiedReminderAfter = getEnvDuration(unverifiedReminderEnvVar, defaultUnverifiedReminder)
+	UnverifiedAccountTTL = getEnvDuration(unverifiedTTLEnvVar, defaultUnverifiedTTL)
+	if UnverifiedReminderAfter >= UnverifiedAccountTTL {
+		log.Warn("Unverified account reminder is due after the account is deleted, no reminders will be sent",
+			"reminderAfter", UnverifiedReminderAfter, "ttl", UnverifiedAccountTTL)
+	}
+}
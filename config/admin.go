@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	impersonationTTLEnvVar = "IMPERSONATION_TTL"
+	passwordResetTTLEnvVar = "PASSWORD_RESET_TTL"
+
+	defaultImpersonationTTL = 15 * time.Minute
+	defaultPasswordResetTTL = time.Hour
+)
+
+var (
+	// ImpersonationTTL is how long a support session token issued to an admin for a user stays valid
+	ImpersonationTTL time.Duration
+
+	// PasswordResetTTL is how long an emailed password reset link stays valid
+	PasswordResetTTL time.Duration
+)
+
+func setAdminConfig() {
+	ImpersonationTTL = getEnvDuration(impersonationTTLEnvVar, defaultImpersonationTTL)
+	PasswordResetTTL = getEnvDuration(passwordResetTTLEnvVar, defaultPasswordResetTTL)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	log "log/slog"
+	"time"
+)
+
+const (
+	announcementIntervalEnvVar  = "ANNOUNCEMENT_INTERVAL"
+	announcementBatchSizeEnvVar = "ANNOUNCEMENT_BATCH_SIZE"
+
+	defaultAnnouncementInterval  = time.Minute
+	defaultAnnouncementBatchSize = 200
+)
+
+var (
+	// AnnouncementInterval is how often the announcement job sends the next batch of each announcement
+	AnnouncementInterval time.Duration
+
+	// AnnouncementBatchSize is how many users each announcement is sent to per AnnouncementInterval,
+	// throttling the notifications and emails a broadcast produces
+	AnnouncementBatchSize int
+)
+
+func setAnnouncementConfig() {
+	AnnouncementInterval = getEnvDuration(announcementIntervalEnvVar, defaultAnnouncementInterval)
+	AnnouncementBatchSize = getEnvInt(announcementBatchSizeEnvVar, defaultAnnouncementBatchSize)
+	if AnnouncementBatchSize < 1 {
+		log.Error("invalid ANNOUNCEMENT_BATCH_SIZE, using default", "value", AnnouncementBatchSize,
+			"default", defaultAnnouncementBatchSize)
+		AnnouncementBatchSize = defaultAnnouncementBatchSize
+	}
+}
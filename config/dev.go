@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	devAuthEnvVar       = "DEV_AUTH"
+	devUserEmailEnvVar  = "DEV_USER_EMAIL"
+	devMailboxTTLEnvVar = "DEV_MAILBOX_TTL"
+
+	defaultDevUserEmail  = "dev@moneyfly.local"
+	defaultDevMailboxTTL = 24 * time.Hour
+)
+
+var (
+	// DevAuth is whether development identities were requested, see common.DevAuthEnabled
+	DevAuth bool
+
+	// DevUserEmail is the email of the seeded development user
+	DevUserEmail string
+
+	// DevMailboxTTL is how long emails captured by the development mailbox are kept
+	DevMailboxTTL time.Duration
+)
+
+func setDevAuthConfig() {
+	DevAuth = getEnvBool(devAuthEnvVar, false)
+	DevUserEmail = os.Getenv(devUserEmailEnvVar)
+	if DevUserEmail == "" {
+		DevUserEmail = defaultDevUserEmail
+	}
+	DevMailboxTTL = getEnvDuration(devMailboxTTLEnvVar, defaultDevMailboxTTL)
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	log "log/slog"
+	"time"
+)
+
+const (
+	digestIntervalEnvVar = "DIGEST_INTERVAL"
+	digestHourEnvVar     = "DIGEST_HOUR"
+
+	defaultDigestInterval = 15 * time.Minute
+	defaultDigestHour     = 8
+)
+
+var (
+	// DigestInterval is how often the digest job looks for digests that are due
+	DigestInterval time.Duration
+
+	// DigestHour is the hour of the day, in each user's timezone, digests are sent from
+	DigestHour int
+)
+
+func setDigestConfig() {
+	DigestInterval = getEnvDuration(digestIntervalEnvVar, defaultDigestInterval)
+	DigestHour = getEnvInt(digestHourEnvVar, defaultDigestHour)
+	if DigestHour < 0 || DigestHour > 23 {
+		log.Error("invalid DIGEST_HOUR, using default", "value", DigestHour, "default", defaultDigestHour)
+		DigestHour = defaultDigestHour
+	}
+}
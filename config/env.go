@@ -33,6 +33,23 @@ func InitEnvVar() {
 		log.Error("Error loading .env file")
 	}
 	setTokenTTLConfig()
+	setPasswordPolicyConfig()
+	setPasswordHashConfig()
+	setDevAuthConfig()
+	setLinksConfig()
+	setWebAuthnConfig()
+	setAccountsConfig()
+	setExportConfig()
+	setStorageConfig()
+	setAdminConfig()
+	setMailConfig()
+	setOutboxConfig()
+	setNotificationConfig()
+	setWebhookConfig()
+	setDigestConfig()
+	setPushConfig()
+	setAnnouncementConfig()
+	setReminderConfig()
 }
 
 func setTokenTTLConfig() {
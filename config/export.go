@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	exportLinkTTLEnvVar   = "EXPORT_LINK_TTL"
+	exportRetentionEnvVar = "EXPORT_RETENTION"
+
+	defaultExportLinkTTL   = 15 * time.Minute
+	defaultExportRetention = 24 * time.Hour
+)
+
+var (
+	// ExportLinkTTL is how long a signed export download link stays valid
+	ExportLinkTTL time.Duration
+
+	// ExportRetention is how long a generated export is kept if it is never downloaded
+	ExportRetention time.Duration
+)
+
+func setExportConfig() {
+	ExportLinkTTL = getEnvDuration(exportLinkTTLEnvVar, defaultExportLinkTTL)
+	ExportRetention = getEnvDuration(exportRetentionEnvVar, defaultExportRetention)
+}
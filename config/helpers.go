@@ -0,0 +1,50 @@
+package config
+
+import (
+	log "log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// getEnvInt reads an integer env var, falling back to def when unset or invalid
+func getEnvInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Error("invalid integer env var, using default", "key", key, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
+
+// getEnvBool reads a boolean env var, falling back to def when unset or invalid
+func getEnvBool(key string, def bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Error("invalid boolean env var, using default", "key", key, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
+
+// getEnvDuration reads a duration env var (e.g. "15m"), falling back to def when unset or invalid
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Error("invalid duration env var, using default", "key", key, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	appBaseURLEnvVar     = "APP_BASE_URL"
+	apiBaseURLEnvVar     = "API_BASE_URL"
+	magicLinkTTLEnvVar   = "MAGIC_LINK_TTL"
+	inviteTTLEnvVar      = "HOUSEHOLD_INVITE_TTL"
+	emailChangeTTLEnvVar = "EMAIL_CHANGE_TTL"
+	accountCodeTTLEnvVar = "ACCOUNT_CODE_TTL"
+
+	defaultAppBaseURL     = "http://localhost:8801"
+	defaultAPIBaseURL     = "http://localhost:8801"
+	defaultMagicLinkTTL   = 15 * time.Minute
+	defaultInviteTTL      = 7 * 24 * time.Hour
+	defaultEmailChangeTTL = 30 * time.Minute
+	defaultAccountCodeTTL = 15 * time.Minute
+)
+
+var (
+	// AppBaseURL is the base URL of the app that links in emails point to
+	AppBaseURL string
+
+	// APIBaseURL is the public base URL of this service, for links served by the API itself
+	APIBaseURL string
+
+	// MagicLinkTTL is how long a passwordless sign-in link stays valid
+	MagicLinkTTL time.Duration
+
+	// HouseholdInviteTTL is how long an emailed household invitation can be accepted
+	HouseholdInviteTTL time.Duration
+
+	// EmailChangeTTL is how long the code confirming a new email address stays valid
+	EmailChangeTTL time.Duration
+
+	// AccountCodeTTL is how long the emailed code confirming an account deletion or restore stays valid
+	AccountCodeTTL time.Duration
+)
+
+func setLinksConfig() {
+	AppBaseURL = strings.TrimSuffix(os.Getenv(appBaseURLEnvVar), "/")
+	if AppBaseURL == "" {
+		AppBaseURL = defaultAppBaseURL
+	}
+	APIBaseURL = strings.TrimSuffix(os.Getenv(apiBaseURLEnvVar), "/")
+	if APIBaseURL == "" {
+		APIBaseURL = defaultAPIBaseURL
+	}
+	MagicLinkTTL = getEnvDuration(magicLinkTTLEnvVar, defaultMagicLinkTTL)
+	HouseholdInviteTTL = getEnvDuration(inviteTTLEnvVar, defaultInviteTTL)
+	EmailChangeTTL = getEnvDuration(emailChangeTTLEnvVar, defaultEmailChangeTTL)
+	AccountCodeTTL = getEnvDuration(accountCodeTTLEnvVar, defaultAccountCodeTTL)
+}
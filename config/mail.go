@@ -0,0 +1,103 @@
+package config
+
+import (
+	log "log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	mailDriverEnvVar   = "MAIL_DRIVER"
+	mailFromEnvVar     = "MAIL_FROM"
+	mailDirEnvVar      = "MAIL_DIR"
+	smtpHostEnvVar     = "SMTP_HOST"
+	smtpPortEnvVar     = "SMTP_PORT"
+	smtpUsernameEnvVar = "SMTP_USERNAME"
+	smtpPasswordEnvVar = "SMTP_PASSWORD"
+	smtpTLSEnvVar      = "SMTP_TLS"
+	smtpTimeoutEnvVar  = "SMTP_TIMEOUT"
+
+	defaultMailFrom    = "MoneyFly <no-reply@moneyfly.local>"
+	defaultSMTPPort    = 587
+	defaultSMTPTimeout = 30 * time.Second
+)
+
+// Mail drivers, see MailDriver
+const (
+	MailDriverLog     = "log"
+	MailDriverFile    = "file"
+	MailDriverSMTP    = "smtp"
+	MailDriverMailbox = "mailbox" // captured for the /dev/mailbox API, see common.DevMailboxEnabled
+)
+
+// SMTP transport security modes, see SMTPTLS
+const (
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+	SMTPTLSNone     = "none"
+)
+
+var (
+	// MailDriver selects how emails are delivered: "smtp", or the "file", "log" and "mailbox" sinks, which are only
+	// allowed in development and "log" being the default there
+	MailDriver string
+
+	// MailFrom is the From address of every email sent
+	MailFrom string
+
+	// MailDir is where the file driver writes .eml files
+	MailDir string
+
+	// SMTPHost and SMTPPort locate the SMTP relay
+	SMTPHost string
+	SMTPPort int
+
+	// SMTPUsername and SMTPPassword authenticate with the relay, leave the username empty to skip auth
+	SMTPUsername string
+	SMTPPassword string
+
+	// SMTPTLS is "starttls" (required, not opportunistic), "tls" for implicit TLS or "none"
+	SMTPTLS string
+
+	// SMTPTimeout bounds a single delivery, from dialling to QUIT
+	SMTPTimeout time.Duration
+)
+
+func setMailConfig() {
+	MailDriver = strings.ToLower(os.Getenv(mailDriverEnvVar))
+	switch MailDriver {
+	case MailDriverLog, MailDriverFile, MailDriverSMTP, MailDriverMailbox:
+	case "":
+		MailDriver = MailDriverLog
+	default:
+		log.Error("invalid MAIL_DRIVER, using default", "value", MailDriver, "default", MailDriverLog)
+		MailDriver = MailDriverLog
+	}
+
+	MailFrom = os.Getenv(mailFromEnvVar)
+	if MailFrom == "" {
+		MailFrom = defaultMailFrom
+	}
+	MailDir = os.Getenv(mailDirEnvVar)
+	if MailDir == "" {
+		MailDir = filepath.Join(os.TempDir(), "expense-mail")
+	}
+
+	SMTPHost = os.Getenv(smtpHostEnvVar)
+	SMTPPort = getEnvInt(smtpPortEnvVar, defaultSMTPPort)
+	SMTPUsername = os.Getenv(smtpUsernameEnvVar)
+	SMTPPassword = os.Getenv(smtpPasswordEnvVar)
+	SMTPTimeout = getEnvDuration(smtpTimeoutEnvVar, defaultSMTPTimeout)
+
+	SMTPTLS = strings.ToLower(os.Getenv(smtpTLSEnvVar))
+	switch SMTPTLS {
+	case SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	case "":
+		SMTPTLS = SMTPTLSStartTLS
+	default:
+		log.Error("invalid SMTP_TLS, using default", "value", SMTPTLS, "default", SMTPTLSStartTLS)
+		SMTPTLS = SMTPTLSStartTLS
+	}
+}
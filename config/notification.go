@@ -0,0 +1,25 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	notificationRetentionEnvVar    = "NOTIFICATION_RETENTION"
+	budgetWarningThresholdEnvVar   = "BUDGET_WARNING_THRESHOLD"
+	defaultNotificationRetention   = 90 * 24 * time.Hour
+	defaultBudgetWarningPercentage = 80
+)
+
+var (
+	// NotificationRetention is how long notifications are kept before MongoDB deletes them
+	NotificationRetention time.Duration
+
+	// BudgetWarningThreshold is the percentage of a budget spent that triggers a budget warning
+	BudgetWarningThreshold int
+)
+
+func setNotificationConfig() {
+	NotificationRetention = getEnvDuration(notificationRetentionEnvVar, defaultNotificationRetention)
+	BudgetWarningThreshold = getEnvInt(budgetWarningThresholdEnvVar, defaultBudgetWarningPercentage)
+}
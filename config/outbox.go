@@ -0,0 +1,50 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	outboxPollIntervalEnvVar = "OUTBOX_POLL_INTERVAL"
+	outboxBatchSizeEnvVar    = "OUTBOX_BATCH_SIZE"
+	outboxMaxAttemptsEnvVar  = "OUTBOX_MAX_ATTEMPTS"
+	outboxBackoffBaseEnvVar  = "OUTBOX_BACKOFF_BASE"
+	outboxBackoffMaxEnvVar   = "OUTBOX_BACKOFF_MAX"
+	outboxRetentionEnvVar    = "OUTBOX_RETENTION"
+
+	defaultOutboxPollInterval = 2 * time.Second
+	defaultOutboxBatchSize    = 50
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxBackoffBase  = 30 * time.Second
+	defaultOutboxBackoffMax   = 6 * time.Hour
+	defaultOutboxRetention    = 7 * 24 * time.Hour
+)
+
+var (
+	// OutboxPollInterval is how often workers look for outbox entries that are due
+	OutboxPollInterval time.Duration
+
+	// OutboxBatchSize is the most entries of one kind a worker processes per poll
+	OutboxBatchSize int
+
+	// OutboxMaxAttempts is how many times an entry is attempted before it is dead-lettered
+	OutboxMaxAttempts int
+
+	// OutboxBackoffBase is the delay before the first retry, doubling with every further attempt
+	OutboxBackoffBase time.Duration
+
+	// OutboxBackoffMax caps the delay between retries
+	OutboxBackoffMax time.Duration
+
+	// OutboxRetention is how long completed entries are kept for inspection before MongoDB deletes them
+	OutboxRetention time.Duration
+)
+
+func setOutboxConfig() {
+	OutboxPollInterval = getEnvDuration(outboxPollIntervalEnvVar, defaultOutboxPollInterval)
+	OutboxBatchSize = getEnvInt(outboxBatchSizeEnvVar, defaultOutboxBatchSize)
+	OutboxMaxAttempts = getEnvInt(outboxMaxAttemptsEnvVar, defaultOutboxMaxAttempts)
+	OutboxBackoffBase = getEnvDuration(outboxBackoffBaseEnvVar, defaultOutboxBackoffBase)
+	OutboxBackoffMax = getEnvDuration(outboxBackoffMaxEnvVar, defaultOutboxBackoffMax)
+	OutboxRetention = getEnvDuration(outboxRetentionEnvVar, defaultOutboxRetention)
+}
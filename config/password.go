@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+)
+
+const (
+	passwordMinLengthEnvVar       = "PASSWORD_MIN_LENGTH"
+	passwordMaxLengthEnvVar       = "PASSWORD_MAX_LENGTH"
+	passwordRequireUpperEnvVar    = "PASSWORD_REQUIRE_UPPER"
+	passwordRequireLowerEnvVar    = "PASSWORD_REQUIRE_LOWER"
+	passwordRequireDigitEnvVar    = "PASSWORD_REQUIRE_DIGIT"
+	passwordRequireSymbolEnvVar   = "PASSWORD_REQUIRE_SYMBOL"
+	passwordMinScoreEnvVar        = "PASSWORD_MIN_SCORE"
+	breachedPasswordsFileEnvVar   = "BREACHED_PASSWORDS_FILE"
+	defaultPasswordMinLength      = 8
+	defaultPasswordMaxLength      = 128
+	defaultPasswordMinScore       = 2
+	defaultPasswordRequireClasses = false
+)
+
+// PasswordPolicyConfig is the loaded/configured password policy
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinScore is the minimum strength score (0-4) a password must reach
+	MinScore int
+	// BreachedPasswordsFile is a local file of SHA-1 hashes of known breached passwords
+	BreachedPasswordsFile string
+}
+
+// PasswordPolicy is the loaded/configured password policy
+var PasswordPolicy PasswordPolicyConfig
+
+func setPasswordPolicyConfig() {
+	PasswordPolicy = PasswordPolicyConfig{
+		MinLength:             getEnvInt(passwordMinLengthEnvVar, defaultPasswordMinLength),
+		MaxLength:             getEnvInt(passwordMaxLengthEnvVar, defaultPasswordMaxLength),
+		RequireUpper:          getEnvBool(passwordRequireUpperEnvVar, defaultPasswordRequireClasses),
+		RequireLower:          getEnvBool(passwordRequireLowerEnvVar, defaultPasswordRequireClasses),
+		RequireDigit:          getEnvBool(passwordRequireDigitEnvVar, defaultPasswordRequireClasses),
+		RequireSymbol:         getEnvBool(passwordRequireSymbolEnvVar, defaultPasswordRequireClasses),
+		MinScore:              getEnvInt(passwordMinScoreEnvVar, defaultPasswordMinScore),
+		BreachedPasswordsFile: os.Getenv(breachedPasswordsFileEnvVar),
+	}
+}
+
+const (
+	passwordHashAlgorithmEnvVar = "PASSWORD_HASH_ALGORITHM"
+	bcryptCostEnvVar            = "BCRYPT_COST"
+	argon2MemoryEnvVar          = "ARGON2_MEMORY_KIB"
+	argon2IterationsEnvVar      = "ARGON2_ITERATIONS"
+	argon2ParallelismEnvVar     = "ARGON2_PARALLELISM"
+
+	defaultPasswordHashAlgorithm = "argon2id"
+	defaultBcryptCost            = 12
+	defaultArgon2Memory          = 64 * 1024 // 64 MiB
+	defaultArgon2Iterations      = 3
+	defaultArgon2Parallelism     = 2
+)
+
+// PasswordHashConfig is the loaded/configured algorithm and parameters for new password hashes
+type PasswordHashConfig struct {
+	// Algorithm is the algorithm new hashes are created with: "argon2id" or "bcrypt"
+	Algorithm         string
+	BcryptCost        int
+	Argon2MemoryKiB   int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+// PasswordHash is the loaded/configured password hashing config
+var PasswordHash PasswordHashConfig
+
+func setPasswordHashConfig() {
+	algorithm := os.Getenv(passwordHashAlgorithmEnvVar)
+	if algorithm == "" {
+		algorithm = defaultPasswordHashAlgorithm
+	}
+	PasswordHash = PasswordHashConfig{
+		Algorithm:         algorithm,
+		BcryptCost:        getEnvInt(bcryptCostEnvVar, defaultBcryptCost),
+		Argon2MemoryKiB:   getEnvInt(argon2MemoryEnvVar, defaultArgon2Memory),
+		Argon2Iterations:  getEnvInt(argon2IterationsEnvVar, defaultArgon2Iterations),
+		Argon2Parallelism: getEnvInt(argon2ParallelismEnvVar, defaultArgon2Parallelism),
+	}
+}
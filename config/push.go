@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	log "log/slog"
+	"math/big"
+	"os"
+	"time"
+)
+
+const (
+	vapidPrivateKeyEnvVar   = "VAPID_PRIVATE_KEY"
+	vapidSubjectEnvVar      = "VAPID_SUBJECT"
+	pushTTLEnvVar           = "PUSH_TTL"
+	pushTimeoutEnvVar       = "PUSH_TIMEOUT"
+	pushAllowInsecureEnvVar = "PUSH_ALLOW_INSECURE"
+
+	defaultVAPIDSubject = "mailto:no-reply@moneyfly.local"
+	defaultPushTTL      = 24 * time.Hour
+	defaultPushTimeout  = 10 * time.Second
+)
+
+var (
+	// VAPIDKey is the loaded/generated P-256 key push requests are signed with, see RFC 8292
+	VAPIDKey *ecdsa.PrivateKey
+
+	// VAPIDPublicKey is the uncompressed public point of VAPIDKey, base64url encoded without padding.
+	// Browsers subscribe with it as the applicationServerKey.
+	VAPIDPublicKey string
+
+	// VAPIDSubject is a mailto: or https: contact for push services, sent in every VAPID token
+	VAPIDSubject string
+
+	// PushTTL is how long push services keep a push for a device that is offline. A push that could not be
+	// handed to the push service within PushTTL of its notification is dropped.
+	PushTTL time.Duration
+
+	// PushTimeout bounds a single request to a push service
+	PushTimeout time.Duration
+
+	// PushAllowInsecure permits http:// push endpoints and private network addresses, only in development
+	PushAllowInsecure bool
+)
+
+func setPushConfig() {
+	VAPIDSubject = os.Getenv(vapidSubjectEnvVar)
+	if VAPIDSubject == "" {
+		VAPIDSubject = defaultVAPIDSubject
+	}
+	PushTTL = getEnvDuration(pushTTLEnvVar, defaultPushTTL)
+	PushTimeout = getEnvDuration(pushTimeoutEnvVar, defaultPushTimeout)
+	PushAllowInsecure = getEnvBool(pushAllowInsecureEnvVar, false)
+}
+
+// LoadVAPIDKey loads the VAPID private key from .env, or generates a new one for dev.
+// The key is the raw 32 byte private scalar, base64url encoded, as generated by common web push tools.
+// Changing it invalidates every push subscription, browsers must subscribe again with the new public key.
+func LoadVAPIDKey() {
+	if keyFromEnv := os.Getenv(vapidPrivateKeyEnvVar); keyFromEnv != "" {
+		if err := setVAPIDKeyFromEnv(keyFromEnv); err != nil {
+			log.Error("error loading VAPID key from .env file", "err", err)
+		}
+		return
+	}
+	generateRandomVAPIDKey()
+}
+
+func setVAPIDKeyFromEnv(keyFromEnv string) error {
+	keyBytes, err := base64.RawURLEncoding.DecodeString(keyFromEnv)
+	if err != nil {
+		return errors.New("error decoding base64url string")
+	}
+	privateKey, err := ecdh.P256().NewPrivateKey(keyBytes)
+	if err != nil {
+		return errors.New("error parsing VAPID private key")
+	}
+	setVAPIDKey(privateKey)
+	log.Info("Successfully loaded VAPID key from .env file", "publicKey", VAPIDPublicKey)
+	return nil
+}
+
+func generateRandomVAPIDKey() {
+	privateKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Error("error generating VAPID private key", "err", err)
+		return
+	}
+	setVAPIDKey(privateKey)
+
+	log.Warn("VAPID_PRIVATE_KEY environment variable not set, generated random key for testing: " +
+		base64.RawURLEncoding.EncodeToString(privateKey.Bytes()))
+}
+
+// setVAPIDKey sets VAPIDKey and VAPIDPublicKey from privateKey
+func setVAPIDKey(privateKey *ecdh.PrivateKey) {
+	public := privateKey.PublicKey().Bytes() // 0x04 || X || Y
+	VAPIDKey = &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(privateKey.Bytes()),
+	}
+	VAPIDPublicKey = base64.RawURLEncoding.EncodeToString(public)
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	reminderIntervalEnvVar      = "REMINDER_INTERVAL"
+	capWarningThresholdEnvVar   = "CAP_WARNING_THRESHOLD"
+	feeReminderDaysEnvVar       = "FEE_REMINDER_DAYS"
+	statementReminderDaysEnvVar = "STATEMENT_REMINDER_DAYS"
+
+	defaultReminderInterval      = time.Hour
+	defaultCapWarningThreshold   = 80
+	defaultFeeReminderDays       = 14
+	defaultStatementReminderDays = 3
+)
+
+var (
+	// ReminderInterval is how often users' cards and budgets are checked for cap warnings, fee reminders and
+	// budget alerts
+	ReminderInterval time.Duration
+
+	// CapWarningThreshold is the percentage of a card's bonus cap spent in a reward cycle that triggers a
+	// cap warning
+	CapWarningThreshold int
+
+	// FeeReminderDays is how many days before a card's annual fee is due its holder is reminded
+	FeeReminderDays int
+
+	// StatementReminderDays is how many days before a card's statement date its holder is reminded
+	StatementReminderDays int
+)
+
+func setReminderConfig() {
+	ReminderInterval = getEnvDuration(reminderIntervalEnvVar, defaultReminderInterval)
+	CapWarningThreshold = getEnvInt(capWarningThresholdEnvVar, defaultCapWarningThreshold)
+	FeeReminderDays = getEnvInt(feeReminderDaysEnvVar, defaultFeeReminderDays)
+	StatementReminderDays = getEnvInt(statementReminderDaysEnvVar, defaultStatementReminderDays)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	log "log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	blobDirEnvVar                = "BLOB_DIR"
+	blobURLSecretEnvVar          = "BLOB_URL_SECRET"
+	blobURLTTLEnvVar             = "BLOB_URL_TTL"
+	profilePictureMaxBytesEnvVar = "PROFILE_PICTURE_MAX_BYTES"
+
+	defaultBlobURLTTL             = time.Hour
+	defaultProfilePictureMaxBytes = 5 << 20 // 5 MiB
+)
+
+var (
+	// BlobDir is the root directory of the local filesystem blob store
+	BlobDir string
+
+	// BlobURLSecret is the HMAC key signed blob URLs are signed with
+	BlobURLSecret []byte
+
+	// BlobURLTTL is how long a signed blob URL stays valid
+	BlobURLTTL time.Duration
+
+	// ProfilePictureMaxBytes is the largest profile picture upload accepted
+	ProfilePictureMaxBytes int64
+)
+
+func setStorageConfig() {
+	BlobDir = os.Getenv(blobDirEnvVar)
+	if BlobDir == "" {
+		BlobDir = filepath.Join(os.TempDir(), "expense-blobs")
+	}
+	BlobURLTTL = getEnvDuration(blobURLTTLEnvVar, defaultBlobURLTTL)
+	ProfilePictureMaxBytes = int64(getEnvInt(profilePictureMaxBytesEnvVar, defaultProfilePictureMaxBytes))
+
+	if secret := os.Getenv(blobURLSecretEnvVar); secret != "" {
+		decoded, err := base64.URLEncoding.DecodeString(secret)
+		if err == nil {
+			BlobURLSecret = decoded
+			return
+		}
+		log.Error("error decoding BLOB_URL_SECRET, generating a random one", "err", err)
+	}
+
+	BlobURLSecret = make([]byte, 32)
+	if _, err := rand.Read(BlobURLSecret); err != nil {
+		log.Error("error generating blob URL secret", "err", err)
+		return
+	}
+	log.Warn("BLOB_URL_SECRET environment variable not set, generated random secret for testing: " +
+		base64.URLEncoding.EncodeToString(BlobURLSecret))
+}
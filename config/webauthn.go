@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	webAuthnRPIDEnvVar        = "WEBAUTHN_RP_ID"
+	webAuthnRPNameEnvVar      = "WEBAUTHN_RP_NAME"
+	webAuthnRPOriginsEnvVar   = "WEBAUTHN_RP_ORIGINS"
+	webAuthnTimeoutEnvVar     = "WEBAUTHN_TIMEOUT"
+	defaultWebAuthnRPID       = "localhost"
+	defaultWebAuthnRPName     = "MoneyFly"
+	defaultWebAuthnCeremonyTO = 5 * time.Minute
+)
+
+// WebAuthnConfig is the loaded/configured relying party for passkeys
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	// RPOrigins are the fully qualified origins passkey ceremonies may come from, comma separated in the env var
+	RPOrigins []string
+	// Timeout is how long a registration or login challenge stays valid
+	Timeout time.Duration
+}
+
+// WebAuthn is the loaded/configured passkey relying party config
+var WebAuthn WebAuthnConfig
+
+func setWebAuthnConfig() {
+	WebAuthn = WebAuthnConfig{
+		RPID:          os.Getenv(webAuthnRPIDEnvVar),
+		RPDisplayName: os.Getenv(webAuthnRPNameEnvVar),
+		Timeout:       getEnvDuration(webAuthnTimeoutEnvVar, defaultWebAuthnCeremonyTO),
+	}
+	if WebAuthn.RPID == "" {
+		WebAuthn.RPID = defaultWebAuthnRPID
+	}
+	if WebAuthn.RPDisplayName == "" {
+		WebAuthn.RPDisplayName = defaultWebAuthnRPName
+	}
+	for _, origin := range strings.Split(os.Getenv(webAuthnRPOriginsEnvVar), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			WebAuthn.RPOrigins = append(WebAuthn.RPOrigins, origin)
+		}
+	}
+	if len(WebAuthn.RPOrigins) == 0 {
+		WebAuthn.RPOrigins = []string{AppBaseURL}
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	webhookTimeoutEnvVar           = "WEBHOOK_TIMEOUT"
+	webhookMaxFailuresEnvVar       = "WEBHOOK_MAX_FAILURES"
+	webhookDeliveryRetentionEnvVar = "WEBHOOK_DELIVERY_RETENTION"
+	webhookAllowInsecureEnvVar     = "WEBHOOK_ALLOW_INSECURE"
+
+	defaultWebhookTimeout           = 10 * time.Second
+	defaultWebhookMaxFailures       = 20
+	defaultWebhookDeliveryRetention = 30 * 24 * time.Hour
+)
+
+var (
+	// WebhookTimeout bounds a single webhook request, from dialling to reading the response
+	WebhookTimeout time.Duration
+
+	// WebhookMaxFailures is how many attempts in a row may fail before a webhook is disabled
+	WebhookMaxFailures int
+
+	// WebhookDeliveryRetention is how long the delivery log is kept before MongoDB deletes it
+	WebhookDeliveryRetention time.Duration
+
+	// WebhookAllowInsecure permits http:// endpoints and private network addresses, only in development
+	WebhookAllowInsecure bool
+)
+
+func setWebhookConfig() {
+	WebhookTimeout = getEnvDuration(webhookTimeoutEnvVar, defaultWebhookTimeout)
+	WebhookMaxFailures = getEnvInt(webhookMaxFailuresEnvVar, defaultWebhookMaxFailures)
+	WebhookDeliveryRetention = getEnvDuration(webhookDeliveryRetentionEnvVar, defaultWebhookDeliveryRetention)
+	WebhookAllowInsecure = getEnvBool(webhookAllowInsecureEnvVar, false)
+}
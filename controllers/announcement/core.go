@@ -0,0 +1,376 @@
+package announcement
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/announcement"
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultAnnouncementsLimit = 20
+	maxAnnouncementsLimit     = 100
+)
+
+// API is an interface for admins broadcasting models.Announcement to segments of users
+type API interface {
+	AdminPreviewSegment(ctx *gin.Context)
+	AdminCreateAnnouncement(ctx *gin.Context)
+	AdminListAnnouncements(ctx *gin.Context)
+	AdminGetAnnouncement(ctx *gin.Context)
+	AdminUpdateAnnouncement(ctx *gin.Context)
+	AdminScheduleAnnouncement(ctx *gin.Context)
+	AdminCancelAnnouncement(ctx *gin.Context)
+}
+
+// Impl holds dependencies for announcement.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using announcement.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// AdminPreviewSegment counts the users in a segment, how many of them an announcement or service notice
+// would reach, and how many the product updates opt-in leaves out
+func (a *Impl) AdminPreviewSegment(c *gin.Context) {
+	var (
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID))
+		req dto.AnnouncementPreviewRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !a.checkCards(c, req.CardIDs) || !checkServiceNotice(c, req.ServiceNotice, req.CardIDs) {
+		return
+	}
+
+	matching, recipients, err := announcement.Preview(c, a.collections.Users, req.ToModel(), req.ServiceNotice)
+	if err != nil {
+		log.Error("Failed to count announcement recipients", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.AnnouncementPreviewResponse{
+		Matching:   matching,
+		Recipients: recipients,
+		OptedOut:   matching - recipients,
+	})
+}
+
+// AdminCreateAnnouncement saves an announcement, scheduled if it has a send time and as a draft otherwise
+func (a *Impl) AdminCreateAnnouncement(c *gin.Context) {
+	var (
+		adminID = c.GetString(common.UserID)
+		log     = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", adminID)
+		req     dto.CreateAnnouncementRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !a.checkCards(c, req.Segment.CardIDs) || !checkServiceNotice(c, req.ServiceNotice, req.Segment.CardIDs) {
+		return
+	}
+
+	now := time.Now()
+	item := models.Announcement{
+		ID:            uuid.New().String(),
+		Title:         req.Title,
+		Body:          req.Body,
+		Segment:       req.Segment.ToModel(),
+		ServiceNotice: req.ServiceNotice,
+		Status:        models.AnnouncementDraft,
+		CreatedBy:     adminID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if req.SendAt != nil {
+		sendAt := sendTime(req.SendAt, now)
+		item.Status = models.AnnouncementScheduled
+		item.SendAt = &sendAt
+	}
+	if _, err := a.collections.Announcements.InsertOne(c, item); err != nil {
+		log.Error("Failed to create announcement", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if item.SendAt != nil {
+		a.audit(c, models.AuditAnnouncementScheduled, item)
+	}
+
+	log.Info("Announcement created", "announcementID", item.ID, "status", item.Status)
+	c.JSON(http.StatusCreated, dto.NewAnnouncementResponse(item))
+}
+
+// AdminListAnnouncements lists announcements newest first, filtered by ?status=.
+// Supports ?page= (from 1) and ?limit= query parameters.
+func (a *Impl) AdminListAnnouncements(c *gin.Context) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID))
+
+	filter := bson.M{}
+	switch status := models.AnnouncementStatus(c.Query("status")); status {
+	case "":
+	case models.AnnouncementDraft, models.AnnouncementScheduled, models.AnnouncementSending,
+		models.AnnouncementSent, models.AnnouncementCancelled:
+		filter["status"] = status
+	default:
+		c.JSON(http.StatusBadRequest,
+			gin.H{"error": "status must be one of draft, scheduled, sending, sent or cancelled"})
+		return
+	}
+
+	page, limit := common.PaginationParams(c, defaultAnnouncementsLimit, maxAnnouncementsLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := a.collections.Announcements.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find announcements", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var items []models.Announcement
+	if err = cursor.All(c, &items); err != nil {
+		log.Error("Failed to decode announcements", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.AnnouncementResponse, 0, len(items))
+	for _, item := range items {
+		results = append(results, dto.NewAnnouncementResponse(item))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": results, "page": page, "limit": limit})
+}
+
+// AdminGetAnnouncement returns an announcement with the progress of its sending
+func (a *Impl) AdminGetAnnouncement(c *gin.Context) {
+	item, ok := a.findAnnouncement(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewAnnouncementResponse(item))
+}
+
+// AdminUpdateAnnouncement partially updates an announcement that has not started sending
+func (a *Impl) AdminUpdateAnnouncement(c *gin.Context) {
+	var (
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID),
+			"announcementID", c.Param("id"))
+		req dto.UpdateAnnouncementRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Title != nil {
+		set["title"] = *req.Title
+	}
+	if req.Body != nil {
+		set["body"] = *req.Body
+	}
+	if req.Segment != nil {
+		if !a.checkCards(c, req.Segment.CardIDs) {
+			return
+		}
+		set["segment"] = req.Segment.ToModel()
+	}
+	if req.Segment != nil || req.ServiceNotice != nil {
+		// a service notice must keep targeting card holders, whichever of the two changes
+		current, ok := a.findAnnouncement(c)
+		if !ok {
+			return
+		}
+		cardIDs, serviceNotice := current.Segment.CardIDs, current.ServiceNotice
+		if req.Segment != nil {
+			cardIDs = req.Segment.CardIDs
+		}
+		if req.ServiceNotice != nil {
+			serviceNotice = *req.ServiceNotice
+			set["service_notice"] = serviceNotice
+		}
+		if !checkServiceNotice(c, serviceNotice, cardIDs) {
+			return
+		}
+	}
+
+	item, ok := a.transition(c, log, []models.AnnouncementStatus{models.AnnouncementDraft, models.AnnouncementScheduled},
+		bson.M{"$set": set})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewAnnouncementResponse(item))
+}
+
+// AdminScheduleAnnouncement schedules a draft, or reschedules an announcement that has not started sending
+func (a *Impl) AdminScheduleAnnouncement(c *gin.Context) {
+	var (
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID),
+			"announcementID", c.Param("id"))
+		req dto.ScheduleAnnouncementRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":     models.AnnouncementScheduled,
+		"send_at":    sendTime(req.SendAt, now),
+		"updated_at": now,
+	}}
+	item, ok := a.transition(c, log, []models.AnnouncementStatus{models.AnnouncementDraft, models.AnnouncementScheduled},
+		update)
+	if !ok {
+		return
+	}
+	a.audit(c, models.AuditAnnouncementScheduled, item)
+
+	log.Info("Announcement scheduled", "sendAt", item.SendAt)
+	c.JSON(http.StatusOK, dto.NewAnnouncementResponse(item))
+}
+
+// AdminCancelAnnouncement cancels an announcement that has not finished sending. Users already notified
+// keep their notification, the rest are not notified.
+func (a *Impl) AdminCancelAnnouncement(c *gin.Context) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID),
+		"announcementID", c.Param("id"))
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": models.AnnouncementCancelled, "cancelled_at": now, "updated_at": now}}
+	item, ok := a.transition(c, log, []models.AnnouncementStatus{models.AnnouncementDraft,
+		models.AnnouncementScheduled, models.AnnouncementSending}, update)
+	if !ok {
+		return
+	}
+	a.audit(c, models.AuditAnnouncementCancelled, item)
+
+	log.Info("Announcement cancelled", "processed", item.Processed)
+	c.JSON(http.StatusOK, dto.NewAnnouncementResponse(item))
+}
+
+// transition applies update to the announcement with the :id parameter if it is in one of the from states,
+// responding if it cannot
+func (a *Impl) transition(c *gin.Context, log *slog.Logger, from []models.AnnouncementStatus,
+	update bson.M) (models.Announcement, bool) {
+	if _, ok := a.findAnnouncement(c); !ok {
+		return models.Announcement{}, false
+	}
+
+	var item models.Announcement
+	err := a.collections.Announcements.FindOneAndUpdate(c,
+		bson.M{"_id": c.Param("id"), "status": bson.M{"$in": from}}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Announcement has already been sent or cancelled"})
+		return item, false
+	}
+	if err != nil {
+		log.Error("Failed to update announcement", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return item, false
+	}
+	return item, true
+}
+
+// findAnnouncement loads the announcement with the :id parameter, responding if it cannot
+func (a *Impl) findAnnouncement(c *gin.Context) (models.Announcement, bool) {
+	var item models.Announcement
+	err := a.collections.Announcements.FindOne(c, bson.M{"_id": c.Param("id")}).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return item, false
+	}
+	if err != nil {
+		slog.With(common.RequestID, c.MustGet(common.RequestID), "announcementID", c.Param("id")).
+			Error("Failed to find announcement", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return item, false
+	}
+	return item, true
+}
+
+// checkCards checks every card a segment targets the holders of is in the catalogue, responding if not
+func (a *Impl) checkCards(c *gin.Context, cardIDs []string) bool {
+	if len(cardIDs) == 0 {
+		return true
+	}
+	unique := make(map[string]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		unique[id] = true
+	}
+	count, err := a.collections.Cards.CountDocuments(c, bson.M{"_id": bson.M{"$in": cardIDs}})
+	if err != nil {
+		slog.With(common.RequestID, c.MustGet(common.RequestID)).Error("Failed to count cards", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return false
+	}
+	if int(count) != len(unique) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Segment targets a card that is not in the catalogue"})
+		return false
+	}
+	return true
+}
+
+// checkServiceNotice checks a service notice targets the holders of specific cards, responding if not.
+// Service notices skip the product updates opt-in, so they must not reach users about cards they do not hold.
+func checkServiceNotice(c *gin.Context, serviceNotice bool, cardIDs []string) bool {
+	if serviceNotice && len(cardIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A service notice must target the holders of specific cards"})
+		return false
+	}
+	return true
+}
+
+// audit records an admin's action on an announcement
+func (a *Impl) audit(c *gin.Context, action string, item models.Announcement) {
+	metadata := map[string]string{"title": item.Title}
+	if item.SendAt != nil {
+		metadata["send_at"] = item.SendAt.UTC().Format(time.RFC3339)
+	}
+	if item.ServiceNotice {
+		metadata["service_notice"] = "true"
+	}
+	audit := models.AuditLog{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   c.GetString(common.UserID),
+		TargetID:  item.ID,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	if _, err := a.collections.AuditLogs.InsertOne(c, audit); err != nil {
+		slog.With(common.RequestID, c.MustGet(common.RequestID), "announcementID", item.ID).
+			Error("Failed to audit announcement", "err", err, "action", action)
+	}
+}
+
+// sendTime is when an announcement scheduled for sendAt goes out, now if it is omitted or has passed
+func sendTime(sendAt *time.Time, now time.Time) time.Time {
+	if sendAt == nil || sendAt.Before(now) {
+		return now
+	}
+	return *sendAt
+}
@@ -0,0 +1,62 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/storage"
+)
+
+// API is an interface for serving files from a storage.BlobStore
+type API interface {
+	GetBlob(ctx *gin.Context)
+}
+
+// Impl holds dependencies for blob.API
+type Impl struct {
+	blobs storage.BlobStore
+}
+
+// New returns Impl struct with dependencies for using blob.API
+func New(blobs storage.BlobStore) *Impl {
+	return &Impl{blobs}
+}
+
+// GetBlob serves a blob through a URL from storage.SignedURL
+func (a *Impl) GetBlob(c *gin.Context) {
+	var (
+		key = strings.TrimPrefix(c.Param("key"), "/")
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID), "key", key)
+	)
+
+	if !storage.VerifySignedURL(key, c.Query("exp"), c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+
+	blob, err := a.blobs.Get(c, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		log.Error("Failed to open blob", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", storage.ContentType(key))
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	if _, err = io.Copy(c.Writer, blob); err != nil {
+		log.Warn("Failed to write blob", "err", err)
+	}
+}
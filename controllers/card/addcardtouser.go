@@ -40,6 +40,8 @@ func (a *Impl) AddCardToUser(c *gin.Context) {
 		"network", card.Network,
 	)
 
+	card.StatementDay = req.StatementDay
+	card.AnnualFeeDue = req.AnnualFeeDue
 	result, err := a.collections.Users.UpdateOne(c,
 		bson.M{"_id": userID},
 		bson.M{"$push": bson.M{"cards": card}},
@@ -55,4 +57,13 @@ func (a *Impl) AddCardToUser(c *gin.Context) {
 		return
 	}
 	log.Debug("successfully add card to user")
+
+	err = a.webhooks.Publish(c, models.WebhookCardAdded, []string{userID}, gin.H{
+		"userId": userID,
+		"card":   dto.NewCardResponse(card),
+	})
+	if err != nil {
+		log.Error("Failed to publish webhook event", "event", models.WebhookCardAdded, "err", err)
+	}
+	c.JSON(http.StatusCreated, dto.NewCardResponse(card))
 }
@@ -25,14 +25,13 @@ func (a *Impl) AdminCreateCard(c *gin.Context) {
 	}
 
 	card.ID = uuid.New().String()
-	result, err := a.collections.Cards.InsertOne(c, card)
-	if err != nil {
-		log.Error("Failed to create card", err)
+	if _, err := a.collections.Cards.InsertOne(c, card); err != nil {
+		log.Error("Failed to create card", "err", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, result)
+	c.JSON(http.StatusCreated, dto.NewCardResponse(card))
 }
 
 // AdminDeleteCard deletes a card object
@@ -88,5 +87,5 @@ func (a *Impl) AdminUpdateCard(c *gin.Context) {
 	}
 
 	log.Debug("updated card successfully")
-	c.JSON(http.StatusOK, updatedCard)
+	c.JSON(http.StatusOK, dto.NewCardResponse(updatedCard))
 }
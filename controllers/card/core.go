@@ -11,7 +11,9 @@ import (
 
 	"github.com/harisnkr/expense/common"
 	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
 	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/webhook"
 )
 
 // API is an interface for operations related to models.Card
@@ -20,6 +22,7 @@ type API interface {
 	AdminDeleteCard(ctx *gin.Context)
 	GetCard(ctx *gin.Context)
 	GetAllCards(ctx *gin.Context)
+	SearchCards(ctx *gin.Context)
 	AdminUpdateCard(ctx *gin.Context)
 	AddCardToUser(ctx *gin.Context)
 }
@@ -28,31 +31,35 @@ type API interface {
 type Impl struct {
 	database    *mongo.Client
 	collections *data.Collections
+	webhooks    *webhook.Publisher
 }
 
 // New returns Impl struct with dependencies for using card.API
-func New(database *mongo.Client, collections *data.Collections) *Impl {
-	return &Impl{database, collections}
+func New(database *mongo.Client, collections *data.Collections, webhooks *webhook.Publisher) *Impl {
+	return &Impl{database, collections, webhooks}
 }
 
-// GetCard gets one card by name, issuerBank and network
+// GetCard gets the cards named :name, narrowed down by the optional ?issuerBank= and ?network= query parameters
 func (a *Impl) GetCard(c *gin.Context) {
 	var (
 		cards         []models.Card
 		reqName       = c.Param("name")
-		reqIssuerBank = c.Param("issuerBank")
-		reqNetwork    = c.Param("network")
+		reqIssuerBank = c.Query("issuerBank")
+		reqNetwork    = c.Query("network")
 		log           = slog.With(common.RequestID, c.MustGet(common.RequestID)).
 				With("func", "GetCard",
 				"reqName", reqName, "reqIssuerBank", reqIssuerBank, "reqNetwork", reqNetwork)
 	)
 
 	log.Debug("incoming req to search for card")
-	cursor, err := a.collections.Cards.Find(c, bson.D{
-		{"name", c.Param("name")},
-		{"issuer_bank", reqIssuerBank},
-		{"network", reqNetwork},
-	})
+	filter := bson.M{"name": reqName}
+	if reqIssuerBank != "" {
+		filter["issuer_bank"] = reqIssuerBank
+	}
+	if reqNetwork != "" {
+		filter["network"] = reqNetwork
+	}
+	cursor, err := a.collections.Cards.Find(c, filter)
 	if err != nil {
 		log.Warn("find card error: ", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -71,7 +78,7 @@ func (a *Impl) GetCard(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"result": cards,
+		"result": dto.NewCardResponses(cards),
 	})
 }
 
@@ -99,6 +106,6 @@ func (a *Impl) GetAllCards(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cards": results,
+		"cards": dto.NewCardResponses(results),
 	})
 }
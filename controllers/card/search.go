@@ -0,0 +1,286 @@
+package card
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// sortFields maps the ?sort= values to the fields they sort by, and whether they sort highest first by default
+var sortFields = map[string]struct {
+	field      string
+	descending bool
+}{
+	"name":               {"name", false},
+	"annualFee":          {"annual_fee", false},
+	"localMultiplier":    {"miles.multiplier", true},
+	"overseasMultiplier": {"miles.overseas_multiplier", true},
+	"bonusMultiplier":    {"miles.bonus_multiplier", true},
+}
+
+// annualFeeBands are the lower bounds of the annual fee bands counted for the annual fee facet, the first
+// band being the cards without a fee
+var annualFeeBands = []float64{0, 1, 100, 200, 500}
+
+// facetFilter is a filter of a search, and the facet counting the cards for each of its values
+type facetFilter struct {
+	facet     string
+	condition bson.M // nil if the filter is not used
+	counts    mongo.Pipeline
+}
+
+// cardSearchResult is the single document the search aggregation returns
+type cardSearchResult struct {
+	Items []models.Card `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+	Networks        []valueCount `bson:"networks"`
+	IssuerBanks     []valueCount `bson:"issuerBanks"`
+	SpendCategories []struct {
+		Category models.SpendCategory `bson:"_id"`
+		Count    int64                `bson:"count"`
+	} `bson:"spendCategories"`
+	LocalMultipliers    []multiplierCount `bson:"localMultipliers"`
+	OverseasMultipliers []multiplierCount `bson:"overseasMultipliers"`
+	AnnualFees          []struct {
+		Min   float64 `bson:"_id"`
+		Count int64   `bson:"count"`
+	} `bson:"annualFees"`
+}
+
+type valueCount struct {
+	Value string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+type multiplierCount struct {
+	Value float32 `bson:"_id"`
+	Count int64   `bson:"count"`
+}
+
+// SearchCards searches the card catalogue by ?q= over name and issuer bank, filtered by ?network=,
+// ?issuerBank=, ?category=, ?minLocalMultiplier=, ?minOverseasMultiplier=, ?minAnnualFee= and
+// ?maxAnnualFee=, and sorted by ?sort= and ?order=. Supports ?page= (from 1) and ?limit= query parameters.
+// Alongside the page of cards it returns how many cards each value of each filter would find.
+func (a *Impl) SearchCards(c *gin.Context) {
+	var (
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID))
+		query dto.CardSearchQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Sort == "" {
+		query.Sort = "name"
+	}
+	sortBy, ok := sortFields[query.Sort]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "sort must be one of name, annualFee, localMultiplier, overseasMultiplier or bonusMultiplier",
+		})
+		return
+	}
+	if query.MaxAnnualFee != nil && *query.MaxAnnualFee < query.MinAnnualFee {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "maxAnnualFee must not be less than minAnnualFee"})
+		return
+	}
+	categories := make([]models.SpendCategory, 0, len(query.Categories))
+	for _, name := range query.Categories {
+		category, known := models.ParseSpendCategory(name)
+		if !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown spend category " + name})
+			return
+		}
+		categories = append(categories, category)
+	}
+
+	order := 1
+	if query.Order == "desc" || (query.Order == "" && sortBy.descending) {
+		order = -1
+	}
+	sort := bson.D{{Key: sortBy.field, Value: order}}
+	if sortBy.field != "name" {
+		sort = append(sort, bson.E{Key: "name", Value: 1})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: 1}) // so pages neither repeat nor skip cards of equal keys
+
+	filters := searchFilters(query, categories)
+	page, limit := common.PaginationParams(c, defaultSearchLimit, maxSearchLimit)
+	facets := bson.D{
+		{Key: "items", Value: mongo.Pipeline{
+			{{Key: "$match", Value: matchExcept(filters, "")}},
+			{{Key: "$sort", Value: sort}},
+			{{Key: "$skip", Value: (page - 1) * limit}},
+			{{Key: "$limit", Value: limit}},
+		}},
+		{Key: "total", Value: mongo.Pipeline{
+			{{Key: "$match", Value: matchExcept(filters, "")}},
+			{{Key: "$count", Value: "count"}},
+		}},
+	}
+	for _, filter := range filters {
+		pipeline := append(mongo.Pipeline{{{Key: "$match", Value: matchExcept(filters, filter.facet)}}},
+			filter.counts...)
+		facets = append(facets, bson.E{Key: filter.facet, Value: pipeline})
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$facet", Value: facets}}}
+	if query.Query != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(query.Query), "$options": "i"}
+		pipeline = append(mongo.Pipeline{{{Key: "$match", Value: bson.M{
+			"$or": []bson.M{{"name": pattern}, {"issuer_bank": pattern}},
+		}}}}, pipeline...)
+	}
+
+	cursor, err := a.collections.Cards.Aggregate(c, pipeline)
+	if err != nil {
+		log.Error("Failed to search cards", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var results []cardSearchResult
+	if err = cursor.All(c, &results); err != nil || len(results) != 1 {
+		log.Error("Failed to decode card search", "err", err, "results", len(results))
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newCardSearchResponse(results[0], page, limit))
+}
+
+// searchFilters returns the filters of query in the order their facets are listed
+func searchFilters(query dto.CardSearchQuery, categories []models.SpendCategory) []facetFilter {
+	var (
+		byCount = bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}}
+		byValue = bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}}
+	)
+	// countBy counts the cards by the value of field, those without it by missing
+	countBy := func(field string, missing interface{}, order bson.D) mongo.Pipeline {
+		return mongo.Pipeline{
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$" + field, missing}}}},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			order,
+		}
+	}
+	filters := []facetFilter{
+		{facet: "networks", counts: countBy("network", "", byCount)},
+		{facet: "issuerBanks", counts: countBy("issuer_bank", "", byCount)},
+		{facet: "spendCategories", counts: append(mongo.Pipeline{{{Key: "$unwind", Value: "$miles.spend_categories"}}},
+			countBy("miles.spend_categories", nil, byCount)...)},
+		{facet: "localMultipliers", counts: countBy("miles.multiplier", 0.0, byValue)},
+		{facet: "overseasMultipliers", counts: countBy("miles.overseas_multiplier", 0.0, byValue)},
+		{facet: "annualFees", counts: mongo.Pipeline{
+			{{Key: "$bucket", Value: bson.D{
+				{Key: "groupBy", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$annual_fee", 0.0}}}},
+				{Key: "boundaries", Value: annualFeeBands},
+				{Key: "default", Value: annualFeeBands[len(annualFeeBands)-1]}, // the highest band has no bound
+			}}},
+		}},
+	}
+
+	if len(query.Networks) > 0 {
+		filters[0].condition = bson.M{"network": bson.M{"$in": query.Networks}}
+	}
+	if len(query.IssuerBanks) > 0 {
+		filters[1].condition = bson.M{"issuer_bank": bson.M{"$in": query.IssuerBanks}}
+	}
+	if len(categories) > 0 {
+		filters[2].condition = bson.M{"miles.spend_categories": bson.M{"$in": categories}}
+	}
+	if query.MinLocalMultiplier > 0 {
+		filters[3].condition = bson.M{"miles.multiplier": bson.M{"$gte": query.MinLocalMultiplier}}
+	}
+	if query.MinOverseasMultiplier > 0 {
+		filters[4].condition = bson.M{"miles.overseas_multiplier": bson.M{"$gte": query.MinOverseasMultiplier}}
+	}
+	fee := bson.M{}
+	if query.MinAnnualFee > 0 {
+		fee["$gte"] = query.MinAnnualFee
+	}
+	if query.MaxAnnualFee != nil {
+		fee["$not"] = bson.M{"$gt": *query.MaxAnnualFee} // also matches the cards without a fee
+	}
+	if len(fee) > 0 {
+		filters[5].condition = bson.M{"annual_fee": fee}
+	}
+	return filters
+}
+
+// matchExcept combines the conditions of every filter except facet's
+func matchExcept(filters []facetFilter, facet string) bson.M {
+	var conditions []bson.M
+	for _, filter := range filters {
+		if filter.condition != nil && filter.facet != facet {
+			conditions = append(conditions, filter.condition)
+		}
+	}
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": conditions}
+}
+
+func newCardSearchResponse(result cardSearchResult, page, limit int) dto.CardSearchResponse {
+	response := dto.CardSearchResponse{
+		Items: dto.NewCardResponses(result.Items),
+		Page:  page,
+		Limit: limit,
+		Facets: dto.CardFacets{
+			Networks:            make([]dto.FacetCount, 0, len(result.Networks)),
+			IssuerBanks:         make([]dto.FacetCount, 0, len(result.IssuerBanks)),
+			SpendCategories:     make([]dto.FacetCount, 0, len(result.SpendCategories)),
+			LocalMultipliers:    make([]dto.MultiplierFacetCount, 0, len(result.LocalMultipliers)),
+			OverseasMultipliers: make([]dto.MultiplierFacetCount, 0, len(result.OverseasMultipliers)),
+			AnnualFees:          make([]dto.AnnualFeeFacetCount, 0, len(result.AnnualFees)),
+		},
+	}
+	if len(result.Total) > 0 {
+		response.Total = result.Total[0].Count
+	}
+
+	for _, count := range result.Networks {
+		response.Facets.Networks = append(response.Facets.Networks, dto.FacetCount(count))
+	}
+	for _, count := range result.IssuerBanks {
+		response.Facets.IssuerBanks = append(response.Facets.IssuerBanks, dto.FacetCount(count))
+	}
+	for _, count := range result.SpendCategories {
+		response.Facets.SpendCategories = append(response.Facets.SpendCategories,
+			dto.FacetCount{Value: count.Category.String(), Count: count.Count})
+	}
+	for _, count := range result.LocalMultipliers {
+		response.Facets.LocalMultipliers = append(response.Facets.LocalMultipliers, dto.MultiplierFacetCount(count))
+	}
+	for _, count := range result.OverseasMultipliers {
+		response.Facets.OverseasMultipliers = append(response.Facets.OverseasMultipliers,
+			dto.MultiplierFacetCount(count))
+	}
+	for _, count := range result.AnnualFees {
+		band := dto.AnnualFeeFacetCount{Min: count.Min, Count: count.Count}
+		for _, bound := range annualFeeBands {
+			if bound > count.Min {
+				band.Max = &bound
+				break
+			}
+		}
+		response.Facets.AnnualFees = append(response.Facets.AnnualFees, band)
+	}
+	return response
+}
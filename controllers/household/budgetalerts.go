@@ -0,0 +1,110 @@
+package household
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+)
+
+// transactionTypeExpense is the models.HouseholdTransaction type counted against budgets
+const transactionTypeExpense = "expense"
+
+// notifyBudgets notifies every member about the budgets that added pushed past config.BudgetWarningThreshold
+// or over their amount. household holds the transactions before added, each budget alerts once per level.
+// Members' webhooks are sent models.WebhookBudgetExceeded by the transaction that goes over the amount.
+func (h *Impl) notifyBudgets(ctx context.Context, log *slog.Logger, household models.Household,
+	added models.HouseholdTransaction) {
+	if added.Type != transactionTypeExpense {
+		return
+	}
+
+	members := memberIDs(household)
+
+	for _, budget := range household.Budgets {
+		if !budgetCovers(budget, added) || budget.Amount <= 0 {
+			continue
+		}
+		spent := added.Amount
+		for _, transaction := range household.Transactions {
+			if transaction.Type == transactionTypeExpense && budgetCovers(budget, transaction) {
+				spent += transaction.Amount
+			}
+		}
+
+		if spent > budget.Amount && spent-added.Amount <= budget.Amount {
+			err := h.webhooks.Publish(ctx, models.WebhookBudgetExceeded, members, map[string]interface{}{
+				"householdId": household.ID,
+				"budget":      dto.NewHouseholdBudgetResponse(budget),
+				"spent":       spent,
+			})
+			if err != nil {
+				log.Error("Failed to publish webhook event", "event", models.WebhookBudgetExceeded, "err", err)
+			}
+		}
+
+		percent := int(spent / budget.Amount * 100)
+		if percent < config.BudgetWarningThreshold {
+			continue
+		}
+		msg := budgetAlert(household, budget, spent, percent)
+		if err := h.notifier.NotifyUsers(ctx, members, msg); err != nil {
+			log.Error("Failed to notify budget alert", "budgetID", budget.ID.Hex(), "type", msg.Type, "err", err)
+		}
+	}
+}
+
+// budgetAlert is the notification about budget reaching percent with spent
+func budgetAlert(household models.Household, budget models.HouseholdBudget, spent float64,
+	percent int) notify.Message {
+	var (
+		exceeded         = spent > budget.Amount
+		spentFormatted   = fmt.Sprintf("%.2f", spent)
+		amountFormatted  = fmt.Sprintf("%.2f", budget.Amount)
+		notificationType = models.NotificationBudgetWarning
+		title            = fmt.Sprintf("%s budget almost used", budget.Category)
+	)
+	if exceeded {
+		notificationType = models.NotificationBudgetExceeded
+		title = fmt.Sprintf("Over %s budget", budget.Category)
+	}
+
+	return notify.Message{
+		Type:  notificationType,
+		Title: title,
+		Body: fmt.Sprintf("%s has spent %s of its %s %s budget (%d%%).",
+			household.Name, spentFormatted, amountFormatted, budget.Category, percent),
+		Data:     map[string]string{"household_id": household.ID, "budget_id": budget.ID.Hex()},
+		Template: email.TemplateBudgetAlert,
+		TemplateData: email.BudgetAlertData{
+			Household: household.Name,
+			Category:  budget.Category,
+			Spent:     spentFormatted,
+			Amount:    amountFormatted,
+			Percent:   percent,
+			Exceeded:  exceeded,
+		},
+		DedupKey: string(notificationType) + ":" + budget.ID.Hex(),
+	}
+}
+
+// budgetCovers reports whether transaction counts against budget
+func budgetCovers(budget models.HouseholdBudget, transaction models.HouseholdTransaction) bool {
+	return transaction.Category == budget.Category &&
+		!transaction.Date.Before(budget.StartDate) &&
+		!transaction.Date.After(budget.EndDate)
+}
+
+// memberIDs returns the user IDs of the household's members
+func memberIDs(household models.Household) []string {
+	ids := make([]string, 0, len(household.Members))
+	for _, member := range household.Members {
+		ids = append(ids, member.UserID)
+	}
+	return ids
+}
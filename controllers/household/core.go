@@ -0,0 +1,185 @@
+package household
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+	"github.com/harisnkr/expense/outbox"
+	"github.com/harisnkr/expense/webhook"
+)
+
+// API is an interface for operations related to models.Household.
+// Routes with an :id are authorised by middleware.HouseholdRole, which loads the household for the handler.
+type API interface {
+	CreateHousehold(ctx *gin.Context)
+	GetHouseholds(ctx *gin.Context)
+	GetHousehold(ctx *gin.Context)
+	DeleteHousehold(ctx *gin.Context)
+	InviteMember(ctx *gin.Context)
+	RevokeInvitation(ctx *gin.Context)
+	AcceptInvitation(ctx *gin.Context)
+	UpdateMember(ctx *gin.Context)
+	RemoveMember(ctx *gin.Context)
+	AddBudget(ctx *gin.Context)
+	DeleteBudget(ctx *gin.Context)
+	AddTransaction(ctx *gin.Context)
+	DeleteTransaction(ctx *gin.Context)
+}
+
+// Impl holds dependencies for household.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+	outbox      *outbox.Outbox
+	notifier    *notify.Notifier
+	webhooks    *webhook.Publisher
+}
+
+// New returns Impl struct with dependencies for using household.API
+func New(database *mongo.Client, collections *data.Collections, box *outbox.Outbox, notifier *notify.Notifier,
+	webhooks *webhook.Publisher) *Impl {
+	return &Impl{database, collections, box, notifier, webhooks}
+}
+
+// CreateHousehold creates a household with the authenticated user as its owner
+func (h *Impl) CreateHousehold(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.CreateHouseholdRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	household := models.Household{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		Members:      []models.HouseholdMember{{UserID: userID, Role: models.HouseholdRoleOwner, JoinedAt: now}},
+		Invitations:  []models.HouseholdInvitation{},
+		Budgets:      []models.HouseholdBudget{},
+		Transactions: []models.HouseholdTransaction{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := h.collections.Households.InsertOne(c, household); err != nil {
+		log.Error("Failed to create household", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Household created", "householdID", household.ID)
+	c.JSON(http.StatusCreated, dto.NewHouseholdResponse(household, models.HouseholdRoleOwner, h.memberProfiles(c, household)))
+}
+
+// GetHouseholds lists the households the authenticated user belongs to
+func (h *Impl) GetHouseholds(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	opts := options.Find().SetProjection(bson.M{"name": 1, "members": 1})
+	cursor, err := h.collections.Households.Find(c, bson.M{"members.user_id": userID}, opts)
+	if err != nil {
+		log.Error("Failed to find households", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var households []models.Household
+	if err = cursor.All(c, &households); err != nil {
+		log.Error("Failed to decode households", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.HouseholdSummary, 0, len(households))
+	for _, household := range households {
+		member, _ := household.Member(userID)
+		results = append(results, dto.HouseholdSummary{
+			ID:      household.ID,
+			Name:    household.Name,
+			Role:    member.Role,
+			Members: len(household.Members),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"households": results})
+}
+
+// GetHousehold returns a household with its members, shared budgets and transactions
+func (h *Impl) GetHousehold(c *gin.Context) {
+	household, member := current(c)
+	c.JSON(http.StatusOK, dto.NewHouseholdResponse(household, member.Role, h.memberProfiles(c, household)))
+}
+
+// DeleteHousehold deletes a household and everything shared in it
+func (h *Impl) DeleteHousehold(c *gin.Context) {
+	household, member := current(c)
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+		"householdID", household.ID)
+
+	if _, err := h.collections.Households.DeleteOne(c, bson.M{"_id": household.ID}); err != nil {
+		log.Error("Failed to delete household", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	log.Info("Household deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// current returns the household loaded by middleware.HouseholdRole and the requesting user's membership of it
+func current(c *gin.Context) (models.Household, models.HouseholdMember) {
+	household := c.MustGet(common.Household).(models.Household)
+	member, _ := household.Member(c.GetString(common.UserID))
+	return household, member
+}
+
+// memberProfiles looks up the names and emails of a household's members.
+// Failures are logged only, the household is still usable with members listed by ID.
+func (h *Impl) memberProfiles(ctx context.Context, household models.Household) map[string]models.User {
+	ids := make([]string, 0, len(household.Members))
+	for _, member := range household.Members {
+		ids = append(ids, member.UserID)
+	}
+
+	profiles := make(map[string]models.User, len(ids))
+	opts := options.Find().SetProjection(bson.M{"first_name": 1, "last_name": 1, "email": 1})
+	cursor, err := h.collections.Users.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		slog.Warn("Failed to find household members", "err", err, "householdID", household.ID)
+		return profiles
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		slog.Warn("Failed to decode household members", "err", err, "householdID", household.ID)
+		return profiles
+	}
+	for _, user := range users {
+		profiles[user.ID] = user
+	}
+	return profiles
+}
+
+// sendInvitationEmail enqueues an invitation link in the invitee's locale, or the default one if they have no account
+func sendInvitationEmail(ctx context.Context, box *outbox.Outbox, invitee models.User, to, householdName,
+	link string) error {
+	return box.EnqueueEmail(ctx, to, invitee.EffectivePreferences().Locale, email.TemplateHouseholdInvitation,
+		email.HouseholdInvitationData{Household: householdName, Link: link, ValidFor: config.HouseholdInviteTTL})
+}
@@ -0,0 +1,174 @@
+package household
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// AddBudget adds a budget shared by the household, attributed to the requesting member
+func (h *Impl) AddBudget(c *gin.Context) {
+	var (
+		household, member = current(c)
+		log               = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+			"householdID", household.ID)
+		req dto.CreateHouseholdBudgetRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := models.ParseSpendCategory(req.Category); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return
+	}
+
+	now := time.Now()
+	budget := models.HouseholdBudget{
+		ID:        primitive.NewObjectID(),
+		Category:  req.Category,
+		Amount:    req.Amount,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		CreatedBy: member.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err := h.collections.Households.UpdateOne(c, bson.M{"_id": household.ID}, bson.M{
+		"$push": bson.M{"budgets": budget},
+		"$set":  bson.M{"updated_at": now},
+	})
+	if err != nil {
+		log.Error("Failed to add household budget", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusCreated, dto.NewHouseholdBudgetResponse(budget))
+}
+
+// DeleteBudget removes a shared budget, which only its creator or an owner may do
+func (h *Impl) DeleteBudget(c *gin.Context) {
+	household, member := current(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("itemID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	for _, budget := range household.Budgets {
+		if budget.ID == id {
+			h.deleteItem(c, household, member, "budgets", id, budget.CreatedBy)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+}
+
+// AddTransaction records a transaction shared by the household.
+// SpentBy must be a member and defaults to the member recording it. Members are notified about budgets
+// the transaction takes close to or over their amount, and their webhooks are sent the transaction.
+func (h *Impl) AddTransaction(c *gin.Context) {
+	var (
+		household, member = current(c)
+		log               = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+			"householdID", household.ID)
+		req dto.CreateHouseholdTransactionRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := models.ParseSpendCategory(req.Category); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return
+	}
+	spentBy := member.UserID
+	if req.SpentBy != "" {
+		if _, ok := household.Member(req.SpentBy); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "spentBy must be a household member"})
+			return
+		}
+		spentBy = req.SpentBy
+	}
+
+	now := time.Now()
+	transaction := models.HouseholdTransaction{
+		ID:          primitive.NewObjectID(),
+		Type:        req.Type,
+		Category:    req.Category,
+		Amount:      req.Amount,
+		Date:        req.Date,
+		Description: req.Description,
+		SpentBy:     spentBy,
+		CreatedBy:   member.UserID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := h.collections.Households.UpdateOne(c, bson.M{"_id": household.ID}, bson.M{
+		"$push": bson.M{"transactions": transaction},
+		"$set":  bson.M{"updated_at": now},
+	})
+	if err != nil {
+		log.Error("Failed to add household transaction", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	members := memberIDs(household)
+	err = h.webhooks.Publish(c, models.WebhookTransactionCreated, members, gin.H{
+		"householdId": household.ID,
+		"transaction": dto.NewHouseholdTransactionResponse(transaction),
+	})
+	if err != nil {
+		log.Error("Failed to publish webhook event", "event", models.WebhookTransactionCreated, "err", err)
+	}
+	h.notifyBudgets(c, log, household, transaction)
+	c.JSON(http.StatusCreated, dto.NewHouseholdTransactionResponse(transaction))
+}
+
+// DeleteTransaction removes a shared transaction, which only its creator or an owner may do
+func (h *Impl) DeleteTransaction(c *gin.Context) {
+	household, member := current(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("itemID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	for _, transaction := range household.Transactions {
+		if transaction.ID == id {
+			h.deleteItem(c, household, member, "transactions", id, transaction.CreatedBy)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+}
+
+// deleteItem pulls the item with id from the household's field, if member created it or is an owner
+func (h *Impl) deleteItem(c *gin.Context, household models.Household, member models.HouseholdMember,
+	field string, id primitive.ObjectID, createdBy string) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+		"householdID", household.ID, field, id.Hex())
+
+	if createdBy != member.UserID && member.Role != models.HouseholdRoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only its creator or an owner can delete this"})
+		return
+	}
+	_, err := h.collections.Households.UpdateOne(c, bson.M{"_id": household.ID}, bson.M{
+		"$pull": bson.M{field: bson.M{"_id": id}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		log.Error("Failed to delete household item", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,302 @@
+package household
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// InviteMember emails an invitation to join the household with the given role.
+// Inviting the same email again replaces the earlier invitation.
+func (h *Impl) InviteMember(c *gin.Context) {
+	var (
+		household, member = current(c)
+		log               = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+			"householdID", household.ID)
+		req dto.InviteHouseholdMemberRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invitee models.User
+	err := h.collections.Users.FindOne(c, bson.M{"email": req.Email}).Decode(&invitee)
+	if err == nil {
+		if _, ok := household.Member(invitee.ID); ok {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+			return
+		}
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Error("Failed to find invitee", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	tokenString, invitationID, err := common.SignPurposeToken(common.PurposeHouseholdInvite, household.ID,
+		config.HouseholdInviteTTL)
+	if err != nil {
+		log.Error("Failed to sign invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	now := time.Now()
+	invitation := models.HouseholdInvitation{
+		ID:        invitationID,
+		Email:     req.Email,
+		Role:      req.Role,
+		InvitedBy: member.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.HouseholdInviteTTL),
+	}
+
+	filter := bson.M{"_id": household.ID}
+	if _, err = h.collections.Households.UpdateOne(c, filter,
+		bson.M{"$pull": bson.M{"invitations": bson.M{"email": req.Email}}}); err != nil {
+		log.Error("Failed to replace earlier invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if _, err = h.collections.Households.UpdateOne(c, filter, bson.M{
+		"$push": bson.M{"invitations": invitation},
+		"$set":  bson.M{"updated_at": now},
+	}); err != nil {
+		log.Error("Failed to store invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	link := config.AppBaseURL + "/household/invite?token=" + url.QueryEscape(tokenString)
+	if err = sendInvitationEmail(c, h.outbox, invitee, req.Email, household.Name, link); err != nil {
+		log.Error("Failed to enqueue invitation email", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Household invitation sent", "invitationID", invitationID, "role", req.Role)
+	c.JSON(http.StatusCreated, dto.NewHouseholdInvitationResponse(invitation))
+}
+
+// RevokeInvitation cancels a pending invitation so its link no longer works
+func (h *Impl) RevokeInvitation(c *gin.Context) {
+	household, member := current(c)
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+		"householdID", household.ID)
+
+	result, err := h.collections.Households.UpdateOne(c, bson.M{"_id": household.ID},
+		bson.M{"$pull": bson.M{"invitations": bson.M{"id": c.Param("invitationID")}}})
+	if err != nil {
+		log.Error("Failed to revoke invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation adds the authenticated user to the household of an invitation link.
+// The invitation must have been sent to the user's email, and works once.
+func (h *Impl) AcceptInvitation(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.AcceptHouseholdInvitationRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := common.ParsePurposeToken(req.Token, common.PurposeHouseholdInvite)
+	if err != nil {
+		log.Info("Invalid invitation token", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+	log = log.With("householdID", claims.Subject, "invitationID", claims.ID)
+
+	var user models.User
+	if err = h.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// the invitation is matched and removed in the same update that adds the member, so it works once
+	filter := bson.M{
+		"_id":             claims.Subject,
+		"members.user_id": bson.M{"$ne": userID},
+		"invitations": bson.M{"$elemMatch": bson.M{
+			"id":         claims.ID,
+			"email":      bson.M{"$regex": "^" + regexp.QuoteMeta(user.Email) + "$", "$options": "i"},
+			"expires_at": bson.M{"$gt": time.Now()},
+		}},
+	}
+	var household models.Household
+	err = h.collections.Households.FindOne(c, filter).Decode(&household)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			log.Info("Invitation not found, already used or for another email")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+		log.Error("Failed to find invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	var role models.HouseholdRole
+	for _, invitation := range household.Invitations {
+		if invitation.ID == claims.ID {
+			role = invitation.Role
+		}
+	}
+	now := time.Now()
+	err = h.collections.Households.FindOneAndUpdate(c, filter,
+		bson.M{
+			"$pull": bson.M{"invitations": bson.M{"id": claims.ID}},
+			"$push": bson.M{"members": models.HouseholdMember{UserID: userID, Role: role, JoinedAt: now}},
+			"$set":  bson.M{"updated_at": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&household)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+		log.Error("Failed to accept invitation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Household invitation accepted", "role", role)
+	c.JSON(http.StatusOK, dto.NewHouseholdResponse(household, role, h.memberProfiles(c, household)))
+}
+
+// UpdateMember changes a member's role. A household always keeps at least one owner.
+func (h *Impl) UpdateMember(c *gin.Context) {
+	var (
+		household, member = current(c)
+		targetID          = c.Param("userID")
+		log               = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+			"householdID", household.ID, "memberID", targetID)
+		req dto.UpdateHouseholdMemberRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, ok := household.Member(targetID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if target.Role == models.HouseholdRoleOwner && req.Role != models.HouseholdRoleOwner && household.Owners() == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A household needs at least one owner"})
+		return
+	}
+
+	filter := bson.M{"_id": household.ID, "members.user_id": targetID}
+	if req.Role != models.HouseholdRoleOwner {
+		filter = keepingAnOwner(filter, targetID)
+	}
+	result, err := h.collections.Households.UpdateOne(c, filter,
+		bson.M{"$set": bson.M{"members.$[target].role": req.Role, "updated_at": time.Now()}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{"target.user_id": targetID},
+		}}))
+	if err != nil {
+		log.Error("Failed to update member role", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		log.Info("Member role not changed, they left or were the last owner")
+		c.JSON(http.StatusConflict, gin.H{"error": "A household needs at least one owner"})
+		return
+	}
+	log.Info("Household member role changed", "from", target.Role, "to", req.Role)
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember removes a member from the household. Owners can remove anyone, other members only themselves.
+// The last owner cannot leave while others remain, and the last member leaving deletes the household.
+func (h *Impl) RemoveMember(c *gin.Context) {
+	var (
+		household, member = current(c)
+		targetID          = c.Param("userID")
+		log               = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", member.UserID,
+			"householdID", household.ID, "memberID", targetID)
+	)
+
+	if targetID != member.UserID && member.Role != models.HouseholdRoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Household role owner required"})
+		return
+	}
+	target, ok := household.Member(targetID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if len(household.Members) == 1 {
+		if _, err := h.collections.Households.DeleteOne(c, bson.M{"_id": household.ID}); err != nil {
+			log.Error("Failed to delete household", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		log.Info("Last member left, household deleted")
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if target.Role == models.HouseholdRoleOwner && household.Owners() == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Make another member an owner first"})
+		return
+	}
+
+	filter := keepingAnOwner(bson.M{"_id": household.ID, "members.user_id": targetID}, targetID)
+	result, err := h.collections.Households.UpdateOne(c, filter, bson.M{
+		"$pull": bson.M{"members": bson.M{"user_id": targetID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		log.Error("Failed to remove member", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		log.Info("Member not removed, they left or were the last owner")
+		c.JSON(http.StatusConflict, gin.H{"error": "Make another member an owner first"})
+		return
+	}
+	log.Info("Household member removed")
+	c.Status(http.StatusNoContent)
+}
+
+// keepingAnOwner extends filter to only match while a member other than targetID is an owner, so demoting or
+// removing targetID cannot leave the household without one even when owners are changed concurrently
+func keepingAnOwner(filter bson.M, targetID string) bson.M {
+	filter["members"] = bson.M{"$elemMatch": bson.M{
+		"role":    models.HouseholdRoleOwner,
+		"user_id": bson.M{"$ne": targetID},
+	}}
+	return filter
+}
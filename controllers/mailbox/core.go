@@ -0,0 +1,124 @@
+package mailbox
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultMailboxLimit = 20
+	maxMailboxLimit     = 100
+)
+
+// API is an interface for reading emails captured by the development mailbox.
+// Its routes are only registered when common.DevMailboxEnabled.
+type API interface {
+	ListMessages(ctx *gin.Context)
+	GetMessage(ctx *gin.Context)
+	ClearMessages(ctx *gin.Context)
+}
+
+// Impl holds dependencies for mailbox.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using mailbox.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// ListMessages lists captured emails newest first, optionally only those sent ?to= an address.
+// Supports ?page= (from 1) and ?limit= query parameters.
+func (m *Impl) ListMessages(c *gin.Context) {
+	var (
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID))
+		query dto.MailboxQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if query.To != "" {
+		filter["to"] = query.To
+	}
+	page, limit := common.PaginationParams(c, defaultMailboxLimit, maxMailboxLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"text": 0, "html": 0})
+
+	cursor, err := m.collections.Mailbox.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find mailbox messages", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var messages []models.MailboxMessage
+	if err = cursor.All(c, &messages); err != nil {
+		log.Error("Failed to decode mailbox messages", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.MailboxMessageSummary, 0, len(messages))
+	for _, msg := range messages {
+		results = append(results, dto.NewMailboxMessageSummary(msg))
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": results, "page": page, "limit": limit})
+}
+
+// GetMessage returns a captured email with its text and HTML bodies
+func (m *Impl) GetMessage(c *gin.Context) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "messageID", c.Param("id"))
+
+	var msg models.MailboxMessage
+	if err := m.collections.Mailbox.FindOne(c, bson.M{"_id": c.Param("id")}).Decode(&msg); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Error("Failed to find mailbox message", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewMailboxMessageResponse(msg))
+}
+
+// ClearMessages deletes every captured email, or only those sent ?to= an address
+func (m *Impl) ClearMessages(c *gin.Context) {
+	var (
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID))
+		query dto.MailboxQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if query.To != "" {
+		filter["to"] = query.To
+	}
+	if _, err := m.collections.Mailbox.DeleteMany(c, filter); err != nil {
+		log.Error("Failed to clear mailbox", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+package notification
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultNotificationsLimit = 20
+	maxNotificationsLimit     = 100
+)
+
+// API is an interface for the authenticated user's notification centre of models.Notification
+type API interface {
+	ListNotifications(ctx *gin.Context)
+	UnreadCount(ctx *gin.Context)
+	MarkRead(ctx *gin.Context)
+	MarkAllRead(ctx *gin.Context)
+}
+
+// Impl holds dependencies for notification.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using notification.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// ListNotifications lists the authenticated user's in-app notifications newest first, only unread ones with
+// ?unread=true, along with how many are unread. Supports ?page= (from 1) and ?limit= query parameters.
+func (n *Impl) ListNotifications(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		query  dto.ListNotificationsQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := inAppFilter(userID)
+	if query.Unread {
+		filter = unreadFilter(filter)
+	}
+
+	page, limit := common.PaginationParams(c, defaultNotificationsLimit, maxNotificationsLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := n.collections.Notifications.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find notifications", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var notifications []models.Notification
+	if err = cursor.All(c, &notifications); err != nil {
+		log.Error("Failed to decode notifications", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	unread, err := n.countUnread(c, userID)
+	if err != nil {
+		log.Error("Failed to count unread notifications", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		results = append(results, dto.NewNotificationResponse(notification))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": results, "unreadCount": unread, "page": page, "limit": limit})
+}
+
+// UnreadCount returns how many of the authenticated user's in-app notifications are unread, for badges
+func (n *Impl) UnreadCount(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	unread, err := n.countUnread(c, userID)
+	if err != nil {
+		log.Error("Failed to count unread notifications", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unreadCount": unread})
+}
+
+// MarkRead marks one of the authenticated user's notifications read. Marking a read notification is a no-op.
+func (n *Impl) MarkRead(c *gin.Context) {
+	var (
+		userID         = c.GetString(common.UserID)
+		notificationID = c.Param("id")
+		log            = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID,
+			"notificationID", notificationID)
+	)
+
+	filter := inAppFilter(userID)
+	filter["_id"] = notificationID
+	if _, err := n.collections.Notifications.UpdateOne(c, unreadFilter(filter),
+		bson.M{"$set": bson.M{"read_at": time.Now()}}); err != nil {
+		log.Error("Failed to mark notification read", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	var notification models.Notification
+	err := n.collections.Notifications.FindOne(c, filter).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to find notification", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewNotificationResponse(notification))
+}
+
+// MarkAllRead marks all of the authenticated user's notifications read
+func (n *Impl) MarkAllRead(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	result, err := n.collections.Notifications.UpdateMany(c, unreadFilter(inAppFilter(userID)),
+		bson.M{"$set": bson.M{"read_at": time.Now()}})
+	if err != nil {
+		log.Error("Failed to mark notifications read", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": result.ModifiedCount})
+}
+
+func (n *Impl) countUnread(c *gin.Context, userID string) (int64, error) {
+	return n.collections.Notifications.CountDocuments(c, unreadFilter(inAppFilter(userID)))
+}
+
+// inAppFilter matches the user's notifications shown in the notification centre
+func inAppFilter(userID string) bson.M {
+	return bson.M{"user_id": userID, "in_app": true}
+}
+
+// unreadFilter narrows filter to unread notifications
+func unreadFilter(filter bson.M) bson.M {
+	unread := bson.M{"read_at": bson.M{"$exists": false}}
+	for key, value := range filter {
+		unread[key] = value
+	}
+	return unread
+}
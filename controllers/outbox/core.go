@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultOutboxLimit = 20
+	maxOutboxLimit     = 100
+)
+
+// API is an interface for inspecting and retrying models.OutboxEntry side-effects
+type API interface {
+	AdminListOutbox(ctx *gin.Context)
+	AdminRetryOutboxEntry(ctx *gin.Context)
+}
+
+// Impl holds dependencies for outbox.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using outbox.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// AdminListOutbox lists outbox entries newest first, filtered by ?status= and ?kind=, along with how many
+// entries are in each status. Supports ?page= (from 1) and ?limit= query parameters.
+func (o *Impl) AdminListOutbox(c *gin.Context) {
+	var (
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID))
+		query dto.AdminListOutboxQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.Kind != "" {
+		filter["kind"] = query.Kind
+	}
+
+	page, limit := common.PaginationParams(c, defaultOutboxLimit, maxOutboxLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"email.text": 0, "email.html": 0})
+
+	cursor, err := o.collections.Outbox.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find outbox entries", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var entries []models.OutboxEntry
+	if err = cursor.All(c, &entries); err != nil {
+		log.Error("Failed to decode outbox entries", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	counts, err := o.countByStatus(c, query.Kind)
+	if err != nil {
+		log.Error("Failed to count outbox entries", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.OutboxEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, dto.NewOutboxEntryResponse(entry))
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": results, "counts": counts, "page": page, "limit": limit})
+}
+
+// AdminRetryOutboxEntry puts a dead-lettered entry back in the queue with a fresh set of attempts
+func (o *Impl) AdminRetryOutboxEntry(c *gin.Context) {
+	var (
+		entryID = c.Param("id")
+		log     = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID),
+			"entryID", entryID)
+	)
+
+	now := time.Now()
+	var entry models.OutboxEntry
+	err := o.collections.Outbox.FindOneAndUpdate(c,
+		bson.M{"_id": entryID, "status": models.OutboxDead},
+		bson.M{
+			"$set":   bson.M{"status": models.OutboxPending, "attempts": 0, "available_at": now, "updated_at": now},
+			"$unset": bson.M{"completed_at": ""},
+		},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"email.text": 0, "email.html": 0}),
+	).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No dead-lettered entry with this ID"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to retry outbox entry", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Outbox entry requeued")
+	c.JSON(http.StatusOK, dto.NewOutboxEntryResponse(entry))
+}
+
+// countByStatus returns how many entries, of kind if given, are in each status
+func (o *Impl) countByStatus(c *gin.Context, kind string) (map[models.OutboxStatus]int64, error) {
+	match := bson.M{}
+	if kind != "" {
+		match["kind"] = kind
+	}
+	cursor, err := o.collections.Outbox.Aggregate(c, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Status models.OutboxStatus `bson:"_id"`
+		Count  int64               `bson:"count"`
+	}
+	if err = cursor.All(c, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := map[models.OutboxStatus]int64{
+		models.OutboxPending:    0,
+		models.OutboxProcessing: 0,
+		models.OutboxDone:       0,
+		models.OutboxDead:       0,
+	}
+	for _, group := range groups {
+		counts[group.Status] = group.Count
+	}
+	return counts, nil
+}
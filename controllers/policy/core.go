@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// API is an interface for operations related to models.PolicyVersion
+type API interface {
+	GetCurrentPolicies(ctx *gin.Context)
+	AdminPublishPolicy(ctx *gin.Context)
+	AdminGetPolicyAcceptance(ctx *gin.Context)
+}
+
+// Impl holds dependencies for policy.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using policy.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// GetCurrentPolicies returns the current version of each policy document, for showing at registration
+func (p *Impl) GetCurrentPolicies(c *gin.Context) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID))
+
+	current, err := data.CurrentPolicyVersions(c, p.collections.Policies)
+	if err != nil {
+		log.Error("Failed to look up current policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	results := make([]dto.PolicyVersionResponse, 0, len(current))
+	for _, document := range models.PolicyDocuments {
+		if version, ok := current[document]; ok {
+			results = append(results, dto.NewPolicyVersionResponse(version))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": results})
+}
+
+// AdminPublishPolicy publishes a new version of a policy document. It becomes current immediately, and
+// every user has to accept it on their next authenticated request.
+func (p *Impl) AdminPublishPolicy(c *gin.Context) {
+	var (
+		adminID = c.GetString(common.UserID)
+		log     = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", adminID)
+		req     dto.PublishPolicyRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	version := models.PolicyVersion{
+		ID:          uuid.New().String(),
+		Document:    req.Document,
+		Version:     req.Version,
+		URL:         req.URL,
+		Summary:     req.Summary,
+		PublishedBy: adminID,
+		PublishedAt: now,
+	}
+	if _, err := p.collections.Policies.InsertOne(c, version); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This version has already been published"})
+			return
+		}
+		log.Error("Failed to publish policy", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	audit := models.AuditLog{
+		ID:        uuid.New().String(),
+		Action:    models.AuditPolicyPublished,
+		ActorID:   adminID,
+		TargetID:  version.ID,
+		Metadata:  map[string]string{"document": string(version.Document), "version": version.Version},
+		CreatedAt: now,
+	}
+	if _, err := p.collections.AuditLogs.InsertOne(c, audit); err != nil {
+		log.Error("Failed to audit policy publish", "err", err)
+	}
+
+	log.Info("Published policy", "document", version.Document, "version", version.Version)
+	c.JSON(http.StatusCreated, dto.NewPolicyVersionResponse(version))
+}
+
+// AdminGetPolicyAcceptance reports how many active users accepted each version of a policy document
+func (p *Impl) AdminGetPolicyAcceptance(c *gin.Context) {
+	var (
+		document = models.PolicyDocument(c.Param("document"))
+		log      = slog.With(common.RequestID, c.MustGet(common.RequestID), "document", document)
+	)
+
+	opts := options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}})
+	cursor, err := p.collections.Policies.Find(c, bson.M{"document": document}, opts)
+	if err != nil {
+		log.Error("Failed to find policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var versions []models.PolicyVersion
+	if err = cursor.All(c, &versions); err != nil {
+		log.Error("Failed to decode policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No versions published for this document"})
+		return
+	}
+
+	active := bson.M{
+		"verified":    true,
+		"deleted_at":  bson.M{"$exists": false},
+		"disabled_at": bson.M{"$exists": false},
+	}
+	activeUsers, err := p.collections.Users.CountDocuments(c, active)
+	if err != nil {
+		log.Error("Failed to count active users", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	report := dto.PolicyAcceptanceReport{
+		Document:       document,
+		CurrentVersion: versions[0].Version,
+		ActiveUsers:    activeUsers,
+		Versions:       make([]dto.PolicyVersionAcceptance, 0, len(versions)),
+	}
+	for _, version := range versions {
+		filter := bson.M{"consents": bson.M{"$elemMatch": bson.M{"document": document, "version": version.Version}}}
+		for key, value := range active {
+			filter[key] = value
+		}
+		accepted, err := p.collections.Users.CountDocuments(c, filter)
+		if err != nil {
+			log.Error("Failed to count acceptances", "err", err, "version", version.Version)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+
+		var rate float64
+		if activeUsers > 0 {
+			rate = float64(accepted) / float64(activeUsers)
+		}
+		report.Versions = append(report.Versions, dto.PolicyVersionAcceptance{
+			Version:     version.Version,
+			PublishedAt: version.PublishedAt,
+			Accepted:    accepted,
+			Rate:        rate,
+		})
+	}
+	c.JSON(http.StatusOK, report)
+}
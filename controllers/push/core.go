@@ -0,0 +1,165 @@
+package push
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	webpush "github.com/harisnkr/expense/push"
+)
+
+// maxSubscriptionsPerUser caps the devices a user may receive push notifications on
+const maxSubscriptionsPerUser = 10
+
+// API is an interface for the authenticated user's devices subscribed to Web Push, models.PushSubscription
+type API interface {
+	GetVAPIDKey(ctx *gin.Context)
+	Subscribe(ctx *gin.Context)
+	ListSubscriptions(ctx *gin.Context)
+	Unsubscribe(ctx *gin.Context)
+}
+
+// Impl holds dependencies for push.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+}
+
+// New returns Impl struct with dependencies for using push.API
+func New(database *mongo.Client, collections *data.Collections) *Impl {
+	return &Impl{database, collections}
+}
+
+// GetVAPIDKey returns the public key browsers subscribe with, as the applicationServerKey
+func (p *Impl) GetVAPIDKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"publicKey": config.VAPIDPublicKey})
+}
+
+// Subscribe saves the push subscription of the calling device for the authenticated user. A device that
+// subscribes again, e.g. after its push service rotated its keys, replaces its previous subscription.
+func (p *Impl) Subscribe(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.SubscribePushRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webpush.ValidateEndpoint(req.Endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, _, err := webpush.ParseKeys(req.Keys.P256dh, req.Keys.Auth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, err := p.collections.PushSubscriptions.CountDocuments(c,
+		bson.M{"user_id": userID, "endpoint": bson.M{"$ne": req.Endpoint}})
+	if err != nil {
+		log.Error("Failed to count push subscriptions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if count >= maxSubscriptionsPerUser {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many devices, unsubscribe one first"})
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"user_id":    userID,
+		"p256dh":     req.Keys.P256dh,
+		"auth":       req.Keys.Auth,
+		"vapid_key":  config.VAPIDPublicKey,
+		"user_agent": c.Request.UserAgent(),
+		"updated_at": now,
+	}
+	update := bson.M{"$set": set, "$setOnInsert": bson.M{"_id": uuid.New().String(), "created_at": now}}
+	if req.ExpirationTime != nil {
+		set["expires_at"] = time.UnixMilli(*req.ExpirationTime)
+	} else {
+		update["$unset"] = bson.M{"expires_at": ""}
+	}
+
+	// the endpoint identifies the device, which moves to whoever is signed in on it now
+	var subscription models.PushSubscription
+	err = p.collections.PushSubscriptions.FindOneAndUpdate(c, bson.M{"endpoint": req.Endpoint}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&subscription)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Subscription is being saved, try again"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to save push subscription", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Push subscription saved", "subscriptionID", subscription.ID)
+	c.JSON(http.StatusCreated, dto.NewPushSubscriptionResponse(subscription))
+}
+
+// ListSubscriptions lists the authenticated user's devices subscribed to push notifications
+func (p *Impl) ListSubscriptions(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	cursor, err := p.collections.PushSubscriptions.Find(c, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		log.Error("Failed to find push subscriptions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var subscriptions []models.PushSubscription
+	if err = cursor.All(c, &subscriptions); err != nil {
+		log.Error("Failed to decode push subscriptions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.PushSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		results = append(results, dto.NewPushSubscriptionResponse(subscription))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": results})
+}
+
+// Unsubscribe stops pushing to one of the authenticated user's devices
+func (p *Impl) Unsubscribe(c *gin.Context) {
+	var (
+		userID         = c.GetString(common.UserID)
+		subscriptionID = c.Param("id")
+		log            = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID,
+			"subscriptionID", subscriptionID)
+	)
+
+	result, err := p.collections.PushSubscriptions.DeleteOne(c, bson.M{"_id": subscriptionID, "user_id": userID})
+	if err != nil {
+		log.Error("Failed to delete push subscription", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	log.Info("Push subscription deleted")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+)
+
+// SendDeleteCode emails the authenticated user a code confirming DeleteUser, for accounts without a password
+func (u *Impl) SendDeleteCode(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := u.sendAccountCode(c, user, false); err != nil {
+		log.Error("Failed to send account deletion code", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "A code has been sent to your email address"})
+}
+
+// SendRestoreCode emails a code confirming RestoreUser to an account pending deletion, for accounts without a
+// password. The response is the same whether or not the email has a restorable account, to avoid leaking
+// accounts.
+func (u *Impl) SendRestoreCode(c *gin.Context) {
+	var (
+		req dto.RestoreCodeRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log = log.With("email", req.Email)
+	accepted := gin.H{"message": "If the email has an account pending deletion, a code has been sent."}
+
+	var user models.User
+	filter := bson.M{"email": req.Email, "purge_after": bson.M{"$gt": time.Now()}}
+	if err := u.collections.Users.FindOne(c, filter).Decode(&user); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Error("Failed to find user", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		log.Info("Restore code requested for no restorable account")
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+	if err := u.sendAccountCode(c, user, true); err != nil {
+		log.Error("Failed to send account restore code", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// sendAccountCode stores a new account code for user, replacing any earlier one, and emails it to them
+func (u *Impl) sendAccountCode(ctx context.Context, user models.User, restore bool) error {
+	code := common.GenerateOTP()
+	update := bson.M{"$set": bson.M{
+		"account_code":            code,
+		"account_code_expires_at": time.Now().Add(config.AccountCodeTTL),
+	}}
+	if _, err := u.collections.Users.UpdateOne(ctx, bson.M{"_id": user.ID}, update); err != nil {
+		return err
+	}
+	return sendEmail(ctx, u.outbox, user, email.TemplateAccountCode, email.AccountCodeData{
+		Name:     user.FirstName,
+		Code:     code,
+		Restore:  restore,
+		ValidFor: config.AccountCodeTTL,
+	})
+}
+
+// reauthenticated checks the password, or the account code if one is given, re-authenticates user for
+// deleting or restoring their account, responding if not. The code is only used up by the update that
+// withAccountCode guards.
+func reauthenticated(c *gin.Context, log *slog.Logger, user models.User, password, code string,
+	now time.Time) bool {
+	if code != "" {
+		if !accountCodeValid(user, code, now) {
+			log.Warn("Invalid or expired account code entered")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
+			return false
+		}
+		return true
+	}
+	if user.Password == "" {
+		c.JSON(http.StatusUnauthorized,
+			gin.H{"error": "This account has no password, confirm with a code sent to your email instead"})
+		return false
+	}
+	if ok, _, err := common.VerifyPassword(user.Password, password); err != nil || !ok {
+		log.Warn("Invalid password entered to re-authenticate")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return false
+	}
+	return true
+}
+
+// accountCodeValid reports whether code is user's unexpired account code
+func accountCodeValid(user models.User, code string, now time.Time) bool {
+	return user.AccountCode != "" && now.Before(user.AccountCodeExpiresAt) &&
+		subtle.ConstantTimeCompare([]byte(code), []byte(user.AccountCode)) == 1
+}
+
+// withAccountCode extends filter to only match while code is still the user's unexpired account code, so
+// that an update also unsetting it uses the code once. Without a code filter is returned as is.
+func withAccountCode(filter bson.M, code string, now time.Time) bson.M {
+	if code == "" {
+		return filter
+	}
+	filter["account_code"] = code
+	filter["account_code_expires_at"] = bson.M{"$gt": now}
+	return filter
+}
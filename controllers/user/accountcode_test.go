@@ -0,0 +1,83 @@
+package user
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/models"
+)
+
+func TestReauthenticatedWithoutPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var (
+		now  = time.Now()
+		log  = slog.New(slog.NewTextHandler(io.Discard, nil))
+		user = models.User{ID: "user-1", Email: "ada@example.com", AccountCode: "12345678",
+			AccountCodeExpiresAt: now.Add(time.Minute)}
+	)
+	for _, tc := range []struct {
+		name      string
+		user      models.User
+		password  string
+		code      string
+		ok        bool
+		wantError string
+	}{
+		{name: "code", user: user, code: "12345678", ok: true},
+		{name: "wrong code", user: user, code: "87654321", wantError: "Invalid or expired code"},
+		{name: "expired code", user: withCodeExpiry(user, now.Add(-time.Second)), code: "12345678",
+			wantError: "Invalid or expired code"},
+		{name: "no code sent", user: models.User{ID: "user-1"}, code: "12345678",
+			wantError: "Invalid or expired code"},
+		{name: "password", user: user, password: "hunter2", wantError: "This account has no password"},
+		{name: "code for an account with a password", user: withPassword(user), code: "12345678", ok: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+
+			if ok := reauthenticated(c, log, tc.user, tc.password, tc.code, now); ok != tc.ok {
+				t.Fatalf("reauthenticated = %v, want %v (response %s)", ok, tc.ok, recorder.Body)
+			}
+			if tc.ok {
+				return
+			}
+			if recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), tc.wantError) {
+				t.Errorf("response = %d %s, want 401 %q", recorder.Code, recorder.Body, tc.wantError)
+			}
+		})
+	}
+}
+
+func TestWithAccountCodeUsesTheCodeOnce(t *testing.T) {
+	now := time.Now()
+	filter := withAccountCode(bson.M{"_id": "user-1"}, "12345678", now)
+	if filter["account_code"] != "12345678" {
+		t.Errorf("filter = %v, want it to match the code", filter)
+	}
+	if expiry, ok := filter["account_code_expires_at"].(bson.M); !ok || expiry["$gt"] != now {
+		t.Errorf("filter = %v, want it to match only unexpired codes", filter)
+	}
+
+	if filter = withAccountCode(bson.M{"_id": "user-1"}, "", now); len(filter) != 1 {
+		t.Errorf("filter for a password = %v, want it unchanged", filter)
+	}
+}
+
+func withCodeExpiry(user models.User, expiresAt time.Time) models.User {
+	user.AccountCodeExpiresAt = expiresAt
+	return user
+}
+
+func withPassword(user models.User) models.User {
+	user.Password = "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+	return user
+}
@@ -0,0 +1,304 @@
+package user
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultAdminUsersLimit = 20
+	maxAdminUsersLimit     = 100
+)
+
+// AdminListUsers lists users newest first, filtered by ?verified=, ?createdFrom=, ?createdTo= (RFC 3339)
+// and ?email= prefix. Supports ?page= (from 1) and ?limit= query parameters.
+func (u *Impl) AdminListUsers(c *gin.Context) {
+	var (
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID))
+		query dto.AdminListUsersQuery
+	)
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if query.Verified != nil {
+		filter["verified"] = *query.Verified
+	}
+	created := bson.M{}
+	if !query.CreatedFrom.IsZero() {
+		created["$gte"] = query.CreatedFrom
+	}
+	if !query.CreatedTo.IsZero() {
+		created["$lt"] = query.CreatedTo
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	if query.EmailPrefix != "" {
+		filter["email"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query.EmailPrefix), "$options": "i"}
+	}
+
+	page, limit := common.PaginationParams(c, defaultAdminUsersLimit, maxAdminUsersLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	total, err := u.collections.Users.CountDocuments(c, filter)
+	if err != nil {
+		log.Error("Failed to count users", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	cursor, err := u.collections.Users.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find users", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var users []models.User
+	if err = cursor.All(c, &users); err != nil {
+		log.Error("Failed to decode users", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.AdminUserSummary, 0, len(users))
+	for _, user := range users {
+		results = append(results, dto.NewAdminUserSummary(user))
+	}
+	c.JSON(http.StatusOK, gin.H{"users": results, "page": page, "limit": limit, "total": total})
+}
+
+// AdminGetUser returns a user's details and account state
+func (u *Impl) AdminGetUser(c *gin.Context) {
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// AdminVerifyUser marks a user's email as verified without them entering the code
+func (u *Impl) AdminVerifyUser(c *gin.Context) {
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	if user.Verified {
+		c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"verified": true, "updated_at": time.Now()},
+		"$unset": bson.M{"verification_code": ""},
+	}
+	if updated, ok := u.applyAdminUpdate(c, user, update, models.AuditUserForceVerified, nil); ok {
+		u.publishVerified(c, updated)
+	}
+}
+
+// AdminDisableUser blocks a user from signing in and ends their sessions, until AdminEnableUser
+func (u *Impl) AdminDisableUser(c *gin.Context) {
+	var req dto.AdminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	if user.ID == c.GetString(common.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot disable themselves"})
+		return
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already disabled"})
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{"disabled_at": time.Now(), "disabled_reason": req.Reason, "updated_at": time.Now()},
+		// sign-in links and challenges issued before the account was disabled must not work afterwards
+		"$unset": bson.M{
+			"magic_link_id": "", "magic_link_expires_at": "", "passkey_challenge": "",
+			"password_reset_id": "", "password_reset_expires_at": "",
+		},
+	}
+	u.applyAdminUpdate(c, user, update, models.AuditUserDisabled, map[string]string{"reason": req.Reason})
+}
+
+// AdminEnableUser re-enables a disabled user
+func (u *Impl) AdminEnableUser(c *gin.Context) {
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	if user.DisabledAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is not disabled"})
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"disabled_at": "", "disabled_reason": ""},
+	}
+	u.applyAdminUpdate(c, user, update, models.AuditUserEnabled, nil)
+}
+
+// AdminResetPassword ends the user's sessions and emails them a link to choose a new password, see
+// ResetPassword
+func (u *Impl) AdminResetPassword(c *gin.Context) {
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", c.GetString(common.UserID),
+		"userID", user.ID)
+	if accountBlocked(c, user) {
+		return
+	}
+
+	// an admin resets a password when the account may be compromised, so its sessions end right away
+	update := bson.M{"$set": bson.M{"sessions_valid_after": time.Now(), "updated_at": time.Now()}}
+	if _, err := u.collections.Users.UpdateOne(c, bson.M{"_id": user.ID}, update); err != nil {
+		log.Error("Failed to end sessions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if err := u.sendPasswordReset(c, user); err != nil {
+		log.Error("Failed to send password reset", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if err := insertAudit(c, u.collections, models.AuditPasswordResetSent, c.GetString(common.UserID), user.ID,
+		nil); err != nil {
+		log.Error("Failed to audit password reset", "err", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Password reset link sent."})
+}
+
+// AdminImpersonateUser issues a short-lived session token for acting as a user, e.g. to reproduce an issue.
+// The token carries the admin as its actor, every response to it is marked with common.ImpersonatedByHeader,
+// and it cannot reach admin endpoints. Admins, deleted and disabled users cannot be impersonated.
+func (u *Impl) AdminImpersonateUser(c *gin.Context) {
+	var (
+		adminID = c.GetString(common.UserID)
+		req     dto.AdminReasonRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := u.findAdminTarget(c)
+	if !ok {
+		return
+	}
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", adminID, "userID", user.ID)
+	if user.Role == models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins cannot be impersonated"})
+		return
+	}
+	if accountBlocked(c, user) {
+		return
+	}
+
+	tokenString, tokenID, err := signImpersonationJWT(user, adminID)
+	if err != nil {
+		log.Error("Failed to sign impersonation token", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	// no audit record, no token
+	metadata := map[string]string{"reason": req.Reason, "token_id": tokenID, "ttl": config.ImpersonationTTL.String()}
+	if err = insertAudit(c, u.collections, models.AuditUserImpersonated, adminID, user.ID, metadata); err != nil {
+		log.Error("Failed to audit impersonation", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Warn("Issued impersonation token", "tokenID", tokenID)
+	c.JSON(http.StatusOK, dto.ImpersonationResponse{
+		SessionToken:   tokenString,
+		ExpiresIn:      config.ImpersonationTTL.String(),
+		ImpersonatedID: user.ID,
+	})
+}
+
+// findAdminTarget loads the user named by the :id route parameter, responding and returning false if it fails
+func (u *Impl) findAdminTarget(c *gin.Context) (models.User, bool) {
+	var user models.User
+	err := u.collections.Users.FindOne(c, bson.M{"_id": c.Param("id")}).Decode(&user)
+	if err == nil {
+		return user, true
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	slog.Error("Failed to find user", common.RequestID, c.MustGet(common.RequestID), "err", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+	return user, false
+}
+
+// applyAdminUpdate updates user, records action in the audit log and responds with the updated user,
+// which it also returns along with whether the update succeeded
+func (u *Impl) applyAdminUpdate(c *gin.Context, user models.User, update bson.M, action string,
+	metadata map[string]string) (models.User, bool) {
+	adminID := c.GetString(common.UserID)
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "adminID", adminID, "userID", user.ID,
+		"action", action)
+
+	var updated models.User
+	err := u.collections.Users.FindOneAndUpdate(c, bson.M{"_id": user.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		log.Error("Failed to update user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return updated, false
+	}
+	if err = insertAudit(c, u.collections, action, adminID, user.ID, metadata); err != nil {
+		log.Error("Failed to audit admin action", "err", err)
+	}
+	log.Info("Admin updated user")
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(updated))
+	return updated, true
+}
+
+// signImpersonationJWT signs a session token for user with adminID as its actor, returning the token and its jti
+func signImpersonationJWT(user models.User, adminID string) (string, string, error) {
+	now := time.Now()
+	jti := uuid.New().String()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"email": user.Email,
+		"jti":   jti,
+		"exp":   now.Add(config.ImpersonationTTL).Unix(),
+		"iat":   now.Unix(),
+		"iss":   common.Issuer,
+		"nbf":   now.Unix(),
+		"sub":   user.ID,
+		"aud":   user.ID,
+		"act":   map[string]string{"sub": adminID},
+	})
+	tokenString, err := token.SignedString(config.ECDSAKey)
+	return tokenString, jti, err
+}
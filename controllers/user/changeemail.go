@@ -0,0 +1,164 @@
+package user
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// ChangeEmail starts changing the authenticated user's email address by sending a code to the new address.
+// The address only changes once ConfirmEmailChange is called with that code.
+func (u *Impl) ChangeEmail(c *gin.Context) {
+	var (
+		req    dto.ChangeEmailRequest
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Error("Failed to find user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if user.Password != "" {
+		ok, _, err := common.VerifyPassword(user.Password, req.Password)
+		if err != nil {
+			log.Error("Failed to verify password hash", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		if !ok {
+			log.Warn("Invalid password entered for email change")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return
+		}
+	}
+	if req.Email == user.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This is already your email address"})
+		return
+	}
+	if taken, err := u.emailTaken(c, req.Email); err != nil || taken {
+		if err != nil {
+			log.Error("Failed to check email", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	code := common.GenerateOTP()
+	update := bson.M{"$set": bson.M{
+		"pending_email":            req.Email,
+		"pending_email_code":       code,
+		"pending_email_expires_at": time.Now().Add(config.EmailChangeTTL),
+		"updated_at":               time.Now(),
+	}}
+	if _, err := u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+		log.Error("Failed to store pending email", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	// the code goes to the new address, proving the user owns it
+	recipient := user
+	recipient.Email, recipient.VerificationCode = req.Email, code
+	if err := sendVerificationEmail(c, u.outbox, recipient); err != nil {
+		log.Error("Failed to send email change code", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	log.Info("Sent email change code")
+	c.JSON(http.StatusAccepted, gin.H{"message": "A code has been sent to the new email address"})
+}
+
+// ConfirmEmailChange changes the authenticated user's email address to the pending one, given the code sent
+// to it. The user is alerted at their previous address.
+func (u *Impl) ConfirmEmailChange(c *gin.Context) {
+	var (
+		req    dto.ConfirmEmailChangeRequest
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var pending models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&pending); err != nil {
+		log.Error("Failed to find user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if pending.PendingEmail != "" {
+		// someone may have registered the address since the code was sent
+		if taken, err := u.emailTaken(c, pending.PendingEmail); err != nil || taken {
+			if err != nil {
+				log.Error("Failed to check email", "err", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+	}
+
+	// matching and clearing the code in one update makes it single-use
+	var user models.User
+	err := u.collections.Users.FindOneAndUpdate(c,
+		bson.M{
+			"_id":                      userID,
+			"pending_email":            pending.PendingEmail,
+			"pending_email_code":       req.Code,
+			"pending_email_expires_at": bson.M{"$gt": time.Now()},
+		},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{
+				{Key: "email", Value: "$pending_email"},
+				{Key: "updated_at", Value: time.Now()},
+			}}},
+			{{Key: "$unset", Value: bson.A{"pending_email", "pending_email_code", "pending_email_expires_at"}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		log.Info("Invalid or expired email change code")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to change email", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Changed email")
+	u.recordSecurityEvent(c, user, models.EmailChanged, map[string]string{"new_email": user.PendingEmail})
+
+	user.Email = user.PendingEmail
+	user.PendingEmail, user.PendingEmailCode, user.PendingEmailExpiresAt = "", "", time.Time{}
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
+}
+
+// emailTaken reports whether a user already has email
+func (u *Impl) emailTaken(c *gin.Context, email string) (bool, error) {
+	count, err := u.collections.Users.CountDocuments(c, bson.M{"email": email})
+	return count > 0, err
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// GetConsents lists the policy versions the authenticated user accepted, and the current ones still pending
+func (u *Impl) GetConsents(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	current, err := data.CurrentPolicyVersions(c, u.collections.Policies)
+	if err != nil {
+		log.Error("Failed to look up current policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewConsentsResponse(user.Consents, user.PendingPolicies(current)))
+}
+
+// AcceptPolicies records the authenticated user accepting current policy versions.
+// Only current versions can be accepted, so clients cannot accept a version the user was never shown.
+func (u *Impl) AcceptPolicies(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.AcceptPoliciesRequest
+	)
+	if c.GetString(common.ImpersonatorID) != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Policies cannot be accepted while impersonating"})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := data.CurrentPolicyVersions(c, u.collections.Policies)
+	if err != nil {
+		log.Error("Failed to look up current policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var user models.User
+	if err = u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var consents []models.Consent
+	for _, acceptance := range req.Accept {
+		version, ok := current[acceptance.Document]
+		if !ok || version.Version != acceptance.Version {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only the current version of " +
+				string(acceptance.Document) + " can be accepted"})
+			return
+		}
+		if !user.HasConsented(version.Document, version.Version) {
+			consent := newConsent(c, version)
+			consents = append(consents, consent)
+			user.Consents = append(user.Consents, consent)
+		}
+	}
+
+	if len(consents) > 0 {
+		update := bson.M{
+			"$push": bson.M{"consents": bson.M{"$each": consents}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		}
+		if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+			log.Error("Failed to record consents", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		log.Info("Recorded policy consents", "count", len(consents))
+	}
+	c.JSON(http.StatusOK, dto.NewConsentsResponse(user.Consents, user.PendingPolicies(current)))
+}
+
+func newConsent(c *gin.Context, version models.PolicyVersion) models.Consent {
+	return models.Consent{
+		Document:   version.Document,
+		Version:    version.Version,
+		AcceptedAt: time.Now(),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+}
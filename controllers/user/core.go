@@ -1,81 +1,98 @@
 package user
 
 import (
-	"fmt"
+	"context"
 	"log/slog"
-	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/harisnkr/expense/common"
 	"github.com/harisnkr/expense/config"
 	"github.com/harisnkr/expense/data"
 	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/email"
 	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+	"github.com/harisnkr/expense/outbox"
+	"github.com/harisnkr/expense/storage"
+	"github.com/harisnkr/expense/webhook"
 )
 
 // API is an interface for operations related to models.User
 type API interface {
 	RegisterUser(ctx *gin.Context)
+	UsernameAvailable(ctx *gin.Context)
+	GetConsents(ctx *gin.Context)
+	AcceptPolicies(ctx *gin.Context)
 	VerifyEmail(ctx *gin.Context)
 	Login(ctx *gin.Context)
+	SendMagicLink(ctx *gin.Context)
+	ConsumeMagicLink(ctx *gin.Context)
+	BeginPasskeyRegistration(ctx *gin.Context)
+	FinishPasskeyRegistration(ctx *gin.Context)
+	BeginPasskeyLogin(ctx *gin.Context)
+	FinishPasskeyLogin(ctx *gin.Context)
+	RemovePasskey(ctx *gin.Context)
+	GetSecurityEvents(ctx *gin.Context)
+	RequestExport(ctx *gin.Context)
+	GetExport(ctx *gin.Context)
+	DownloadExport(ctx *gin.Context)
+	UploadProfilePicture(ctx *gin.Context)
+	DeleteProfilePicture(ctx *gin.Context)
+	GetMe(ctx *gin.Context)
 	UpdateProfile(ctx *gin.Context)
+	ChangeEmail(ctx *gin.Context)
+	ConfirmEmailChange(ctx *gin.Context)
+	GetPreferences(ctx *gin.Context)
+	UpdatePreferences(ctx *gin.Context)
 	DeleteUser(ctx *gin.Context)
-	GetEmailOTP(ctx *gin.Context)
+	SendDeleteCode(ctx *gin.Context)
+	RestoreUser(ctx *gin.Context)
+	SendRestoreCode(ctx *gin.Context)
+	RequestPasswordReset(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	AdminListUsers(ctx *gin.Context)
+	AdminGetUser(ctx *gin.Context)
+	AdminVerifyUser(ctx *gin.Context)
+	AdminDisableUser(ctx *gin.Context)
+	AdminEnableUser(ctx *gin.Context)
+	AdminResetPassword(ctx *gin.Context)
+	AdminImpersonateUser(ctx *gin.Context)
 }
 
 // Impl is the implementation for user.API
 type Impl struct {
 	database    *mongo.Client
 	collections *data.Collections
+	blobs       storage.BlobStore
+	outbox      *outbox.Outbox
+	notifier    *notify.Notifier
+	webhooks    *webhook.Publisher
+	webAuthn    *webauthn.WebAuthn
 }
 
 // New creates and returns a new user.API implementation for usage with routes
-func New(database *mongo.Client, collections *data.Collections) *Impl {
-	return &Impl{database, collections}
+func New(database *mongo.Client, collections *data.Collections, blobs storage.BlobStore, box *outbox.Outbox,
+	notifier *notify.Notifier, webhooks *webhook.Publisher) *Impl {
+	return &Impl{database, collections, blobs, box, notifier, webhooks, newWebAuthn()}
 }
 
-// DeleteUser deletes the authenticated user's profile
-func (u *Impl) DeleteUser(_ *gin.Context) {
-	//TODO implement me
-	panic("implement me")
-}
-
-// GetEmailOTP is an internal endpoint (used for testing) to retrieve OTP assigned to user's email
-func (u *Impl) GetEmailOTP(c *gin.Context) {
-	var (
-		email = c.Query("email")
-		log   = slog.With(common.RequestID, c.MustGet(common.RequestID))
-	)
-	emailEscaped, _ := url.QueryUnescape(email)
-	log = log.With("email", emailEscaped)
-	log.Info("getting email OTP")
-
-	var user models.User
-	err := u.collections.Users.FindOne(c, bson.M{"email": strings.TrimSpace(emailEscaped)}).Decode(&user)
-	if err != nil {
-		log.Warn("OTP not found for given email")
-		c.JSON(http.StatusNotFound, gin.H{"message": "Unknown error"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"otp": user.VerificationCode})
-}
-
-func populateUserEntry(newUser *models.User, req *dto.RegisterUserRequest, hashedPassword []byte) string {
+func populateUserEntry(newUser *models.User, req *dto.RegisterUserRequest, hashedPassword string) {
 	newUser.ID = uuid.New().String()
 	newUser.Email = req.Email
 	newUser.FirstName = req.FirstName
 	newUser.LastName = req.LastName
-	newUser.Password = string(hashedPassword)
-	otp := common.GenerateOTP()
-	newUser.VerificationCode = otp
+	newUser.Password = hashedPassword
+	if req.Username != "" {
+		newUser.Username = req.Username
+		newUser.UsernameLower = common.NormalizeUsername(req.Username)
+	}
+	newUser.VerificationCode = common.GenerateOTP()
 	newUser.VerificationSentAt = time.Now()
 	newUser.UpdatedAt = time.Now()
 	newUser.CreatedAt = time.Now()
@@ -83,8 +100,9 @@ func populateUserEntry(newUser *models.User, req *dto.RegisterUserRequest, hashe
 	newUser.Transactions = []models.Transaction{}
 	newUser.Budgets = []models.Budget{}
 	newUser.Savings = []models.Savings{}
-
-	return otp
+	newUser.Passkeys = []models.Passkey{}
+	newUser.KnownDevices = []models.KnownDevice{}
+	newUser.Consents = []models.Consent{}
 }
 
 func generateSessionJWT(c *gin.Context, user models.User) (time.Duration, string) {
@@ -93,6 +111,16 @@ func generateSessionJWT(c *gin.Context, user models.User) (time.Duration, string
 		tokenTTL = config.SessionTokenTTLInHours
 	)
 
+	tokenString, err := signSessionJWT(user)
+	if err != nil {
+		log.Error("failed to generate jwt", "err", err)
+		return time.Duration(0), tokenString
+	}
+	return tokenTTL, tokenString
+}
+
+// signSessionJWT signs a session token for user with the service's ECDSA key
+func signSessionJWT(user models.User) (string, error) {
 	exp := time.Now().Add(config.SessionTokenTTLInHours).Unix()
 	iat := time.Now().Unix()
 	nbf := time.Now().Unix()
@@ -110,15 +138,27 @@ func generateSessionJWT(c *gin.Context, user models.User) (time.Duration, string
 		"sub":   sub,
 		"aud":   aud,
 	})
-	tokenString, err := token.SignedString(config.ECDSAKey)
-	if err != nil {
-		log.Error("failed to generate jwt", err)
-		return time.Duration(0), tokenString
-	}
-	return tokenTTL, tokenString
+	return token.SignedString(config.ECDSAKey)
+}
+
+func sendVerificationEmail(ctx context.Context, box *outbox.Outbox, user models.User) error {
+	return sendEmail(ctx, box, user, email.TemplateVerification, email.VerificationData{
+		Name: user.FirstName,
+		Code: user.VerificationCode,
+	})
+}
+
+func sendMagicLinkEmail(ctx context.Context, box *outbox.Outbox, user models.User, link string) error {
+	return sendEmail(ctx, box, user, email.TemplateMagicLink,
+		email.MagicLinkData{Link: link, ValidFor: config.MagicLinkTTL})
+}
+
+func sendPasswordResetEmail(ctx context.Context, box *outbox.Outbox, user models.User, link string) error {
+	return sendEmail(ctx, box, user, email.TemplatePasswordReset,
+		email.PasswordResetData{Link: link, ValidFor: config.PasswordResetTTL})
 }
 
-func sendVerificationEmail(c *gin.Context, email, token string) {
-	// TODO: Implement email sending logic here]
-	slog.InfoContext(c, fmt.Sprintf("Sending verification email to %s with OTP: %s", email, token))
+// sendEmail enqueues the named template, rendered in the user's locale, for delivery by the outbox worker
+func sendEmail(ctx context.Context, box *outbox.Outbox, user models.User, template string, data interface{}) error {
+	return box.EnqueueEmail(ctx, user.Email, user.EffectivePreferences().Locale, template, data)
 }
@@ -0,0 +1,278 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/storage"
+)
+
+// DeleteUser soft-deletes the authenticated user's account after re-checking their password, or the code
+// from SendDeleteCode for accounts without one. The account can be restored with RestoreUser until the grace
+// period ends, then PurgeDeletedUsers removes it.
+func (u *Impl) DeleteUser(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.DeleteUserRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	now := time.Now()
+	if !reauthenticated(c, log, user, req.Password, req.Code, now) {
+		return
+	}
+
+	purgeAfter := now.Add(config.DeletionGracePeriod)
+	update := bson.M{
+		"$set": bson.M{"deleted_at": now, "purge_after": purgeAfter, "updated_at": now},
+		// outstanding sign-in links, challenges and codes must not outlive the account
+		"$unset": bson.M{
+			"magic_link_id": "", "magic_link_expires_at": "", "passkey_challenge": "",
+			"account_code": "", "account_code_expires_at": "",
+		},
+	}
+	result, err := u.collections.Users.UpdateOne(c, withAccountCode(bson.M{"_id": userID}, req.Code, now), update)
+	if err != nil {
+		log.Error("Failed to soft delete user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		// the code was used by a concurrent request
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+
+	log.Info("User scheduled for deletion", "purgeAfter", purgeAfter)
+	c.JSON(http.StatusOK, dto.DeleteUserResponse{
+		Message:      "Account deleted. It can be restored until the date below, after which it is permanently removed.",
+		RestoreUntil: purgeAfter,
+	})
+}
+
+// RestoreUser cancels a pending account deletion within the grace period and starts a new session. The user
+// re-authenticates with their password, or the code from SendRestoreCode for accounts without one.
+func (u *Impl) RestoreUser(c *gin.Context) {
+	var (
+		req dto.RestoreUserRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log = log.With("email", req.Email)
+
+	var user models.User
+	filter := bson.M{"email": req.Email, "purge_after": bson.M{"$gt": time.Now()}}
+	if err := u.collections.Users.FindOne(c, filter).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No restorable account found"})
+			return
+		}
+		log.Error("Failed to find user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	now := time.Now()
+	if !reauthenticated(c, log, user, req.Password, req.Code, now) {
+		return
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"updated_at": now},
+		"$unset": bson.M{"deleted_at": "", "purge_after": "", "account_code": "", "account_code_expires_at": ""},
+	}
+	result, err := u.collections.Users.UpdateOne(c, withAccountCode(filter, req.Code, now), update)
+	if err != nil {
+		log.Error("Failed to restore user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No restorable account found"})
+		return
+	}
+	log.Info("User restored")
+
+	tokenDuration, tokenString := generateSessionJWT(c, user)
+	if tokenString == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate session token"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.UserLoginResponse{
+		SessionToken: tokenString,
+		ExpiresIn:    tokenDuration.String(),
+	})
+}
+
+// PurgeDeletedUsers permanently removes accounts whose deletion grace period has ended, along with
+// everything stored for them, and leaves an audit record for each purge
+func PurgeDeletedUsers(ctx context.Context, collections *data.Collections, blobs storage.BlobStore) {
+	log := slog.With("func", "PurgeDeletedUsers")
+
+	cursor, err := collections.Users.Find(ctx, bson.M{"purge_after": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		log.Error("Failed to find users to purge", "err", err)
+		return
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		log.Error("Failed to decode users to purge", "err", err)
+		return
+	}
+
+	for _, user := range users {
+		if err = purgeUser(ctx, collections, blobs, user); err != nil {
+			log.Error("Failed to purge user", "err", err, "userID", user.ID)
+			continue
+		}
+		log.Info("Purged user", "userID", user.ID)
+	}
+}
+
+func purgeUser(ctx context.Context, collections *data.Collections, blobs storage.BlobStore, user models.User) error {
+	// re-check the grace period in the claim, the user may have restored since the Find
+	claim := bson.M{"_id": user.ID, "purge_after": bson.M{"$lte": time.Now()}}
+	return purgeAccount(ctx, collections, blobs, user, claim, models.AuditUserPurged,
+		map[string]string{"deleted_at": user.DeletedAt.Format(time.RFC3339)})
+}
+
+// purgeAccount permanently removes user if their document still matches claim. The document is marked as
+// purging first, which blocks sign-in, and deleted last, after everything stored for the user and the audit
+// record. Every step can be repeated, so a purge that fails part way is finished by the next run.
+func purgeAccount(ctx context.Context, collections *data.Collections, blobs storage.BlobStore, user models.User,
+	claim bson.M, action string, metadata map[string]string) error {
+	result, err := collections.Users.UpdateOne(ctx, claim, bson.M{"$set": bson.M{"purging_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return nil
+	}
+
+	counts, err := deleteUserData(ctx, collections, blobs, user)
+	if err != nil {
+		return err
+	}
+	for key, value := range metadata {
+		counts[key] = value
+	}
+	if err = insertAuditOnce(ctx, collections, action, user.ID, counts); err != nil {
+		return err
+	}
+	_, err = collections.Users.DeleteOne(ctx, bson.M{"_id": user.ID, "purging_at": bson.M{"$exists": true}})
+	return err
+}
+
+// deleteUserWebhooks deletes the webhooks userID registered for themselves and their delivery logs.
+// Global webhooks an admin registered stay, as they belong to the service rather than the admin.
+func deleteUserWebhooks(ctx context.Context, collections *data.Collections, userID string) (int64, error) {
+	filter := bson.M{"owner_id": userID, "global": false}
+	ids, err := collections.Webhooks.Distinct(ctx, "_id", filter)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	if _, err = collections.WebhookDeliveries.DeleteMany(ctx, bson.M{"webhook_id": bson.M{"$in": ids}}); err != nil {
+		return 0, err
+	}
+	result, err := collections.Webhooks.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// leaveHouseholds removes userID from every household they belong to. Households left empty are deleted,
+// and households left without an owner pass ownership to their longest-standing member.
+func leaveHouseholds(ctx context.Context, collections *data.Collections, userID string) (int64, error) {
+	result, err := collections.Households.UpdateMany(ctx, bson.M{"members.user_id": userID},
+		bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	if err != nil {
+		return 0, err
+	}
+	if _, err = collections.Households.DeleteMany(ctx, bson.M{"members": bson.M{"$size": 0}}); err != nil {
+		return 0, err
+	}
+
+	ownerless := bson.M{"members.role": bson.M{"$ne": models.HouseholdRoleOwner}, "members.0": bson.M{"$exists": true}}
+	_, err = collections.Households.UpdateMany(ctx, ownerless,
+		bson.M{"$set": bson.M{"members.0.role": models.HouseholdRoleOwner}})
+	return result.ModifiedCount, err
+}
+
+// insertAudit appends to the audit log, actorID is the admin's ID or models.AuditActorSystem
+func insertAudit(ctx context.Context, collections *data.Collections, action, actorID, targetID string,
+	metadata map[string]string) error {
+	audit := models.AuditLog{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   actorID,
+		TargetID:  targetID,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	_, err := collections.AuditLogs.InsertOne(ctx, audit)
+	return err
+}
+
+// insertAuditOnce appends a system audit record of action on targetID unless one is already there, for
+// jobs that may repeat the action after a failure
+func insertAuditOnce(ctx context.Context, collections *data.Collections, action, targetID string,
+	metadata map[string]string) error {
+	audit := models.AuditLog{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   models.AuditActorSystem,
+		TargetID:  targetID,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	_, err := collections.AuditLogs.UpdateOne(ctx, bson.M{"action": action, "target_id": targetID},
+		bson.M{"$setOnInsert": audit}, options.Update().SetUpsert(true))
+	return err
+}
+
+// accountBlocked responds and returns true if user has a pending deletion or was disabled by an admin,
+// for sign-in flows to refuse them
+func accountBlocked(c *gin.Context, user models.User) bool {
+	switch {
+	case user.PurgingAt != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is being deleted"})
+	case user.DeletedAt != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is scheduled for deletion, restore it to sign in"})
+	case user.DisabledAt != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+	default:
+		return false
+	}
+	return true
+}
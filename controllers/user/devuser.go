@@ -0,0 +1,63 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// SeedDevUser creates (or reuses) a verified development admin and logs a locally signed
+// session token for it, so user-scoped endpoints can be exercised without registering.
+// It is a no-op unless common.DevAuthEnabled.
+func SeedDevUser(ctx context.Context, collections *data.Collections) {
+	if !common.DevAuthEnabled() {
+		return
+	}
+	log := slog.With("func", "SeedDevUser", "email", config.DevUserEmail)
+
+	var devUser models.User
+	err := collections.Users.FindOne(ctx, bson.M{"email": config.DevUserEmail}).Decode(&devUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		hashedPassword, hashErr := common.HashPassword(common.GenerateOTP() + common.GenerateOTP())
+		if hashErr != nil {
+			log.Error("failed to hash dev user password", "err", hashErr)
+			return
+		}
+		populateUserEntry(&devUser, &dto.RegisterUserRequest{
+			FirstName: "Dev",
+			LastName:  "User",
+			Email:     config.DevUserEmail,
+		}, hashedPassword)
+		devUser.Verified = true
+		devUser.Role = models.RoleAdmin
+		devUser.VerificationCode = ""
+		devUser.VerificationSentAt = time.Time{}
+
+		if _, err = collections.Users.InsertOne(ctx, devUser); err != nil {
+			log.Error("failed to insert dev user", "err", err)
+			return
+		}
+		log.Info("seeded dev user")
+	} else if err != nil {
+		log.Error("failed to find dev user", "err", err)
+		return
+	}
+
+	tokenString, err := signSessionJWT(devUser)
+	if err != nil {
+		log.Error("failed to sign dev user session token", "err", err)
+		return
+	}
+	log.Warn("dev user ready, use either header to authenticate",
+		"Authorization", tokenString, common.DevUserHeader, devUser.ID)
+}
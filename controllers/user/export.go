@@ -0,0 +1,301 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+	"github.com/harisnkr/expense/storage"
+)
+
+// ExportTimeout bounds how long generating a single export archive may take
+const ExportTimeout = 5 * time.Minute
+
+// RequestExport starts generating an archive of all data held for the authenticated user.
+// Generation is asynchronous, poll GetExport for its status and download link.
+func (u *Impl) RequestExport(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var pending models.DataExport
+	err := u.collections.Exports.FindOne(c, bson.M{"user_id": userID, "status": models.ExportPending}).Decode(&pending)
+	if err == nil {
+		c.JSON(http.StatusConflict, dto.NewDataExportResponse(pending, ""))
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Error("Failed to find pending exports", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	now := time.Now()
+	export := models.DataExport{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.ExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.ExportRetention),
+	}
+	if _, err = u.collections.Exports.InsertOne(c, export); err != nil {
+		log.Error("Failed to create export", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	if err = u.outbox.Enqueue(c, models.OutboxEntry{Kind: models.OutboxGenerateExport, ExportID: export.ID}); err != nil {
+		log.Error("Failed to enqueue export", "err", err, "exportID", export.ID)
+		if _, err = u.collections.Exports.DeleteOne(c, bson.M{"_id": export.ID}); err != nil {
+			log.Error("Failed to remove unqueued export", "err", err, "exportID", export.ID)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Export requested", "exportID", export.ID)
+	c.JSON(http.StatusAccepted, dto.NewDataExportResponse(export, ""))
+}
+
+// GetExport returns the status of one of the authenticated user's exports.
+// Once ready, each call issues a fresh single-use download link and invalidates the previous one.
+func (u *Impl) GetExport(c *gin.Context) {
+	var (
+		userID   = c.GetString(common.UserID)
+		exportID = c.Param("id")
+		log      = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID, "exportID", exportID)
+	)
+
+	var export models.DataExport
+	if err := u.collections.Exports.FindOne(c, bson.M{"_id": exportID, "user_id": userID}).Decode(&export); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+			return
+		}
+		log.Error("Failed to find export", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if export.Status != models.ExportReady {
+		c.JSON(http.StatusOK, dto.NewDataExportResponse(export, ""))
+		return
+	}
+
+	tokenString, downloadID, err := common.SignPurposeToken(common.PurposeExportDownload, export.ID, config.ExportLinkTTL)
+	if err != nil {
+		log.Error("Failed to sign export download link", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	filter := bson.M{"_id": export.ID, "status": models.ExportReady}
+	update := bson.M{"$set": bson.M{"download_id": downloadID}}
+	if _, err = u.collections.Exports.UpdateOne(c, filter, update); err != nil {
+		log.Error("Failed to store export download link", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	downloadURL := config.APIBaseURL + "/user/export/download?token=" + url.QueryEscape(tokenString)
+	c.JSON(http.StatusOK, dto.NewDataExportResponse(export, downloadURL))
+}
+
+// DownloadExport serves an export archive through a signed link from GetExport.
+// The link works once: it is used up only once the archive is open, and the archive is deleted after it is served.
+func (u *Impl) DownloadExport(c *gin.Context) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID))
+
+	claims, err := common.ParsePurposeToken(c.Query("token"), common.PurposeExportDownload)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired download link"})
+		return
+	}
+	log = log.With("exportID", claims.Subject)
+
+	filter := bson.M{
+		"_id":         claims.Subject,
+		"download_id": claims.ID,
+		"status":      models.ExportReady,
+		"expires_at":  bson.M{"$gt": time.Now()},
+	}
+	var export models.DataExport
+	if err = u.collections.Exports.FindOne(c, filter).Decode(&export); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusGone, gin.H{"error": "Download link already used or expired"})
+			return
+		}
+		log.Error("Failed to find export", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	blob, err := u.blobs.Get(c, export.BlobKey)
+	if err != nil {
+		log.Error("Failed to open export archive", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	defer blob.Close()
+
+	update := bson.M{
+		"$set":   bson.M{"status": models.ExportDownloaded},
+		"$unset": bson.M{"download_id": ""},
+	}
+	result, err := u.collections.Exports.UpdateOne(c, filter, update)
+	if err != nil {
+		log.Error("Failed to claim export download", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusGone, gin.H{"error": "Download link already used or expired"})
+		return
+	}
+
+	filename := "moneyfly-export-" + export.CreatedAt.Format("2006-01-02") + ".zip"
+	c.Header("Content-Type", storage.ContentType(export.BlobKey))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Content-Length", strconv.FormatInt(export.SizeBytes, 10))
+	c.Status(http.StatusOK)
+	if _, err = io.Copy(c.Writer, blob); err != nil {
+		log.Warn("Failed to write export archive", "err", err)
+	}
+	if err = u.blobs.Delete(c, export.BlobKey); err != nil {
+		log.Error("Failed to remove downloaded export", "err", err)
+	}
+	log.Info("Export downloaded")
+}
+
+// ExpireExports deletes export archives that were never downloaded within the retention period
+func ExpireExports(ctx context.Context, collections *data.Collections, blobs storage.BlobStore) {
+	log := slog.With("func", "ExpireExports")
+
+	filter := bson.M{
+		"status":     bson.M{"$in": []models.ExportStatus{models.ExportPending, models.ExportReady}},
+		"expires_at": bson.M{"$lte": time.Now()},
+	}
+	cursor, err := collections.Exports.Find(ctx, filter)
+	if err != nil {
+		log.Error("Failed to find expired exports", "err", err)
+		return
+	}
+	var exports []models.DataExport
+	if err = cursor.All(ctx, &exports); err != nil {
+		log.Error("Failed to decode expired exports", "err", err)
+		return
+	}
+
+	for _, export := range exports {
+		removeExportArchive(ctx, log, blobs, export)
+		update := bson.M{"$set": bson.M{"status": models.ExportExpired}, "$unset": bson.M{"download_id": ""}}
+		if _, err = collections.Exports.UpdateOne(ctx, bson.M{"_id": export.ID}, update); err != nil {
+			log.Error("Failed to expire export", "err", err, "exportID", export.ID)
+		}
+	}
+}
+
+// deleteUserExports removes every export of a user, archives included
+func deleteUserExports(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+	userID string) (int64, error) {
+	cursor, err := collections.Exports.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	var exports []models.DataExport
+	if err = cursor.All(ctx, &exports); err != nil {
+		return 0, err
+	}
+	for _, export := range exports {
+		removeExportArchive(ctx, slog.Default(), blobs, export)
+	}
+
+	result, err := collections.Exports.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func removeExportArchive(ctx context.Context, log *slog.Logger, blobs storage.BlobStore, export models.DataExport) {
+	if export.BlobKey == "" {
+		return
+	}
+	if err := blobs.Delete(ctx, export.BlobKey); err != nil {
+		log.Error("Failed to remove export archive", "err", err, "exportID", export.ID)
+	}
+}
+
+// ExportHandler generates the archive of a models.OutboxGenerateExport entry. Exports that are no longer
+// pending, because an earlier attempt finished them or they expired, are left as they are.
+func ExportHandler(collections *data.Collections, blobs storage.BlobStore) outbox.Handler {
+	return func(ctx context.Context, entry models.OutboxEntry) error {
+		var export models.DataExport
+		filter := bson.M{"_id": entry.ExportID, "status": models.ExportPending}
+		err := collections.Exports.FindOne(ctx, filter).Decode(&export)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return generateExport(ctx, collections, blobs, export, entry.Attempts >= config.OutboxMaxAttempts)
+	}
+}
+
+// generateExport stores the archive and records the outcome. Failures are returned for the outbox to retry,
+// the export stays pending until the last attempt, which fails it for the user to request again.
+func generateExport(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+	export models.DataExport, lastAttempt bool) error {
+	var (
+		log    = slog.With("func", "generateExport", "exportID", export.ID, "userID", export.UserID)
+		key    = path.Join("exports", export.UserID, export.ID+".zip")
+		filter = bson.M{"_id": export.ID, "status": models.ExportPending}
+	)
+
+	size, err := writeExportArchive(ctx, collections, blobs, export.UserID, key)
+	if err != nil {
+		log.Error("Failed to generate export", "err", err, "lastAttempt", lastAttempt)
+		if lastAttempt {
+			update := bson.M{"$set": bson.M{
+				"status":       models.ExportFailed,
+				"error":        "Export could not be generated, please try again",
+				"completed_at": time.Now(),
+			}}
+			if _, updateErr := collections.Exports.UpdateOne(ctx, filter, update); updateErr != nil {
+				log.Error("Failed to fail export", "err", updateErr)
+			}
+		}
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":       models.ExportReady,
+		"blob_key":     key,
+		"size_bytes":   size,
+		"completed_at": time.Now(),
+	}}
+	if _, err = collections.Exports.UpdateOne(ctx, filter, update); err != nil {
+		log.Error("Failed to update export status", "err", err)
+		return err
+	}
+	log.Info("Export generated", "sizeBytes", size)
+	return nil
+}
@@ -0,0 +1,273 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/storage"
+)
+
+// exportProfile is profile.json in an export archive
+type exportProfile struct {
+	Profile      dto.UserResponse     `json:"profile"`
+	KnownDevices []models.KnownDevice `json:"knownDevices"`
+	ExportedAt   time.Time            `json:"exportedAt"`
+}
+
+// exportConsent is an entry of consents.json, with where the policy was accepted from
+type exportConsent struct {
+	dto.ConsentResponse
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+}
+
+// writeExportArchive stores a zip of everything in userDataSets for userID in blobs under key, each dataset as
+// JSON and the flat ones also as CSV. It returns the size of the archive in bytes.
+func writeExportArchive(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+	userID, key string) (int64, error) {
+	var user models.User
+	err := collections.Users.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	prefs := user.EffectivePreferences()
+	archive := exportArchive{zw: zw, loc: prefs.Location()}
+
+	archive.json("profile.json", exportProfile{
+		Profile:      dto.NewUserResponse(user),
+		KnownDevices: user.KnownDevices,
+		ExportedAt:   prefs.Now(),
+	})
+	for _, set := range userDataSets {
+		if set.export == nil {
+			continue
+		}
+		if err = set.export(ctx, collections, blobs, user, &archive); err != nil {
+			return 0, err
+		}
+	}
+
+	if archive.err != nil {
+		return 0, archive.err
+	}
+	if err = zw.Close(); err != nil {
+		return 0, err
+	}
+	size := int64(buf.Len())
+	if err = blobs.Put(ctx, key, &buf); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// exportArchive writes files into a zip, remembering the first error so callers can check once at the end.
+// CSV timestamps are written in loc, the user's timezone.
+type exportArchive struct {
+	zw  *zip.Writer
+	loc *time.Location
+	err error
+}
+
+func (a *exportArchive) json(name string, v interface{}) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zw.Create(name)
+	if err != nil {
+		a.err = err
+		return
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	a.err = encoder.Encode(v)
+}
+
+func (a *exportArchive) file(name string, r io.Reader) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zw.Create(name)
+	if err != nil {
+		a.err = err
+		return
+	}
+	_, a.err = io.Copy(w, r)
+}
+
+func (a *exportArchive) csv(name string, header []string, rows [][]string) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zw.Create(name)
+	if err != nil {
+		a.err = err
+		return
+	}
+	writer := csv.NewWriter(w)
+	if err = writer.Write(header); err != nil {
+		a.err = err
+		return
+	}
+	if err = writer.WriteAll(rows); err != nil {
+		a.err = err
+	}
+}
+
+func (a *exportArchive) cards(cards []models.Card) {
+	results := dto.NewCardResponses(cards)
+	rows := make([][]string, 0, len(results))
+	for _, card := range results {
+		rows = append(rows, []string{
+			card.ID, card.Name, card.IssuerBank, card.Network,
+			formatFloat(float64(card.Miles.LocalMultiplier)),
+			formatFloat(float64(card.Miles.OverseasMultiplier)),
+			strings.Join(card.Miles.SpendCategories, ";"),
+		})
+	}
+	a.json("cards.json", results)
+	a.csv("cards.csv",
+		[]string{"id", "name", "issuer_bank", "network", "local_multiplier", "overseas_multiplier", "spend_categories"},
+		rows)
+}
+
+func (a *exportArchive) transactions(transactions []models.Transaction) {
+	results := make([]dto.TransactionResponse, 0, len(transactions))
+	rows := make([][]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		t := dto.NewTransactionResponse(transaction)
+		results = append(results, t)
+		rows = append(rows, []string{
+			t.ID, t.Type, t.Category, formatFloat(t.Amount), a.formatTime(t.Date), t.Description,
+			a.formatTime(t.CreatedAt),
+		})
+	}
+	a.json("transactions.json", results)
+	a.csv("transactions.csv",
+		[]string{"id", "type", "category", "amount", "date", "description", "created_at"},
+		rows)
+}
+
+func (a *exportArchive) budgets(budgets []models.Budget) {
+	results := make([]dto.BudgetResponse, 0, len(budgets))
+	rows := make([][]string, 0, len(budgets))
+	for _, budget := range budgets {
+		b := dto.NewBudgetResponse(budget)
+		results = append(results, b)
+		rows = append(rows, []string{
+			b.ID, b.Category, formatFloat(b.Amount), a.formatTime(b.StartDate), a.formatTime(b.EndDate),
+			a.formatTime(b.CreatedAt),
+		})
+	}
+	a.json("budgets.json", results)
+	a.csv("budgets.csv",
+		[]string{"id", "category", "amount", "start_date", "end_date", "created_at"},
+		rows)
+}
+
+func (a *exportArchive) savings(savings []models.Savings) {
+	results := make([]dto.SavingsResponse, 0, len(savings))
+	rows := make([][]string, 0, len(savings))
+	for _, pocket := range savings {
+		s := dto.NewSavingsResponse(pocket)
+		results = append(results, s)
+		rows = append(rows, []string{
+			s.ID, s.Description, formatFloat(s.TargetAmount), formatFloat(s.CurrentAmount), a.formatTime(s.Deadline),
+			a.formatTime(s.CreatedAt),
+		})
+	}
+	a.json("savings.json", results)
+	a.csv("savings.csv",
+		[]string{"id", "description", "target_amount", "current_amount", "deadline", "created_at"},
+		rows)
+}
+
+func (a *exportArchive) securityEvents(events []models.SecurityEvent) {
+	results := make([]dto.SecurityEventResponse, 0, len(events))
+	rows := make([][]string, 0, len(events))
+	for _, event := range events {
+		e := dto.NewSecurityEventResponse(event)
+		results = append(results, e)
+		rows = append(rows, []string{e.ID, e.Type, e.Description, e.IP, e.UserAgent, a.formatTime(e.CreatedAt)})
+	}
+	a.json("security_events.json", results)
+	a.csv("security_events.csv",
+		[]string{"id", "type", "description", "ip", "user_agent", "created_at"},
+		rows)
+}
+
+func (a *exportArchive) consents(consents []models.Consent) {
+	results := make([]exportConsent, 0, len(consents))
+	rows := make([][]string, 0, len(consents))
+	for _, consent := range consents {
+		results = append(results, exportConsent{
+			ConsentResponse: dto.ConsentResponse{
+				Document:   consent.Document,
+				Version:    consent.Version,
+				AcceptedAt: consent.AcceptedAt,
+			},
+			IP:        consent.IP,
+			UserAgent: consent.UserAgent,
+		})
+		rows = append(rows, []string{
+			string(consent.Document), consent.Version, a.formatTime(consent.AcceptedAt), consent.IP, consent.UserAgent,
+		})
+	}
+	a.json("consents.json", results)
+	a.csv("consents.csv", []string{"document", "version", "accepted_at", "ip", "user_agent"}, rows)
+}
+
+func (a *exportArchive) notifications(notifications []models.Notification) {
+	results := make([]dto.NotificationResponse, 0, len(notifications))
+	rows := make([][]string, 0, len(notifications))
+	for _, notification := range notifications {
+		n := dto.NewNotificationResponse(notification)
+		results = append(results, n)
+		var readAt string
+		if n.ReadAt != nil {
+			readAt = a.formatTime(*n.ReadAt)
+		}
+		rows = append(rows, []string{n.ID, n.Type, n.Title, n.Body, readAt, a.formatTime(n.CreatedAt)})
+	}
+	a.json("notifications.json", results)
+	a.csv("notifications.csv",
+		[]string{"id", "type", "title", "body", "read_at", "created_at"},
+		rows)
+}
+
+func (a *exportArchive) pushDevices(subscriptions []models.PushSubscription) {
+	results := make([]dto.PushSubscriptionResponse, 0, len(subscriptions))
+	rows := make([][]string, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		s := dto.NewPushSubscriptionResponse(subscription)
+		results = append(results, s)
+		rows = append(rows, []string{s.ID, s.PushService, s.UserAgent, a.formatTime(s.CreatedAt)})
+	}
+	a.json("push_devices.json", results)
+	a.csv("push_devices.csv", []string{"id", "push_service", "user_agent", "created_at"}, rows)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (a *exportArchive) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(a.loc).Format(time.RFC3339)
+}
@@ -4,18 +4,18 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/harisnkr/expense/common"
 	"github.com/harisnkr/expense/dto"
 	"github.com/harisnkr/expense/models"
 )
 
-// Login logs in the user with username and password (TODO: google login integration)
+// Login logs in the user with email or username and password (TODO: google login integration)
 func (u *Impl) Login(c *gin.Context) {
 	var (
 		req dto.UserLoginRequest
@@ -26,30 +26,53 @@ func (u *Impl) Login(c *gin.Context) {
 		return
 	}
 
+	filter := bson.M{"email": req.Email}
+	if req.Email == "" {
+		filter = bson.M{"username_lower": common.NormalizeUsername(req.Username)}
+	}
+
 	var user models.User
-	if err := u.collections.Users.FindOne(c, bson.M{"email": req.Email}).Decode(&user); err != nil {
+	if err := u.collections.Users.FindOne(c, filter).Decode(&user); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			log.Info("User not found")
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
 		}
-		log.Info("Failed to find user", err)
+		log.Info("Failed to find user", "err", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	log = log.With("email", user.Email)
+	if accountBlocked(c, user) {
+		return
+	}
 	if !user.Verified {
 		log.Info("User is not verified")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not verified"})
 		return
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(req.Password), []byte(user.Password))
+	if user.Password == "" {
+		log.Info("User has no password, they were verified by magic link")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No password is set, sign in by link or reset your password"})
+		return
+	}
+	ok, needsRehash, err := common.VerifyPassword(user.Password, req.Password)
 	if err != nil {
+		log.Error("Failed to verify password hash", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
-		log.Warn("Invalid password entered for user", err)
+		log.Warn("Invalid password entered for user")
 		return
 	}
+	if needsRehash {
+		u.rehashPassword(c, user.ID, req.Password)
+	}
+
+	u.recordSignIn(c, user, "password")
 
 	tokenDuration, tokenString := generateSessionJWT(c, user)
 	if tokenString == "" {
@@ -61,3 +84,21 @@ func (u *Impl) Login(c *gin.Context) {
 		ExpiresIn:    tokenDuration.String(),
 	})
 }
+
+// rehashPassword upgrades a user's stored hash to the current algorithm and parameters.
+// Failures are logged only, the old hash keeps working and is retried on the next login.
+func (u *Impl) rehashPassword(c *gin.Context, userID, password string) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+
+	hashedPassword, err := common.HashPassword(password)
+	if err != nil {
+		log.Error("Failed to rehash password", "err", err)
+		return
+	}
+	update := bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()}}
+	if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+		log.Error("Failed to store rehashed password", "err", err)
+		return
+	}
+	log.Info("Upgraded stored password hash")
+}
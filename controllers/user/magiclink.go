@@ -0,0 +1,152 @@
+package user
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// SendMagicLink emails a single-use, short-lived sign-in link to the user.
+// The response is the same whether or not the email is registered, to avoid leaking accounts.
+func (u *Impl) SendMagicLink(c *gin.Context) {
+	var (
+		req dto.MagicLinkRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log = log.With("email", req.Email)
+	accepted := gin.H{"message": "If the email is registered, a sign-in link has been sent."}
+
+	var user models.User
+	filter := bson.M{"email": req.Email, "deleted_at": bson.M{"$exists": false}, "disabled_at": bson.M{"$exists": false}}
+	if err := u.collections.Users.FindOne(c, filter).Decode(&user); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Error("Failed to find user", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		log.Info("Magic link requested for unknown email")
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	tokenString, linkID, err := common.SignPurposeToken(common.PurposeMagicLink, user.ID, config.MagicLinkTTL)
+	if err != nil {
+		log.Error("Failed to sign magic link", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	// storing the link ID invalidates any earlier link, and lets ConsumeMagicLink use it exactly once
+	update := bson.M{"$set": bson.M{
+		"magic_link_id":         linkID,
+		"magic_link_expires_at": time.Now().Add(config.MagicLinkTTL),
+	}}
+	if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": user.ID}, update); err != nil {
+		log.Error("Failed to store magic link", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	link := config.AppBaseURL + "/login/link?token=" + url.QueryEscape(tokenString)
+	if err = sendMagicLinkEmail(c, u.outbox, user, link); err != nil {
+		log.Error("Failed to enqueue magic link email", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// ConsumeMagicLink exchanges a sign-in link token for a session token.
+// Signing in through a link proves ownership of the email, so unverified users become verified. Their
+// password is cleared as they are: whoever registered the address before its owner could otherwise keep
+// signing in with the password they chose. The owner can set one with RequestPasswordReset.
+func (u *Impl) ConsumeMagicLink(c *gin.Context) {
+	var (
+		req dto.ConsumeMagicLinkRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := common.ParsePurposeToken(req.Token, common.PurposeMagicLink)
+	if err != nil {
+		log.Info("Invalid magic link token", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+	log = log.With("userID", claims.Subject)
+
+	// matching and clearing the link ID in one update makes the link single-use, and the password is
+	// cleared in the same update only if the link is what verifies the user
+	var user models.User
+	err = u.collections.Users.FindOneAndUpdate(c,
+		bson.M{
+			"_id":                   claims.Subject,
+			"magic_link_id":         claims.ID,
+			"magic_link_expires_at": bson.M{"$gt": time.Now()},
+			"deleted_at":            bson.M{"$exists": false},
+			"disabled_at":           bson.M{"$exists": false},
+			"purging_at":            bson.M{"$exists": false},
+		},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{
+				{Key: "password", Value: bson.D{{Key: "$cond", Value: bson.A{"$verified", "$password", ""}}}},
+				{Key: "verified", Value: true},
+				{Key: "updated_at", Value: time.Now()},
+			}}},
+			{{Key: "$unset", Value: bson.A{"magic_link_id", "magic_link_expires_at", "verification_code"}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			log.Info("Magic link already used or superseded")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+			return
+		}
+		log.Error("Failed to consume magic link", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	if !user.Verified {
+		log.Info("Verified by magic link, cleared password")
+		hadPassword := user.Password != ""
+		user.Verified, user.Password, user.VerificationCode = true, "", ""
+		u.publishVerified(c, user)
+		if hadPassword {
+			u.recordSecurityEvent(c, user, models.PasswordChanged, map[string]string{"method": "cleared_by_magic_link"})
+		}
+	}
+	user.MagicLinkID, user.MagicLinkExpiresAt = "", time.Time{}
+
+	u.recordSignIn(c, user, "magic_link")
+
+	tokenDuration, tokenString := generateSessionJWT(c, user)
+	if tokenString == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate session token"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.UserLoginResponse{
+		SessionToken: tokenString,
+		ExpiresIn:    tokenDuration.String(),
+	})
+}
@@ -0,0 +1,440 @@
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// errPasskeyCloned is returned for an assertion whose signature counter did not advance
+var errPasskeyCloned = errors.New("passkey signature counter did not increase")
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+
+	defaultPasskeyName = "Passkey"
+)
+
+func newWebAuthn() *webauthn.WebAuthn {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    config.WebAuthn.Timeout,
+		TimeoutUVD: config.WebAuthn.Timeout,
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthn.RPID,
+		RPDisplayName: config.WebAuthn.RPDisplayName,
+		RPOrigins:     config.WebAuthn.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		slog.Error("invalid WebAuthn relying party config", "err", err)
+		panic(err)
+	}
+	return w
+}
+
+// BeginPasskeyRegistration issues a registration challenge for the authenticated user
+func (u *Impl) BeginPasskeyRegistration(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	passkeyUser := webAuthnUser{&user}
+	excluded := make([]protocol.CredentialDescriptor, 0, len(user.Passkeys))
+	for _, credential := range passkeyUser.WebAuthnCredentials() {
+		excluded = append(excluded, credential.Descriptor())
+	}
+
+	creation, session, err := u.webAuthn.BeginRegistration(passkeyUser,
+		webauthn.WithExclusions(excluded),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		log.Error("Failed to begin passkey registration", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	if err = u.storePasskeyChallenge(c, user.ID, ceremonyRegistration, session); err != nil {
+		log.Error("Failed to store passkey registration challenge", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishPasskeyRegistration verifies the authenticator's attestation and stores the new passkey.
+// The passkey can be named with the optional ?name= query parameter.
+func (u *Impl) FinishPasskeyRegistration(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(c.Request.Body)
+	if err != nil {
+		log.Info("Invalid passkey registration response", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey registration response"})
+		return
+	}
+
+	user, session, err := u.takePasskeyChallenge(c, bson.M{"_id": userID}, ceremonyRegistration)
+	if err != nil {
+		log.Info("No outstanding passkey registration", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey registration expired, please try again"})
+		return
+	}
+
+	passkey, err := u.verifyPasskeyRegistration(user, session, parsed)
+	if err != nil {
+		log.Info("Passkey registration verification failed", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey registration could not be verified"})
+		return
+	}
+	passkey.Name = c.DefaultQuery("name", defaultPasskeyName)
+	update := bson.M{
+		"$push": bson.M{"passkeys": passkey},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	// guard against the same credential being registered twice by concurrent requests
+	filter := bson.M{"_id": user.ID, "passkeys.id": bson.M{"$ne": passkey.ID}}
+	result, err := u.collections.Users.UpdateOne(c, filter, update)
+	if err != nil {
+		log.Error("Failed to store passkey", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Passkey is already registered"})
+		return
+	}
+
+	log.Info("Registered passkey")
+	u.recordSecurityEvent(c, *user, models.TwoFactorChanged, map[string]string{
+		"change": "passkey_added",
+		"name":   passkey.Name,
+	})
+	c.JSON(http.StatusCreated, dto.PasskeyRegisteredResponse{
+		ID:   base64.RawURLEncoding.EncodeToString(passkey.ID),
+		Name: passkey.Name,
+	})
+}
+
+// RemovePasskey removes the authenticated user's passkey with the base64url credential ID in the path
+func (u *Impl) RemovePasskey(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	var user models.User
+	err = u.collections.Users.FindOneAndUpdate(c,
+		bson.M{"_id": userID, "passkeys.id": credentialID},
+		bson.M{
+			"$pull": bson.M{"passkeys": bson.M{"id": credentialID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to remove passkey", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	var name string
+	for _, passkey := range user.Passkeys {
+		if bytes.Equal(passkey.ID, credentialID) {
+			name = passkey.Name
+		}
+	}
+	log.Info("Removed passkey")
+	u.recordSecurityEvent(c, user, models.TwoFactorChanged, map[string]string{
+		"change": "passkey_removed",
+		"name":   name,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// BeginPasskeyLogin issues a login challenge for the passkeys of the user with the given email
+func (u *Impl) BeginPasskeyLogin(c *gin.Context) {
+	var (
+		req dto.BeginPasskeyLoginRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log = log.With("email", req.Email)
+
+	// unknown emails and users without passkeys get the same response, so it reveals no accounts
+	var user models.User
+	err := u.collections.Users.FindOne(c, bson.M{"email": req.Email}).Decode(&user)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Error("Failed to find user", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if err != nil || len(user.Passkeys) == 0 {
+		log.Info("No passkeys to sign in with", "found", err == nil)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey sign-in is not available for this email"})
+		return
+	}
+	if accountBlocked(c, user) {
+		return
+	}
+
+	assertion, session, err := u.webAuthn.BeginLogin(webAuthnUser{&user})
+	if err != nil {
+		log.Error("Failed to begin passkey login", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	if err = u.storePasskeyChallenge(c, user.ID, ceremonyLogin, session); err != nil {
+		log.Error("Failed to store passkey login challenge", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, assertion)
+}
+
+// FinishPasskeyLogin verifies a passkey assertion for the user in ?email= and starts a session.
+// Assertions whose signature counter did not advance are rejected as a possibly cloned authenticator.
+func (u *Impl) FinishPasskeyLogin(c *gin.Context) {
+	var (
+		email = c.Query("email")
+		log   = slog.With(common.RequestID, c.MustGet(common.RequestID), "email", email)
+	)
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email query parameter is required"})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		log.Info("Invalid passkey login response", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey login response"})
+		return
+	}
+
+	user, session, err := u.takePasskeyChallenge(c, bson.M{"email": email}, ceremonyLogin)
+	if err != nil {
+		log.Info("No outstanding passkey login", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey login expired, please try again"})
+		return
+	}
+	log = log.With("userID", user.ID)
+	if accountBlocked(c, *user) {
+		return
+	}
+
+	credential, err := u.verifyPasskeyAssertion(user, session, parsed)
+	if errors.Is(err, errPasskeyCloned) {
+		log.Warn("Passkey signature counter did not increase, possible cloned authenticator",
+			"signCount", credential.Authenticator.SignCount)
+		u.recordSecurityEvent(c, *user, models.PasskeyCloneWarned, map[string]string{
+			"passkey_id": base64.RawURLEncoding.EncodeToString(credential.ID),
+		})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey could not be verified"})
+		return
+	}
+	if err != nil {
+		log.Info("Passkey assertion verification failed", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey could not be verified"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"passkeys.$.sign_count":   credential.Authenticator.SignCount,
+		"passkeys.$.backup_state": credential.Flags.BackupState,
+		"passkeys.$.last_used_at": time.Now(),
+	}}
+	if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": user.ID, "passkeys.id": credential.ID}, update); err != nil {
+		log.Error("Failed to update passkey sign count", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	u.recordSignIn(c, *user, "passkey")
+
+	tokenDuration, tokenString := generateSessionJWT(c, *user)
+	if tokenString == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate session token"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.UserLoginResponse{
+		SessionToken: tokenString,
+		ExpiresIn:    tokenDuration.String(),
+	})
+}
+
+// verifyPasskeyRegistration verifies an authenticator's attestation against the registration session of user
+func (u *Impl) verifyPasskeyRegistration(user *models.User, session *webauthn.SessionData,
+	parsed *protocol.ParsedCredentialCreationData) (models.Passkey, error) {
+	credential, err := u.webAuthn.CreateCredential(webAuthnUser{user}, *session, parsed)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+	return passkeyFromCredential(credential), nil
+}
+
+// verifyPasskeyAssertion verifies an assertion against the login session of user. An assertion whose
+// signature counter did not advance past the stored one returns the credential with errPasskeyCloned.
+func (u *Impl) verifyPasskeyAssertion(user *models.User, session *webauthn.SessionData,
+	parsed *protocol.ParsedCredentialAssertionData) (*webauthn.Credential, error) {
+	credential, err := u.webAuthn.ValidateLogin(webAuthnUser{user}, *session, parsed)
+	if err != nil {
+		return nil, err
+	}
+	if credential.Authenticator.CloneWarning {
+		return credential, errPasskeyCloned
+	}
+	return credential, nil
+}
+
+func (u *Impl) storePasskeyChallenge(c *gin.Context, userID, ceremony string, session *webauthn.SessionData) error {
+	encoded, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	challenge := models.PasskeyChallenge{
+		Ceremony:  ceremony,
+		Session:   encoded,
+		ExpiresAt: time.Now().Add(config.WebAuthn.Timeout),
+	}
+	_, err = u.collections.Users.UpdateOne(c, bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"passkey_challenge": challenge}})
+	return err
+}
+
+// takePasskeyChallenge atomically removes and returns the outstanding challenge of the user matching filter,
+// so that each challenge can only be answered once
+func (u *Impl) takePasskeyChallenge(c *gin.Context, filter bson.M, ceremony string) (*models.User, *webauthn.SessionData, error) {
+	filter["passkey_challenge.ceremony"] = ceremony
+	filter["passkey_challenge.expires_at"] = bson.M{"$gt": time.Now()}
+
+	var user models.User
+	err := u.collections.Users.FindOneAndUpdate(c, filter,
+		bson.M{"$unset": bson.M{"passkey_challenge": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var session webauthn.SessionData
+	if err = json.Unmarshal(user.PasskeyChallenge.Session, &session); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(session.UserID, []byte(user.ID)) {
+		return nil, nil, errors.New("passkey challenge issued for another user")
+	}
+	return &user, &session, nil
+}
+
+// webAuthnUser adapts models.User to webauthn.User
+type webAuthnUser struct {
+	*models.User
+}
+
+// WebAuthnID implements webauthn.User
+func (w webAuthnUser) WebAuthnID() []byte {
+	return []byte(w.ID)
+}
+
+// WebAuthnName implements webauthn.User
+func (w webAuthnUser) WebAuthnName() string {
+	return w.Email
+}
+
+// WebAuthnDisplayName implements webauthn.User
+func (w webAuthnUser) WebAuthnDisplayName() string {
+	return w.FirstName + " " + w.LastName
+}
+
+// WebAuthnIcon implements webauthn.User
+func (w webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials implements webauthn.User
+func (w webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(w.Passkeys))
+	for _, passkey := range w.Passkeys {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+		for _, transport := range passkey.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.ID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+func passkeyFromCredential(credential *webauthn.Credential) models.Passkey {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+	return models.Passkey{
+		ID:              credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+}
@@ -0,0 +1,260 @@
+package user
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/models"
+)
+
+const testOrigin = "https://app.moneyfly.test"
+
+// softwareAuthenticator is a P-256 platform authenticator holding a single credential, with a signature
+// counter the test controls
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err = rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+// authenticatorData encodes the authenticator data for the relying party, with the attested credential
+// when attest is set
+func (a *softwareAuthenticator) authenticatorData(t *testing.T, attest bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(config.WebAuthn.RPID))
+	data := append([]byte{}, rpIDHash[:]...)
+
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attest {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attest {
+		return data
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, make([]byte, 16)...) // AAGUID, zero for a software authenticator
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, publicKey...)
+}
+
+func clientDataJSON(t *testing.T, ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	data, err := json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: challenge.String(),
+		Origin:    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// register answers a registration challenge with a "none" attestation, as a browser would send it
+func (a *softwareAuthenticator) register(t *testing.T,
+	creation *protocol.CredentialCreation) *protocol.ParsedCredentialCreationData {
+	t.Helper()
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON": base64.RawURLEncoding.EncodeToString(
+				clientDataJSON(t, protocol.CreateCeremony, creation.Response.Challenge)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("parse registration response: %v", err)
+	}
+	return parsed
+}
+
+// assert signs a login challenge with the authenticator's current signature counter
+func (a *softwareAuthenticator) assert(t *testing.T, assertion *protocol.CredentialAssertion,
+	userID string) *protocol.ParsedCredentialAssertionData {
+	t.Helper()
+	authData := a.authenticatorData(t, false)
+	clientData := clientDataJSON(t, protocol.AssertCeremony, assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString([]byte(userID)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("parse assertion response: %v", err)
+	}
+	return parsed
+}
+
+func newPasskeyTestImpl() *Impl {
+	config.WebAuthn = config.WebAuthnConfig{
+		RPID:          "app.moneyfly.test",
+		RPDisplayName: "MoneyFly",
+		RPOrigins:     []string{testOrigin},
+		Timeout:       time.Minute,
+	}
+	return &Impl{webAuthn: newWebAuthn()}
+}
+
+// registerPasskey runs a registration ceremony for user and stores the passkey as FinishPasskeyRegistration does
+func registerPasskey(t *testing.T, u *Impl, user *models.User, authenticator *softwareAuthenticator) {
+	t.Helper()
+	creation, session, err := u.webAuthn.BeginRegistration(webAuthnUser{user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	passkey, err := u.verifyPasskeyRegistration(user, session, authenticator.register(t, creation))
+	if err != nil {
+		t.Fatalf("registration rejected: %v", err)
+	}
+	user.Passkeys = append(user.Passkeys, passkey)
+}
+
+// login runs a login ceremony for user, storing the new signature counter as FinishPasskeyLogin does
+func login(t *testing.T, u *Impl, user *models.User, authenticator *softwareAuthenticator) error {
+	t.Helper()
+	assertion, session, err := u.webAuthn.BeginLogin(webAuthnUser{user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential, err := u.verifyPasskeyAssertion(user, session, authenticator.assert(t, assertion, user.ID))
+	if err != nil {
+		return err
+	}
+	for i := range user.Passkeys {
+		if bytes.Equal(user.Passkeys[i].ID, credential.ID) {
+			user.Passkeys[i].SignCount = credential.Authenticator.SignCount
+		}
+	}
+	return nil
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	var (
+		u             = newPasskeyTestImpl()
+		user          = &models.User{ID: "user-1", Email: "ada@example.com", FirstName: "Ada"}
+		authenticator = newSoftwareAuthenticator(t)
+	)
+	registerPasskey(t, u, user, authenticator)
+	if len(user.Passkeys) != 1 || !bytes.Equal(user.Passkeys[0].ID, authenticator.credentialID) {
+		t.Fatalf("registered passkeys = %+v, want the authenticator's credential", user.Passkeys)
+	}
+
+	for count := uint32(1); count <= 3; count++ {
+		authenticator.signCount = count
+		if err := login(t, u, user, authenticator); err != nil {
+			t.Fatalf("login with sign count %d rejected: %v", count, err)
+		}
+		if user.Passkeys[0].SignCount != count {
+			t.Fatalf("stored sign count = %d, want %d", user.Passkeys[0].SignCount, count)
+		}
+	}
+}
+
+func TestPasskeyLoginRejectsSignCountRegression(t *testing.T) {
+	var (
+		u             = newPasskeyTestImpl()
+		user          = &models.User{ID: "user-1", Email: "ada@example.com", FirstName: "Ada"}
+		authenticator = newSoftwareAuthenticator(t)
+	)
+	registerPasskey(t, u, user, authenticator)
+
+	authenticator.signCount = 5
+	if err := login(t, u, user, authenticator); err != nil {
+		t.Fatalf("login rejected: %v", err)
+	}
+	for _, count := range []uint32{5, 4} {
+		authenticator.signCount = count
+		if err := login(t, u, user, authenticator); !errors.Is(err, errPasskeyCloned) {
+			t.Fatalf("login with sign count %d after 5: err = %v, want errPasskeyCloned", count, err)
+		}
+	}
+}
+
+func TestPasskeyLoginRejectsOtherKey(t *testing.T) {
+	var (
+		u             = newPasskeyTestImpl()
+		user          = &models.User{ID: "user-1", Email: "ada@example.com", FirstName: "Ada"}
+		authenticator = newSoftwareAuthenticator(t)
+	)
+	registerPasskey(t, u, user, authenticator)
+
+	// a different key presenting the registered credential ID must not verify
+	impostor := newSoftwareAuthenticator(t)
+	impostor.credentialID, impostor.signCount = authenticator.credentialID, 1
+	if err := login(t, u, user, impostor); err == nil {
+		t.Fatal("login signed by another key was accepted")
+	}
+}
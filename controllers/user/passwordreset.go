@@ -0,0 +1,148 @@
+package user
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// RequestPasswordReset emails the user a single-use password reset link, for users who forgot their password
+// or never had one, e.g. passkey-only accounts and those whose password a magic link cleared.
+// The response is the same whether or not the email is registered, to avoid leaking accounts.
+func (u *Impl) RequestPasswordReset(c *gin.Context) {
+	var (
+		req dto.PasswordResetLinkRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log = log.With("email", req.Email)
+	accepted := gin.H{"message": "If the email is registered, a password reset link has been sent."}
+
+	var user models.User
+	filter := bson.M{
+		"email":       req.Email,
+		"deleted_at":  bson.M{"$exists": false},
+		"disabled_at": bson.M{"$exists": false},
+		"purging_at":  bson.M{"$exists": false},
+	}
+	if err := u.collections.Users.FindOne(c, filter).Decode(&user); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Error("Failed to find user", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		log.Info("Password reset requested for unknown email")
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	if err := u.sendPasswordReset(c, user); err != nil {
+		log.Error("Failed to send password reset", "err", err, "userID", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	log.Info("Sent password reset link", "userID", user.ID)
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// ResetPassword sets a new password using a single-use reset link token
+func (u *Impl) ResetPassword(c *gin.Context) {
+	var (
+		req dto.ResetPasswordRequest
+		log = slog.With(common.RequestID, c.MustGet(common.RequestID))
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := common.ParsePurposeToken(req.Token, common.PurposePasswordReset)
+	if err != nil {
+		log.Info("Invalid password reset token", "err", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+	log = log.With("userID", claims.Subject)
+
+	if violations := common.CheckPassword(req.Password); len(violations) > 0 {
+		c.JSON(http.StatusBadRequest, dto.PasswordPolicyErrorResponse{
+			Error:      "Password does not meet requirements",
+			Violations: violations,
+		})
+		return
+	}
+	hashedPassword, err := common.HashPassword(req.Password)
+	if err != nil {
+		log.Error("Failed to hash password", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// matching and clearing the reset ID in one update makes the link single-use
+	var user models.User
+	err = u.collections.Users.FindOneAndUpdate(c,
+		bson.M{
+			"_id":                       claims.Subject,
+			"password_reset_id":         claims.ID,
+			"password_reset_expires_at": bson.M{"$gt": time.Now()},
+			"deleted_at":                bson.M{"$exists": false},
+			"disabled_at":               bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": bson.M{
+				"password":             hashedPassword,
+				"sessions_valid_after": time.Now(),
+				"updated_at":           time.Now(),
+			},
+			"$unset": bson.M{"password_reset_id": "", "password_reset_expires_at": "", "magic_link_id": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			log.Info("Password reset link already used or superseded")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired reset link"})
+			return
+		}
+		log.Error("Failed to reset password", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	u.recordSecurityEvent(c, user, models.PasswordChanged, map[string]string{"method": "reset_link"})
+	log.Info("Password reset")
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated, sign in with your new password."})
+}
+
+// sendPasswordReset emails user a single-use password reset link, invalidating any earlier one
+func (u *Impl) sendPasswordReset(c *gin.Context, user models.User) error {
+	tokenString, resetID, err := common.SignPurposeToken(common.PurposePasswordReset, user.ID, config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{
+		"password_reset_id":         resetID,
+		"password_reset_expires_at": time.Now().Add(config.PasswordResetTTL),
+	}}
+	if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": user.ID}, update); err != nil {
+		return err
+	}
+
+	link := config.AppBaseURL + "/reset-password?token=" + url.QueryEscape(tokenString)
+	return sendPasswordResetEmail(c, u.outbox, user, link)
+}
@@ -0,0 +1,279 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/storage"
+)
+
+const (
+	profilePictureField = "picture"
+
+	// maxPictureDimension rejects images that would decode into huge bitmaps from small files
+	maxPictureDimension = 6000
+	pictureSize         = 1024
+	thumbnailSize       = 128
+	jpegQuality         = 85
+
+	// multipartOverhead allows for multipart boundaries and headers around the file itself
+	multipartOverhead = 64 << 10
+)
+
+// allowedPictureTypes are the sniffed content types accepted as profile pictures
+var allowedPictureTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// UploadProfilePicture replaces the authenticated user's profile picture with a multipart upload
+// in the "picture" field. The image is sniffed, re-encoded (dropping any metadata) and thumbnailed.
+func (u *Impl) UploadProfilePicture(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.ProfilePictureMaxBytes+multipartOverhead)
+	file, header, err := c.Request.FormFile(profilePictureField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Picture is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A picture file is required in the \"picture\" field"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > config.ProfilePictureMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Picture is too large"})
+		return
+	}
+	raw, err := io.ReadAll(io.LimitReader(file, config.ProfilePictureMaxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read picture"})
+		return
+	}
+	if int64(len(raw)) > config.ProfilePictureMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Picture is too large"})
+		return
+	}
+
+	// trust the bytes, not the client's Content-Type or filename
+	if contentType := http.DetectContentType(raw); !allowedPictureTypes[contentType] {
+		log.Info("Rejected profile picture", "contentType", contentType)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Picture must be a JPEG, PNG or GIF image"})
+		return
+	}
+	picture, thumbnail, ext, err := processPicture(raw)
+	if err != nil {
+		log.Info("Invalid profile picture", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Picture could not be processed"})
+		return
+	}
+
+	var user models.User
+	if err = u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	id := uuid.New().String()
+	pictureKey := path.Join("profile-pictures", userID, id+ext)
+	thumbnailKey := path.Join("profile-pictures", userID, id+"-thumb"+ext)
+	if err = u.blobs.Put(c, pictureKey, bytes.NewReader(picture)); err != nil {
+		log.Error("Failed to store profile picture", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if err = u.blobs.Put(c, thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		log.Error("Failed to store profile picture thumbnail", "err", err)
+		u.deleteBlobs(c, pictureKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"profile_picture":           pictureKey,
+		"profile_picture_thumbnail": thumbnailKey,
+		"updated_at":                time.Now(),
+	}}
+	if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+		log.Error("Failed to update profile picture", "err", err)
+		u.deleteBlobs(c, pictureKey, thumbnailKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	u.deleteBlobs(c, user.ProfilePicture, user.ProfilePictureThumbnail)
+
+	log.Info("Updated profile picture", "key", pictureKey)
+	c.JSON(http.StatusOK, dto.ProfilePictureResponse{
+		URL:          storage.SignedURL(pictureKey),
+		ThumbnailURL: storage.SignedURL(thumbnailKey),
+	})
+}
+
+// DeleteProfilePicture removes the authenticated user's profile picture
+func (u *Impl) DeleteProfilePicture(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"profile_picture": "", "profile_picture_thumbnail": ""},
+	}
+	if _, err := u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+		log.Error("Failed to remove profile picture", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	u.deleteBlobs(c, user.ProfilePicture, user.ProfilePictureThumbnail)
+	c.Status(http.StatusNoContent)
+}
+
+// deleteBlobs removes blobs that are no longer referenced, failures only leave orphans behind so are logged
+func (u *Impl) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := u.blobs.Delete(ctx, key); err != nil {
+			slog.Warn("Failed to delete blob", "err", err, "key", key)
+		}
+	}
+}
+
+// processPicture decodes an uploaded image and re-encodes it as a picture fitting pictureSize and a square
+// thumbnail. JPEGs stay JPEG, everything else becomes PNG to keep transparency.
+func processPicture(raw []byte) (picture, thumbnail []byte, ext string, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if cfg.Width > maxPictureDimension || cfg.Height > maxPictureDimension {
+		return nil, nil, "", errors.New("image dimensions too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	src := toRGBA(img)
+	w, h := fitWithin(src.Bounds().Dx(), src.Bounds().Dy(), pictureSize)
+	resized := resizeBox(src, w, h)
+	thumb := resizeBox(cropSquare(src), thumbnailSize, thumbnailSize)
+
+	encode, ext := encodePNG, ".png"
+	if format == "jpeg" {
+		encode, ext = encodeJPEG, ".jpg"
+	}
+	if picture, err = encode(resized); err != nil {
+		return nil, nil, "", err
+	}
+	if thumbnail, err = encode(thumb); err != nil {
+		return nil, nil, "", err
+	}
+	return picture, thumbnail, ext, nil
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	return buf.Bytes(), err
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), err
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// fitWithin scales w x h down to fit in a limit x limit square, keeping the aspect ratio
+func fitWithin(w, h, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// cropSquare returns the largest centred square of src
+func cropSquare(src *image.RGBA) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	size := min(w, h)
+	x0, y0 := (w-size)/2, (h-size)/2
+	return src.SubImage(image.Rect(x0, y0, x0+size, y0+size)).(*image.RGBA)
+}
+
+// resizeBox scales src to w x h by averaging the source pixels each destination pixel covers.
+// Good enough for downscaling photos, which is all profile pictures need.
+func resizeBox(src *image.RGBA, w, h int) *image.RGBA {
+	var (
+		dst    = image.NewRGBA(image.Rect(0, 0, w, h))
+		bounds = src.Bounds()
+		sw, sh = bounds.Dx(), bounds.Dy()
+	)
+	for y := 0; y < h; y++ {
+		sy0, sy1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			sx0, sx1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.PixOffset(bounds.Min.X+sx0, bounds.Min.Y+sy)
+				for sx := sx0; sx < sx1; sx++ {
+					i := row + (sx-sx0)*4
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,139 @@
+package user
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// GetPreferences returns the authenticated user's preferences, with defaults for anything never saved
+func (u *Impl) GetPreferences(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewPreferencesResponse(user.EffectivePreferences()))
+}
+
+// UpdatePreferences partially updates the authenticated user's preferences
+func (u *Impl) UpdatePreferences(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+		req    dto.UpdatePreferencesRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := u.collections.Users.FindOne(c, bson.M{"_id": userID}).Decode(&user); err != nil {
+		log.Warn("Failed to find user", "err", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	prefs := user.EffectivePreferences()
+	if req.HomeCurrency != nil {
+		prefs.HomeCurrency = *req.HomeCurrency
+	}
+	if req.Timezone != nil {
+		prefs.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+	if req.FirstDayOfWeek != nil {
+		prefs.FirstDayOfWeek = time.Weekday(*req.FirstDayOfWeek)
+	}
+	if req.DefaultCardID != nil {
+		if *req.DefaultCardID != "" && !hasCard(user, *req.DefaultCardID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Default card must be one of your cards"})
+			return
+		}
+		prefs.DefaultCardID = *req.DefaultCardID
+	}
+	if req.DefaultCategory != nil {
+		if _, ok := models.ParseSpendCategory(*req.DefaultCategory); *req.DefaultCategory != "" && !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown default category"})
+			return
+		}
+		prefs.DefaultCategory = *req.DefaultCategory
+	}
+	if n := req.Notifications; n != nil {
+		setIfPresent(&prefs.Notifications.SecurityAlerts, n.SecurityAlerts)
+		setIfPresent(&prefs.Notifications.BudgetAlerts, n.BudgetAlerts)
+		setIfPresent(&prefs.Notifications.FeeReminders, n.FeeReminders)
+		setIfPresent(&prefs.Notifications.ProductUpdates, n.ProductUpdates)
+		if !setChannels(&prefs.Notifications, n.Channels) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification category"})
+			return
+		}
+	}
+
+	if d := req.Digests; d != nil {
+		setIfPresent(&prefs.Digests.Weekly, d.Weekly)
+		setIfPresent(&prefs.Digests.Monthly, d.Monthly)
+	}
+
+	update := bson.M{"$set": bson.M{"preferences": prefs, "updated_at": time.Now()}}
+	if _, err := u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update); err != nil {
+		log.Error("Failed to update preferences", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewPreferencesResponse(prefs))
+}
+
+func hasCard(user models.User, cardID string) bool {
+	for _, card := range user.Cards {
+		if card.ID == cardID {
+			return true
+		}
+	}
+	return false
+}
+
+// setChannels applies updates to the channels of each category, returning false if a category is unknown
+func setChannels(prefs *models.NotificationPreferences, updates map[string]dto.UpdateNotificationChannels) bool {
+	for name, update := range updates {
+		category, ok := models.ParseNotificationCategory(name)
+		if !ok {
+			return false
+		}
+		channels, ok := prefs.Channels[category]
+		if !ok {
+			channels = models.DefaultNotificationChannels(category)
+		}
+		setIfPresent(&channels.InApp, update.InApp)
+		setIfPresent(&channels.Email, update.Email)
+		setIfPresent(&channels.Push, update.Push)
+
+		if prefs.Channels == nil {
+			prefs.Channels = make(map[models.NotificationCategory]models.NotificationChannels)
+		}
+		prefs.Channels[category] = channels
+	}
+	return true
+}
+
+func setIfPresent(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -7,9 +7,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
 	"github.com/harisnkr/expense/dto"
 	"github.com/harisnkr/expense/models"
 )
@@ -26,6 +27,14 @@ func (u *Impl) RegisterUser(c *gin.Context) {
 		return
 	}
 
+	if violations := common.CheckPassword(req.Password); len(violations) > 0 {
+		c.JSON(http.StatusBadRequest, dto.PasswordPolicyErrorResponse{
+			Error:      "Password does not meet requirements",
+			Violations: violations,
+		})
+		return
+	}
+
 	// Check if email in request already exists in database
 	var existingUser models.User
 	err := collection.FindOne(c, bson.M{"email": req.Email}).Decode(&existingUser)
@@ -36,24 +45,74 @@ func (u *Impl) RegisterUser(c *gin.Context) {
 		}
 	}
 
+	if req.Username != "" {
+		taken, err := u.usernameTaken(c, req.Username, "")
+		if err != nil {
+			log.Error("Failed to check username", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+			return
+		}
+	}
+
+	current, err := data.CurrentPolicyVersions(c, u.collections.Policies)
+	if err != nil {
+		log.Error("Failed to look up current policy versions", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var notAccepted []models.PolicyVersion
+	for _, document := range models.PolicyDocuments {
+		if version, ok := current[document]; ok && req.Accepted(document) != version.Version {
+			notAccepted = append(notAccepted, version)
+		}
+	}
+	if len(notAccepted) > 0 {
+		c.JSON(http.StatusBadRequest, dto.NewConsentRequiredResponse(notAccepted))
+		return
+	}
+
 	// if not proceed on to create newUser
 	newUser := &models.User{}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := common.HashPassword(req.Password)
 	if err != nil {
+		log.Error("Failed to hash password", "err", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
-	otp := populateUserEntry(newUser, req, hashedPassword)
+	populateUserEntry(newUser, req, hashedPassword)
+	for _, document := range models.PolicyDocuments {
+		if version, ok := current[document]; ok {
+			newUser.Consents = append(newUser.Consents, newConsent(c, version))
+		}
+	}
 
-	// Insert the new user into the database
-	if _, err = collection.InsertOne(c, newUser); err != nil {
-		log.Error("Failed to insert new user", err)
+	// the account is only usable once verified, so it is inserted in one transaction with its verification email
+	session, err := u.database.StartSession()
+	if err != nil {
+		log.Error("Failed to start session", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	defer session.EndSession(c)
+	_, err = session.WithTransaction(c, func(ctx mongo.SessionContext) (interface{}, error) {
+		if _, err := collection.InsertOne(ctx, newUser); err != nil {
+			return nil, err
+		}
+		return nil, sendVerificationEmail(ctx, u.outbox, *newUser)
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+			return
+		}
+		log.Error("Failed to register new user", "err", err)
 		// TODO: create generic handlers for errors
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
 		return
 	}
-
-	// Send email with verification link
-	go sendVerificationEmail(c, req.Email, otp)
 	c.JSON(http.StatusCreated, gin.H{"message": "Check email for verification code."})
 }
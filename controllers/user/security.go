@@ -0,0 +1,181 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+)
+
+const (
+	// maxKnownDevices caps the devices remembered per user, the least recently added are forgotten first
+	maxKnownDevices = 20
+
+	defaultSecurityEventsLimit = 20
+	maxSecurityEventsLimit     = 100
+)
+
+// GetSecurityEvents lists the authenticated user's security event history, newest first.
+// Supports ?page= (from 1) and ?limit= query parameters.
+func (u *Impl) GetSecurityEvents(c *gin.Context) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID)
+	)
+
+	page, limit := common.PaginationParams(c, defaultSecurityEventsLimit, maxSecurityEventsLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := u.collections.SecurityEvents.Find(c, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		log.Error("Failed to find security events", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var events []models.SecurityEvent
+	if err = cursor.All(c, &events); err != nil {
+		log.Error("Failed to decode security events", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]dto.SecurityEventResponse, 0, len(events))
+	for _, event := range events {
+		results = append(results, dto.NewSecurityEventResponse(event))
+	}
+	c.JSON(http.StatusOK, gin.H{"events": results, "page": page, "limit": limit})
+}
+
+// recordSignIn remembers the device and network of a successful sign-in, and alerts the user when
+// either has not been seen before. A user's very first sign-in is remembered without an alert.
+func (u *Impl) recordSignIn(c *gin.Context, user models.User, method string) {
+	var (
+		log         = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", user.ID)
+		userAgent   = c.Request.UserAgent()
+		ipRange     = ipRangeOf(c.ClientIP())
+		fingerprint = deviceFingerprint(userAgent)
+		now         = time.Now()
+	)
+
+	var seenDevice, seenRange bool
+	for _, device := range user.KnownDevices {
+		seenDevice = seenDevice || device.Fingerprint == fingerprint
+		seenRange = seenRange || device.IPRange == ipRange
+	}
+
+	filter := bson.M{"_id": user.ID, "known_devices": bson.M{"$elemMatch": bson.M{
+		"fingerprint": fingerprint,
+		"ip_range":    ipRange,
+	}}}
+	result, err := u.collections.Users.UpdateOne(c, filter,
+		bson.M{"$set": bson.M{"known_devices.$.last_seen_at": now}})
+	if err != nil {
+		log.Error("Failed to update known device", "err", err)
+		return
+	}
+	if result.MatchedCount == 0 {
+		device := models.KnownDevice{
+			Fingerprint: fingerprint,
+			IPRange:     ipRange,
+			UserAgent:   userAgent,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		}
+		update := bson.M{"$push": bson.M{"known_devices": bson.M{
+			"$each":  []models.KnownDevice{device},
+			"$slice": -maxKnownDevices,
+		}}}
+		if _, err = u.collections.Users.UpdateOne(c, bson.M{"_id": user.ID}, update); err != nil {
+			log.Error("Failed to add known device", "err", err)
+			return
+		}
+	}
+
+	if len(user.KnownDevices) > 0 && (!seenDevice || !seenRange) {
+		u.recordSecurityEvent(c, user, models.NewSignIn, map[string]string{
+			"method":   method,
+			"ip_range": ipRange,
+		})
+	}
+}
+
+// recordSecurityEvent adds an entry to the user's security history and notifies them about it
+func (u *Impl) recordSecurityEvent(c *gin.Context, user models.User, eventType models.SecurityEventType,
+	metadata map[string]string) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", user.ID, "type", eventType)
+
+	event := models.SecurityEvent{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Type:      eventType,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	if _, err := u.collections.SecurityEvents.InsertOne(c, event); err != nil {
+		log.Error("Failed to record security event", "err", err)
+	} else {
+		log.Info("Recorded security event")
+	}
+
+	if err := u.notifier.Notify(c, user, securityAlert(user, event)); err != nil {
+		log.Error("Failed to notify security event", "err", err)
+	}
+}
+
+// securityAlert is the notification about event
+func securityAlert(user models.User, event models.SecurityEvent) notify.Message {
+	at := event.CreatedAt.In(user.EffectivePreferences().Location()).Format(time.RFC1123)
+	return notify.Message{
+		Type:     models.NotificationSecurityAlert,
+		Title:    event.Type.String(),
+		Body:     fmt.Sprintf("%s from %s. If this wasn't you, reset your password.", at, event.IP),
+		Data:     map[string]string{"security_event_id": event.ID, "security_event_type": string(event.Type)},
+		Template: email.TemplateSecurityAlert,
+		TemplateData: email.SecurityAlertData{
+			EventType:   string(event.Type),
+			Description: event.Type.String(),
+			At:          at,
+			IP:          event.IP,
+			UserAgent:   event.UserAgent,
+		},
+		DedupKey: "security_event:" + event.ID,
+	}
+}
+
+// deviceFingerprint identifies a device by its user agent, which is coarse but needs no client cooperation
+func deviceFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ipRangeOf returns the network an IP belongs to: its /24 for IPv4 and /48 for IPv6
+func ipRangeOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
@@ -0,0 +1,125 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+	"github.com/harisnkr/expense/storage"
+)
+
+// unverifiedTTLSlack is how long the TTL index waits beyond config.UnverifiedAccountTTL, so that
+// PurgeUnverifiedUsers normally gets to clean up and audit an account before MongoDB drops it
+const unverifiedTTLSlack = 24 * time.Hour
+
+// UnverifiedUserTTL is the expiry for the TTL index backing PurgeUnverifiedUsers, see data.EnsureUnverifiedUserTTL
+func UnverifiedUserTTL() time.Duration {
+	return config.UnverifiedAccountTTL + unverifiedTTLSlack
+}
+
+// RemindUnverifiedUsers emails users who signed up config.UnverifiedReminderAfter ago and still have not
+// verified their email. Each user is reminded at most once.
+func RemindUnverifiedUsers(ctx context.Context, collections *data.Collections, box *outbox.Outbox) {
+	var (
+		log = slog.With("func", "RemindUnverifiedUsers")
+		now = time.Now()
+	)
+	if config.UnverifiedReminderAfter >= config.UnverifiedAccountTTL {
+		return
+	}
+
+	filter := bson.M{
+		"verified":                      false,
+		"verification_reminder_sent_at": bson.M{"$exists": false},
+		"verification_sent_at": bson.M{
+			"$lte": now.Add(-config.UnverifiedReminderAfter),
+			"$gt":  now.Add(-config.UnverifiedAccountTTL),
+		},
+	}
+	cursor, err := collections.Users.Find(ctx, filter)
+	if err != nil {
+		log.Error("Failed to find unverified users", "err", err)
+		return
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		log.Error("Failed to decode unverified users", "err", err)
+		return
+	}
+
+	for _, user := range users {
+		// claim the reminder first so concurrent runs never send it twice
+		claim := bson.M{"_id": user.ID, "verified": false, "verification_reminder_sent_at": bson.M{"$exists": false}}
+		result, err := collections.Users.UpdateOne(ctx, claim,
+			bson.M{"$set": bson.M{"verification_reminder_sent_at": now}})
+		if err != nil {
+			log.Error("Failed to mark verification reminder sent", "err", err, "userID", user.ID)
+			continue
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		if err = sendVerificationReminderEmail(ctx, box, user); err != nil {
+			log.Error("Failed to enqueue verification reminder", "err", err, "userID", user.ID)
+			// release the claim so the next run tries again
+			if _, err = collections.Users.UpdateOne(ctx, bson.M{"_id": user.ID},
+				bson.M{"$unset": bson.M{"verification_reminder_sent_at": ""}}); err != nil {
+				log.Error("Failed to release verification reminder", "err", err, "userID", user.ID)
+			}
+			continue
+		}
+		log.Info("Enqueued verification reminder", "userID", user.ID)
+	}
+}
+
+// PurgeUnverifiedUsers deletes accounts that never verified their email within config.UnverifiedAccountTTL,
+// freeing the email for a new sign-up, and leaves an audit record for each
+func PurgeUnverifiedUsers(ctx context.Context, collections *data.Collections, blobs storage.BlobStore) {
+	var (
+		log    = slog.With("func", "PurgeUnverifiedUsers")
+		cutoff = time.Now().Add(-config.UnverifiedAccountTTL)
+	)
+
+	cursor, err := collections.Users.Find(ctx, bson.M{"verified": false, "verification_sent_at": bson.M{"$lte": cutoff}})
+	if err != nil {
+		log.Error("Failed to find unverified users to purge", "err", err)
+		return
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		log.Error("Failed to decode unverified users to purge", "err", err)
+		return
+	}
+
+	for _, user := range users {
+		if err = purgeUnverifiedUser(ctx, collections, blobs, user, cutoff); err != nil {
+			log.Error("Failed to purge unverified user", "err", err, "userID", user.ID)
+			continue
+		}
+		log.Info("Purged unverified user", "userID", user.ID)
+	}
+}
+
+func purgeUnverifiedUser(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+	user models.User, cutoff time.Time) error {
+	// re-check verification in the claim, the user may have verified since the Find
+	claim := bson.M{"_id": user.ID, "verified": false, "verification_sent_at": bson.M{"$lte": cutoff}}
+	return purgeAccount(ctx, collections, blobs, user, claim, models.AuditUnverifiedUserPurged,
+		map[string]string{"verification_sent_at": user.VerificationSentAt.Format(time.RFC3339)})
+}
+
+func sendVerificationReminderEmail(ctx context.Context, box *outbox.Outbox, user models.User) error {
+	deleteOn := user.VerificationSentAt.Add(config.UnverifiedAccountTTL).In(user.EffectivePreferences().Location())
+	return sendEmail(ctx, box, user, email.TemplateVerificationReminder, email.VerificationReminderData{
+		Name:     user.FirstName,
+		Code:     user.VerificationCode,
+		DeleteOn: deleteOn.Format(time.RFC1123),
+	})
+}
@@ -1,11 +1,14 @@
 package user
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/harisnkr/expense/common"
 	"github.com/harisnkr/expense/dto"
@@ -16,17 +19,17 @@ import (
 func (u *Impl) UpdateProfile(c *gin.Context) {
 	var (
 		log    = slog.With(common.RequestID, c.MustGet(common.RequestID))
-		userID = c.Query("userID")
+		userID = c.GetString(common.UserID) // get from userID set from JWT auth
 		req    *dto.UpdateMeRequest
 	)
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Debug("err", err, "invalid request body")
+		log.Debug("invalid request body", "err", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	filter := bson.M{"userID": userID}
+	filter := bson.M{"_id": userID}
 	update := bson.M{"$set": bson.M{}}
 
 	if req.FirstName != nil {
@@ -35,19 +38,41 @@ func (u *Impl) UpdateProfile(c *gin.Context) {
 	if req.LastName != nil {
 		update["$set"].(bson.M)["last_name"] = *req.LastName
 	}
-	if req.ProfilePicture != nil {
-		update["$set"].(bson.M)["profile_picture"] = *req.ProfilePicture
+	if req.Username != nil {
+		taken, err := u.usernameTaken(c, *req.Username, userID)
+		if err != nil {
+			log.Error("Failed to check username", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+			return
+		}
+		update["$set"].(bson.M)["username"] = *req.Username
+		update["$set"].(bson.M)["username_lower"] = common.NormalizeUsername(*req.Username)
 	}
 
-	result, err := u.collections.Users.UpdateOne(c, bson.M{"_id": userID}, update)
+	var previous models.User
+	err := u.collections.Users.FindOneAndUpdate(c, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
-
-	if result.ModifiedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found or no changes made"})
-		return
+	if req.Username != nil && *req.Username != previous.Username {
+		u.recordSecurityEvent(c, previous, models.UsernameChanged, map[string]string{
+			"from": previous.Username,
+			"to":   *req.Username,
+		})
 	}
 
 	var updatedUser models.User
@@ -56,5 +81,5 @@ func (u *Impl) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedUser)
+	c.JSON(http.StatusOK, dto.NewUserResponse(updatedUser))
 }
@@ -0,0 +1,295 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/storage"
+)
+
+// userDataSet is a kind of data held for a user, which their export archive includes and purging their
+// account removes
+type userDataSet struct {
+	name string // key in the purge audit record
+
+	// export adds the set to the archive, nil if the set is already part of profile.json
+	export func(ctx context.Context, collections *data.Collections, blobs storage.BlobStore, user models.User,
+		archive *exportArchive) error
+
+	// purge removes the set and returns how much was removed. Sets held in the user document are counted
+	// only, as they go with the document.
+	purge func(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+		user models.User) (int64, error)
+}
+
+// userDataSets is everything held for a user. Data stored for users must be listed here, so that what is
+// exported and what is purged cannot drift apart.
+var userDataSets = []userDataSet{
+	{
+		name: "cards",
+		export: func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			archive.cards(user.Cards)
+			return nil
+		},
+		purge: inUserDocument(func(user models.User) int { return len(user.Cards) }),
+	},
+	{
+		name: "transactions",
+		export: func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			archive.transactions(user.Transactions)
+			return nil
+		},
+		purge: inUserDocument(func(user models.User) int { return len(user.Transactions) }),
+	},
+	{
+		name: "budgets",
+		export: func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			archive.budgets(user.Budgets)
+			return nil
+		},
+		purge: inUserDocument(func(user models.User) int { return len(user.Budgets) }),
+	},
+	{
+		name: "savings",
+		export: func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			archive.savings(user.Savings)
+			return nil
+		},
+		purge: inUserDocument(func(user models.User) int { return len(user.Savings) }),
+	},
+	{
+		name: "consents",
+		export: func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			archive.consents(user.Consents)
+			return nil
+		},
+		purge: inUserDocument(func(user models.User) int { return len(user.Consents) }),
+	},
+	{
+		name:  "passkeys", // listed in profile.json
+		purge: inUserDocument(func(user models.User) int { return len(user.Passkeys) }),
+	},
+	{
+		name: "security_events",
+		export: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			var events []models.SecurityEvent
+			cursor, err := collections.SecurityEvents.Find(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return err
+			}
+			if err = cursor.All(ctx, &events); err != nil {
+				return err
+			}
+			archive.securityEvents(events)
+			return nil
+		},
+		purge: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore,
+			user models.User) (int64, error) {
+			result, err := collections.SecurityEvents.DeleteMany(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return 0, err
+			}
+			return result.DeletedCount, nil
+		},
+	},
+	{
+		name: "notifications",
+		export: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			var notifications []models.Notification
+			cursor, err := collections.Notifications.Find(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return err
+			}
+			if err = cursor.All(ctx, &notifications); err != nil {
+				return err
+			}
+			archive.notifications(notifications)
+			return nil
+		},
+		purge: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore,
+			user models.User) (int64, error) {
+			result, err := collections.Notifications.DeleteMany(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return 0, err
+			}
+			return result.DeletedCount, nil
+		},
+	},
+	{
+		name:   "webhooks",
+		export: exportWebhooks,
+		purge: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore,
+			user models.User) (int64, error) {
+			return deleteUserWebhooks(ctx, collections, user.ID)
+		},
+	},
+	{
+		name: "push_devices",
+		export: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			var subscriptions []models.PushSubscription
+			cursor, err := collections.PushSubscriptions.Find(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return err
+			}
+			if err = cursor.All(ctx, &subscriptions); err != nil {
+				return err
+			}
+			archive.pushDevices(subscriptions)
+			return nil
+		},
+		purge: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore,
+			user models.User) (int64, error) {
+			result, err := collections.PushSubscriptions.DeleteMany(ctx, bson.M{"user_id": user.ID})
+			if err != nil {
+				return 0, err
+			}
+			return result.DeletedCount, nil
+		},
+	},
+	{
+		name: "households",
+		export: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore, user models.User,
+			archive *exportArchive) error {
+			var households []models.Household
+			cursor, err := collections.Households.Find(ctx, bson.M{"members.user_id": user.ID})
+			if err != nil {
+				return err
+			}
+			if err = cursor.All(ctx, &households); err != nil {
+				return err
+			}
+			// other members are listed by ID only, their profiles are theirs to export
+			results := make([]dto.HouseholdResponse, 0, len(households))
+			for _, household := range households {
+				member, _ := household.Member(user.ID)
+				results = append(results, dto.NewHouseholdResponse(household, member.Role, nil))
+			}
+			archive.json("households.json", results)
+			return nil
+		},
+		purge: func(ctx context.Context, collections *data.Collections, _ storage.BlobStore,
+			user models.User) (int64, error) {
+			return leaveHouseholds(ctx, collections, user.ID)
+		},
+	},
+	{
+		name:   "profile_picture",
+		export: exportProfilePicture,
+		purge: func(ctx context.Context, _ *data.Collections, blobs storage.BlobStore,
+			user models.User) (int64, error) {
+			var deleted int64
+			for _, key := range []string{user.ProfilePicture, user.ProfilePictureThumbnail} {
+				if key == "" {
+					continue
+				}
+				if err := blobs.Delete(ctx, key); err != nil {
+					return 0, err
+				}
+				deleted++
+			}
+			return deleted, nil
+		},
+	},
+	{
+		name: "exports", // the archives themselves, so not exported
+		purge: func(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+			user models.User) (int64, error) {
+			return deleteUserExports(ctx, collections, blobs, user.ID)
+		},
+	},
+}
+
+// inUserDocument is the purge of a set held in the user document, which only counts it
+func inUserDocument(count func(user models.User) int) func(context.Context, *data.Collections, storage.BlobStore,
+	models.User) (int64, error) {
+	return func(_ context.Context, _ *data.Collections, _ storage.BlobStore, user models.User) (int64, error) {
+		return int64(count(user)), nil
+	}
+}
+
+// deleteUserData removes everything stored outside the user document of a user being purged. It can be
+// repeated, and returns counts of what was removed for the audit log.
+func deleteUserData(ctx context.Context, collections *data.Collections, blobs storage.BlobStore,
+	user models.User) (map[string]string, error) {
+	counts := make(map[string]string, len(userDataSets))
+	for _, set := range userDataSets {
+		removed, err := set.purge(ctx, collections, blobs, user)
+		if err != nil {
+			return nil, err
+		}
+		counts[set.name] = strconv.FormatInt(removed, 10)
+	}
+	return counts, nil
+}
+
+// exportWebhooks adds the webhooks the user registered for themselves, each with its delivery log
+func exportWebhooks(ctx context.Context, collections *data.Collections, _ storage.BlobStore, user models.User,
+	archive *exportArchive) error {
+	var webhooks []models.Webhook
+	cursor, err := collections.Webhooks.Find(ctx, bson.M{"owner_id": user.ID, "global": false})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return err
+	}
+
+	type exportWebhook struct {
+		dto.WebhookResponse
+		Deliveries []dto.WebhookDeliveryResponse `json:"deliveries"`
+	}
+	results := make([]exportWebhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		var deliveries []models.WebhookDelivery
+		cursor, err = collections.WebhookDeliveries.Find(ctx, bson.M{"webhook_id": webhook.ID})
+		if err != nil {
+			return err
+		}
+		if err = cursor.All(ctx, &deliveries); err != nil {
+			return err
+		}
+		result := exportWebhook{
+			WebhookResponse: dto.NewWebhookResponse(webhook),
+			Deliveries:      make([]dto.WebhookDeliveryResponse, 0, len(deliveries)),
+		}
+		for _, delivery := range deliveries {
+			result.Deliveries = append(result.Deliveries, dto.NewWebhookDeliveryResponse(delivery))
+		}
+		results = append(results, result)
+	}
+	archive.json("webhooks.json", results)
+	return nil
+}
+
+// exportProfilePicture adds the user's profile picture as uploaded, if they have one
+func exportProfilePicture(ctx context.Context, _ *data.Collections, blobs storage.BlobStore, user models.User,
+	archive *exportArchive) error {
+	if user.ProfilePicture == "" {
+		return nil
+	}
+	blob, err := blobs.Get(ctx, user.ProfilePicture)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	archive.file("profile_picture"+path.Ext(user.ProfilePicture), blob)
+	return nil
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+)
+
+// UsernameAvailable reports whether ?username= can be registered, and if not, why
+func (u *Impl) UsernameAvailable(c *gin.Context) {
+	var (
+		username = c.Query("username")
+		log      = slog.With(common.RequestID, c.MustGet(common.RequestID), "username", username)
+		response = dto.UsernameAvailableResponse{Username: username}
+	)
+
+	if err := common.CheckUsername(username); err != nil {
+		response.Reason = "invalid"
+		if errors.Is(err, common.ErrUsernameReserved) {
+			response.Reason = "reserved"
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	taken, err := u.usernameTaken(c, username, "")
+	if err != nil {
+		log.Error("Failed to check username", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if taken {
+		response.Reason = "taken"
+	}
+	response.Available = !taken
+	c.JSON(http.StatusOK, response)
+}
+
+// usernameTaken reports whether another user than exceptUserID already has username, ignoring case.
+// The unique index still has the final say when two users race for the same username.
+func (u *Impl) usernameTaken(ctx context.Context, username, exceptUserID string) (bool, error) {
+	filter := bson.M{"username_lower": common.NormalizeUsername(username), "_id": bson.M{"$ne": exceptUserID}}
+	count, err := u.collections.Users.CountDocuments(ctx, filter)
+	return count > 0, err
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -34,6 +35,10 @@ func (u *Impl) VerifyEmail(c *gin.Context) {
 		return
 	}
 
+	if accountBlocked(c, user) {
+		return
+	}
+
 	// check if user is already verified, user can proceed to login
 	if user.Verified {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
@@ -43,11 +48,14 @@ func (u *Impl) VerifyEmail(c *gin.Context) {
 	// Mark the user as verified
 	update := bson.M{"$set": bson.M{"verified": true}}
 	if _, err = collection.UpdateOne(c, bson.M{"email": user.Email}, update); err != nil {
-		log.Warn("failed to mark the user as verified: ", err)
+		log.Warn("failed to mark the user as verified", "err", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
 		return
 	}
 
+	u.recordSignIn(c, user, "email_verification")
+	u.publishVerified(c, user)
+
 	// generate sessionJWT
 	tokenDuration, tokenString := generateSessionJWT(c, user)
 	if tokenString == "" {
@@ -59,3 +67,16 @@ func (u *Impl) VerifyEmail(c *gin.Context) {
 		ExpiresIn:    tokenDuration.String(),
 	})
 }
+
+// publishVerified sends the models.WebhookUserVerified event about user
+func (u *Impl) publishVerified(c *gin.Context, user models.User) {
+	err := u.webhooks.Publish(c, models.WebhookUserVerified, []string{user.ID}, gin.H{
+		"userId":     user.ID,
+		"email":      user.Email,
+		"verifiedAt": time.Now().UTC(),
+	})
+	if err != nil {
+		slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", user.ID).
+			Error("Failed to publish webhook event", "event", models.WebhookUserVerified, "err", err)
+	}
+}
@@ -0,0 +1,311 @@
+package webhook
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+	webhooks "github.com/harisnkr/expense/webhook"
+)
+
+// maxWebhooksPerOwner caps the webhooks a user, or the admins together, may register
+const maxWebhooksPerOwner = 10
+
+// API is an interface for operations related to models.Webhook. Users manage the webhooks that receive events
+// about them, admins manage global webhooks through the Admin variants.
+type API interface {
+	ListEvents(ctx *gin.Context)
+	CreateWebhook(ctx *gin.Context)
+	ListWebhooks(ctx *gin.Context)
+	GetWebhook(ctx *gin.Context)
+	UpdateWebhook(ctx *gin.Context)
+	DeleteWebhook(ctx *gin.Context)
+	ListDeliveries(ctx *gin.Context)
+	Redeliver(ctx *gin.Context)
+	AdminCreateWebhook(ctx *gin.Context)
+	AdminListWebhooks(ctx *gin.Context)
+	AdminGetWebhook(ctx *gin.Context)
+	AdminUpdateWebhook(ctx *gin.Context)
+	AdminDeleteWebhook(ctx *gin.Context)
+	AdminListDeliveries(ctx *gin.Context)
+	AdminRedeliver(ctx *gin.Context)
+}
+
+// Impl holds dependencies for webhook.API
+type Impl struct {
+	database    *mongo.Client
+	collections *data.Collections
+	publisher   *webhooks.Publisher
+}
+
+// New returns Impl struct with dependencies for using webhook.API
+func New(database *mongo.Client, collections *data.Collections, publisher *webhooks.Publisher) *Impl {
+	return &Impl{database, collections, publisher}
+}
+
+// ListEvents lists the events webhooks can subscribe to
+func (w *Impl) ListEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": models.WebhookEvents})
+}
+
+// CreateWebhook registers a webhook for events about the authenticated user
+func (w *Impl) CreateWebhook(c *gin.Context) { w.createWebhook(c, false) }
+
+// AdminCreateWebhook registers a global webhook, receiving events about every user
+func (w *Impl) AdminCreateWebhook(c *gin.Context) { w.createWebhook(c, true) }
+
+// ListWebhooks lists the authenticated user's webhooks
+func (w *Impl) ListWebhooks(c *gin.Context) { w.listWebhooks(c, userScope(c)) }
+
+// AdminListWebhooks lists the global webhooks
+func (w *Impl) AdminListWebhooks(c *gin.Context) { w.listWebhooks(c, globalScope()) }
+
+// GetWebhook returns one of the authenticated user's webhooks
+func (w *Impl) GetWebhook(c *gin.Context) { w.getWebhook(c, userScope(c)) }
+
+// AdminGetWebhook returns a global webhook
+func (w *Impl) AdminGetWebhook(c *gin.Context) { w.getWebhook(c, globalScope()) }
+
+// UpdateWebhook partially updates one of the authenticated user's webhooks
+func (w *Impl) UpdateWebhook(c *gin.Context) { w.updateWebhook(c, userScope(c)) }
+
+// AdminUpdateWebhook partially updates a global webhook
+func (w *Impl) AdminUpdateWebhook(c *gin.Context) { w.updateWebhook(c, globalScope()) }
+
+// DeleteWebhook deletes one of the authenticated user's webhooks along with its delivery log
+func (w *Impl) DeleteWebhook(c *gin.Context) { w.deleteWebhook(c, userScope(c)) }
+
+// AdminDeleteWebhook deletes a global webhook along with its delivery log
+func (w *Impl) AdminDeleteWebhook(c *gin.Context) { w.deleteWebhook(c, globalScope()) }
+
+// userScope matches the authenticated user's own webhooks
+func userScope(c *gin.Context) bson.M {
+	return bson.M{"owner_id": c.GetString(common.UserID), "global": false}
+}
+
+// globalScope matches the webhooks admins registered
+func globalScope() bson.M {
+	return bson.M{"global": true}
+}
+
+// inScope narrows scope to the webhook with id
+func inScope(scope bson.M, id string) bson.M {
+	filter := bson.M{"_id": id}
+	for key, value := range scope {
+		filter[key] = value
+	}
+	return filter
+}
+
+func (w *Impl) createWebhook(c *gin.Context, global bool) {
+	var (
+		userID = c.GetString(common.UserID)
+		log    = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", userID, "global", global)
+		req    dto.CreateWebhookRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := userScope(c)
+	if global {
+		scope = globalScope()
+	}
+	count, err := w.collections.Webhooks.CountDocuments(c, scope)
+	if err != nil {
+		log.Error("Failed to count webhooks", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if count >= maxWebhooksPerOwner {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many webhooks, delete one first"})
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		log.Error("Failed to generate webhook secret", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	now := time.Now()
+	webhook := models.Webhook{
+		ID:          uuid.New().String(),
+		OwnerID:     userID,
+		Global:      global,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      parseEvents(req.Events),
+		Secret:      secret,
+		Enabled:     true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err = w.collections.Webhooks.InsertOne(c, webhook); err != nil {
+		log.Error("Failed to create webhook", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	log.Info("Webhook created", "webhookID", webhook.ID)
+	response := dto.NewWebhookResponse(webhook)
+	response.Secret = secret
+	c.JSON(http.StatusCreated, response)
+}
+
+func (w *Impl) listWebhooks(c *gin.Context, scope bson.M) {
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", c.GetString(common.UserID))
+
+	cursor, err := w.collections.Webhooks.Find(c, scope, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		log.Error("Failed to find webhooks", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var list []models.Webhook
+	if err = cursor.All(c, &list); err != nil {
+		log.Error("Failed to decode webhooks", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.WebhookResponse, 0, len(list))
+	for _, webhook := range list {
+		results = append(results, dto.NewWebhookResponse(webhook))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": results})
+}
+
+func (w *Impl) getWebhook(c *gin.Context, scope bson.M) {
+	webhook, ok := w.findWebhook(c, scope)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewWebhookResponse(webhook))
+}
+
+func (w *Impl) updateWebhook(c *gin.Context, scope bson.M) {
+	var (
+		webhookID = c.Param("id")
+		log       = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", c.GetString(common.UserID),
+			"webhookID", webhookID)
+		req dto.UpdateWebhookRequest
+	)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	update := bson.M{"$set": set}
+	if req.URL != nil {
+		if err := webhooks.ValidateURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set["url"] = *req.URL
+	}
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+	if req.Events != nil {
+		set["events"] = parseEvents(req.Events)
+	}
+	if req.Enabled != nil {
+		set["enabled"] = *req.Enabled
+		if *req.Enabled {
+			set["consecutive_failures"] = 0
+			update["$unset"] = bson.M{"disabled_reason": "", "disabled_at": ""}
+		} else {
+			set["disabled_reason"] = "Disabled by its owner"
+			set["disabled_at"] = now
+		}
+	}
+
+	var webhook models.Webhook
+	err := w.collections.Webhooks.FindOneAndUpdate(c, inScope(scope, webhookID), update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to update webhook", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.NewWebhookResponse(webhook))
+}
+
+func (w *Impl) deleteWebhook(c *gin.Context, scope bson.M) {
+	var (
+		webhookID = c.Param("id")
+		log       = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", c.GetString(common.UserID),
+			"webhookID", webhookID)
+	)
+
+	result, err := w.collections.Webhooks.DeleteOne(c, inScope(scope, webhookID))
+	if err != nil {
+		log.Error("Failed to delete webhook", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	// deliveries still queued find the webhook gone and fail
+	if _, err = w.collections.WebhookDeliveries.DeleteMany(c, bson.M{"webhook_id": webhookID}); err != nil {
+		log.Error("Failed to delete webhook deliveries", "err", err)
+	}
+	log.Info("Webhook deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// findWebhook loads the webhook with the :id parameter within scope, responding if it cannot
+func (w *Impl) findWebhook(c *gin.Context, scope bson.M) (models.Webhook, bool) {
+	var webhook models.Webhook
+	err := w.collections.Webhooks.FindOne(c, inScope(scope, c.Param("id"))).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return webhook, false
+	}
+	if err != nil {
+		slog.With(common.RequestID, c.MustGet(common.RequestID), "webhookID", c.Param("id")).
+			Error("Failed to find webhook", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return webhook, false
+	}
+	return webhook, true
+}
+
+// parseEvents converts events validated by the webhook_event binding
+func parseEvents(names []string) []models.WebhookEvent {
+	events := make([]models.WebhookEvent, 0, len(names))
+	seen := make(map[models.WebhookEvent]bool, len(names))
+	for _, name := range names {
+		event, _ := models.ParseWebhookEvent(name)
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return events
+}
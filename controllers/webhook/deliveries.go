@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	defaultDeliveriesLimit = 20
+	maxDeliveriesLimit     = 100
+)
+
+// ListDeliveries lists the delivery log of one of the authenticated user's webhooks
+func (w *Impl) ListDeliveries(c *gin.Context) { w.listDeliveries(c, userScope(c)) }
+
+// AdminListDeliveries lists the delivery log of a global webhook
+func (w *Impl) AdminListDeliveries(c *gin.Context) { w.listDeliveries(c, globalScope()) }
+
+// Redeliver sends a logged delivery of one of the authenticated user's webhooks again
+func (w *Impl) Redeliver(c *gin.Context) { w.redeliver(c, userScope(c)) }
+
+// AdminRedeliver sends a logged delivery of a global webhook again
+func (w *Impl) AdminRedeliver(c *gin.Context) { w.redeliver(c, globalScope()) }
+
+// listDeliveries lists a webhook's deliveries newest first, filtered by ?status=.
+// Supports ?page= (from 1) and ?limit= query parameters.
+func (w *Impl) listDeliveries(c *gin.Context, scope bson.M) {
+	webhook, ok := w.findWebhook(c, scope)
+	if !ok {
+		return
+	}
+	log := slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", c.GetString(common.UserID),
+		"webhookID", webhook.ID)
+
+	filter := bson.M{"webhook_id": webhook.ID}
+	switch status := models.WebhookDeliveryStatus(c.Query("status")); status {
+	case "":
+	case models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+		filter["status"] = status
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, succeeded or failed"})
+		return
+	}
+
+	page, limit := common.PaginationParams(c, defaultDeliveriesLimit, maxDeliveriesLimit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := w.collections.WebhookDeliveries.Find(c, filter, opts)
+	if err != nil {
+		log.Error("Failed to find webhook deliveries", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	var deliveries []models.WebhookDelivery
+	if err = cursor.All(c, &deliveries); err != nil {
+		log.Error("Failed to decode webhook deliveries", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	results := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		results = append(results, dto.NewWebhookDeliveryResponse(delivery))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": results, "page": page, "limit": limit})
+}
+
+// redeliver sends the payload of a logged delivery again as a new delivery, with the same event ID.
+// The webhook must be enabled, re-enable it first to redeliver what failed while it was disabled.
+func (w *Impl) redeliver(c *gin.Context, scope bson.M) {
+	webhook, ok := w.findWebhook(c, scope)
+	if !ok {
+		return
+	}
+	var (
+		deliveryID = c.Param("deliveryID")
+		log        = slog.With(common.RequestID, c.MustGet(common.RequestID), "userID", c.GetString(common.UserID),
+			"webhookID", webhook.ID, "deliveryID", deliveryID)
+	)
+	if !webhook.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook is disabled"})
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	err := w.collections.WebhookDeliveries.FindOne(c, bson.M{"_id": deliveryID, "webhook_id": webhook.ID}).
+		Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if err != nil {
+		log.Error("Failed to find webhook delivery", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+
+	redelivery, err := w.publisher.Redeliver(c, delivery)
+	if err != nil {
+		log.Error("Failed to redeliver webhook delivery", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		return
+	}
+	log.Info("Webhook delivery redelivered", "redeliveryID", redelivery.ID)
+	c.JSON(http.StatusAccepted, dto.NewWebhookDeliveryResponse(redelivery))
+}
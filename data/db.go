@@ -12,14 +12,38 @@ import (
 const (
 	databaseName = "expense"
 
-	cardsCollection = "cards"
-	usersCollection = "users"
+	cardsCollection             = "cards"
+	usersCollection             = "users"
+	securityEventsCollection    = "security_events"
+	auditLogsCollection         = "audit_logs"
+	exportsCollection           = "exports"
+	householdsCollection        = "households"
+	policiesCollection          = "policy_versions"
+	outboxCollection            = "outbox"
+	mailboxCollection           = "dev_mailbox"
+	notificationsCollection     = "notifications"
+	webhooksCollection          = "webhooks"
+	webhookDeliveriesCollection = "webhook_deliveries"
+	pushSubscriptionsCollection = "push_subscriptions"
+	announcementsCollection     = "announcements"
 )
 
 // Collections ...
 type Collections struct {
-	Cards *mongo.Collection
-	Users *mongo.Collection
+	Cards             *mongo.Collection
+	Users             *mongo.Collection
+	SecurityEvents    *mongo.Collection
+	AuditLogs         *mongo.Collection
+	Exports           *mongo.Collection
+	Households        *mongo.Collection
+	Policies          *mongo.Collection
+	Outbox            *mongo.Collection
+	Mailbox           *mongo.Collection // development only, see common.DevMailboxEnabled
+	Notifications     *mongo.Collection
+	Webhooks          *mongo.Collection
+	WebhookDeliveries *mongo.Collection
+	PushSubscriptions *mongo.Collection
+	Announcements     *mongo.Collection
 }
 
 // InitDatabase inits MongoDB and its collections
@@ -42,7 +66,19 @@ func InitDatabase(ctx context.Context) (*mongo.Client, *Collections) {
 	log.Info("Connected to MongoDB!")
 
 	return client, &Collections{
-		Cards: client.Database(databaseName).Collection(cardsCollection),
-		Users: client.Database(databaseName).Collection(usersCollection),
+		Cards:             client.Database(databaseName).Collection(cardsCollection),
+		Users:             client.Database(databaseName).Collection(usersCollection),
+		SecurityEvents:    client.Database(databaseName).Collection(securityEventsCollection),
+		AuditLogs:         client.Database(databaseName).Collection(auditLogsCollection),
+		Exports:           client.Database(databaseName).Collection(exportsCollection),
+		Households:        client.Database(databaseName).Collection(householdsCollection),
+		Policies:          client.Database(databaseName).Collection(policiesCollection),
+		Outbox:            client.Database(databaseName).Collection(outboxCollection),
+		Mailbox:           client.Database(databaseName).Collection(mailboxCollection),
+		Notifications:     client.Database(databaseName).Collection(notificationsCollection),
+		Webhooks:          client.Database(databaseName).Collection(webhooksCollection),
+		WebhookDeliveries: client.Database(databaseName).Collection(webhookDeliveriesCollection),
+		PushSubscriptions: client.Database(databaseName).Collection(pushSubscriptionsCollection),
+		Announcements:     client.Database(databaseName).Collection(announcementsCollection),
 	}
 }
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"errors"
+	log "log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	unverifiedTTLIndex = "unverified_ttl"
+	usernameIndex      = "username_unique"
+	policyVersionIndex = "document_version_unique"
+	outboxClaimIndex   = "kind_status_available_at"
+	outboxTTLIndex     = "done_ttl"
+	mailboxTTLIndex    = "created_at_ttl"
+	notificationIndex  = "user_id_created_at"
+	notificationDedup  = "user_id_dedup_key_unique"
+	notificationTTL    = "created_at_ttl"
+	webhookOwnerIndex  = "owner_id"
+	webhookEventIndex  = "events_enabled"
+	deliveryIndex      = "webhook_id_created_at"
+	deliveryTTLIndex   = "created_at_ttl"
+	pushEndpointIndex  = "endpoint_unique"
+	pushUserIndex      = "user_id"
+	pushExpiryIndex    = "expires_at_ttl"
+	announcementIndex  = "status_send_at"
+
+	// indexOptionsConflict is returned when an index exists under the same name with different options
+	indexOptionsConflict = 85
+)
+
+// EnsureUsernameIndex keeps usernames unique ignoring case, for the users that have one
+func EnsureUsernameIndex(ctx context.Context, users *mongo.Collection) error {
+	_, err := users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "username_lower", Value: 1}},
+		Options: options.Index().
+			SetName(usernameIndex).
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"username_lower": bson.M{"$type": "string"}}),
+	})
+	return err
+}
+
+// EnsurePolicyVersionIndex keeps each version of a policy document published only once
+func EnsurePolicyVersionIndex(ctx context.Context, policies *mongo.Collection) error {
+	_, err := policies.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "document", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetName(policyVersionIndex).SetUnique(true),
+	})
+	return err
+}
+
+// EnsureUnverifiedUserTTL keeps a TTL index that makes MongoDB delete users who have not verified their email
+// ttl after verification_sent_at. Changing ttl updates the existing index in place.
+func EnsureUnverifiedUserTTL(ctx context.Context, users *mongo.Collection, ttl time.Duration) error {
+	return ensureTTLIndex(ctx, users, unverifiedTTLIndex, "verification_sent_at", ttl, bson.M{"verified": false})
+}
+
+// EnsureOutboxIndexes keeps the index workers claim outbox entries through, and a TTL index that deletes
+// delivered entries retention after completion. Dead-lettered entries are kept until handled by an admin.
+func EnsureOutboxIndexes(ctx context.Context, outbox *mongo.Collection, retention time.Duration) error {
+	_, err := outbox.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "status", Value: 1}, {Key: "available_at", Value: 1}},
+		Options: options.Index().SetName(outboxClaimIndex),
+	})
+	if err != nil {
+		return err
+	}
+	return ensureTTLIndex(ctx, outbox, outboxTTLIndex, "completed_at", retention,
+		bson.M{"status": models.OutboxDone})
+}
+
+// EnsureMailboxTTL keeps a TTL index that deletes development mailbox messages ttl after they were captured
+func EnsureMailboxTTL(ctx context.Context, mailbox *mongo.Collection, ttl time.Duration) error {
+	return ensureTTLIndex(ctx, mailbox, mailboxTTLIndex, "created_at", ttl, nil)
+}
+
+// EnsureNotificationIndexes keeps the index notification centres are listed through, a unique index that
+// stops the same event notifying a user twice, and a TTL index that deletes notifications retention after creation
+func EnsureNotificationIndexes(ctx context.Context, notifications *mongo.Collection, retention time.Duration) error {
+	_, err := notifications.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName(notificationIndex),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "dedup_key", Value: 1}},
+			Options: options.Index().
+				SetName(notificationDedup).
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"dedup_key": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return ensureTTLIndex(ctx, notifications, notificationTTL, "created_at", retention, nil)
+}
+
+// EnsureWebhookIndexes keeps the indexes webhooks are listed by owner and matched to events through, and the
+// delivery log's index with a TTL index that deletes deliveries retention after they were created
+func EnsureWebhookIndexes(ctx context.Context, webhooks, deliveries *mongo.Collection,
+	retention time.Duration) error {
+	_, err := webhooks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}}, Options: options.Index().SetName(webhookOwnerIndex)},
+		{
+			Keys:    bson.D{{Key: "events", Value: 1}, {Key: "enabled", Value: 1}},
+			Options: options.Index().SetName(webhookEventIndex),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = deliveries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName(deliveryIndex),
+	})
+	if err != nil {
+		return err
+	}
+	return ensureTTLIndex(ctx, deliveries, deliveryTTLIndex, "created_at", retention, nil)
+}
+
+// EnsurePushIndexes keeps push endpoints unique, so a device re-subscribing replaces its subscription, indexes
+// subscriptions by user and has MongoDB delete subscriptions once the expiry their push service set has passed
+func EnsurePushIndexes(ctx context.Context, subscriptions *mongo.Collection) error {
+	_, err := subscriptions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "endpoint", Value: 1}},
+			Options: options.Index().SetName(pushEndpointIndex).SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName(pushUserIndex)},
+	})
+	if err != nil {
+		return err
+	}
+	return ensureTTLIndex(ctx, subscriptions, pushExpiryIndex, "expires_at", 0,
+		bson.M{"expires_at": bson.M{"$exists": true}})
+}
+
+// EnsureAnnouncementIndex keeps the index the announcement job finds announcements that are due through
+func EnsureAnnouncementIndex(ctx context.Context, announcements *mongo.Collection) error {
+	_, err := announcements.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}},
+		Options: options.Index().SetName(announcementIndex),
+	})
+	return err
+}
+
+// ensureTTLIndex creates a TTL index on field, partial if partial is not nil, or updates the TTL of the existing index in place
+func ensureTTLIndex(ctx context.Context, collection *mongo.Collection, name, field string, ttl time.Duration,
+	partial bson.M) error {
+	seconds := int32(ttl.Seconds())
+
+	opts := options.Index().SetName(name).SetExpireAfterSeconds(seconds)
+	if partial != nil {
+		opts.SetPartialFilterExpression(partial)
+	}
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}, Options: opts})
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Code != indexOptionsConflict {
+		return err
+	}
+
+	log.Info("Updating TTL index", "collection", collection.Name(), "index", name, "ttl", ttl)
+	return collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection.Name()},
+		{Key: "index", Value: bson.M{"name": name, "expireAfterSeconds": seconds}},
+	}).Err()
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// CurrentPolicyVersions returns the latest published version of each policy document.
+// Documents that were never published are missing from the map and need no consent.
+func CurrentPolicyVersions(ctx context.Context, policies *mongo.Collection) (map[models.PolicyDocument]models.PolicyVersion, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "published_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$document"},
+			{Key: "latest", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceWith", Value: "$latest"}},
+	}
+	cursor, err := policies.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var versions []models.PolicyVersion
+	if err = cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	current := make(map[models.PolicyDocument]models.PolicyVersion, len(versions))
+	for _, version := range versions {
+		current[version.Document] = version
+	}
+	return current, nil
+}
@@ -0,0 +1,156 @@
+package digest
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+// Kind is how often a digest is sent, which is also the models.Period it summarises
+type Kind models.Period
+
+const (
+	Weekly  = Kind(models.Weekly)
+	Monthly = Kind(models.Monthly)
+)
+
+// sendWindow is how long after its scheduled time a digest may still go out. A digest missed for longer,
+// e.g. because the user only opted in later, is skipped rather than sent late.
+const sendWindow = 24 * time.Hour
+
+// period is the span a digest summarises, in the user's timezone, claimed under key
+type period struct {
+	kind  Kind
+	start time.Time
+	end   time.Time // exclusive, also the day the digest is sent
+	key   string
+}
+
+// SendDue emails the weekly and monthly digests that are due, each at config.DigestHour on its day in the
+// user's timezone. Every digest is claimed on the user before it is enqueued, so any number of instances can
+// run SendDue at once without sending a digest twice.
+func SendDue(ctx context.Context, collections *data.Collections, box *outbox.Outbox) {
+	var (
+		log = slog.With("func", "SendDue")
+		now = time.Now()
+	)
+
+	filter := bson.M{
+		"verified":    true,
+		"deleted_at":  bson.M{"$exists": false},
+		"disabled_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"preferences.digests.weekly": true},
+			{"preferences.digests.monthly": true},
+		},
+	}
+	opts := options.Find().SetProjection(bson.M{"preferences": 1, "digests_sent": 1})
+	cursor, err := collections.Users.Find(ctx, filter, opts)
+	if err != nil {
+		log.Error("Failed to find users with digests", "err", err)
+		return
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		log.Error("Failed to decode users with digests", "err", err)
+		return
+	}
+
+	for _, user := range users {
+		prefs := user.EffectivePreferences()
+		for _, kind := range []Kind{Weekly, Monthly} {
+			p, due := duePeriod(kind, prefs, now)
+			if !enabledFor(prefs.Digests, kind) || !due || sentFor(user.DigestsSent, kind) == p.key {
+				continue
+			}
+			if err = send(ctx, collections, box, user, p); err != nil {
+				log.Error("Failed to send digest", "err", err, "userID", user.ID, "kind", kind, "period", p.key)
+			}
+		}
+	}
+}
+
+// send claims the digest for p on the user and enqueues it, releasing the claim if it cannot be enqueued
+func send(ctx context.Context, collections *data.Collections, box *outbox.Outbox, claimed models.User,
+	p period) error {
+	var (
+		log      = slog.With("func", "SendDue", "userID", claimed.ID, "kind", p.kind, "period", p.key)
+		field    = "digests_sent." + string(p.kind)
+		previous = sentFor(claimed.DigestsSent, p.kind)
+	)
+
+	// claim the digest first so concurrent runs never send it twice
+	result, err := collections.Users.UpdateOne(ctx, bson.M{"_id": claimed.ID, field: bson.M{"$ne": p.key}},
+		bson.M{"$set": bson.M{field: p.key}})
+	if err != nil || result.ModifiedCount == 0 {
+		return err
+	}
+
+	var user models.User
+	if err = collections.Users.FindOne(ctx, bson.M{"_id": claimed.ID}).Decode(&user); err != nil {
+		return release(ctx, collections, claimed.ID, field, p.key, previous, err)
+	}
+	digest, ok := build(user, p)
+	if !ok {
+		log.Debug("Nothing to report, skipping digest")
+		return nil
+	}
+	err = box.EnqueueEmail(ctx, user.Email, user.EffectivePreferences().Locale, email.TemplateDigest, digest)
+	if err != nil {
+		return release(ctx, collections, claimed.ID, field, p.key, previous, err)
+	}
+	log.Info("Enqueued digest")
+	return nil
+}
+
+// release hands a claimed digest back so the next run tries again, returning cause
+func release(ctx context.Context, collections *data.Collections, userID, field, key, previous string,
+	cause error) error {
+	update := bson.M{"$set": bson.M{field: previous}}
+	if previous == "" {
+		update = bson.M{"$unset": bson.M{field: ""}}
+	}
+	if _, err := collections.Users.UpdateOne(ctx, bson.M{"_id": userID, field: key}, update); err != nil {
+		slog.Error("Failed to release digest", "err", err, "userID", userID, "field", field)
+	}
+	return cause
+}
+
+// duePeriod returns the period the digest of kind is sent for at now, reporting whether it is due:
+// config.DigestHour has passed on its day, by no more than sendWindow
+func duePeriod(kind Kind, prefs models.Preferences, now time.Time) (period, bool) {
+	// the digest is for the period before the one now is in
+	p := period{kind: kind}
+	p.end, _ = prefs.PeriodBounds(models.Period(kind), now)
+	p.start, _ = prefs.PeriodBounds(models.Period(kind), p.end.AddDate(0, 0, -1))
+	p.key = p.start.Format(time.DateOnly)
+	if kind == Monthly {
+		p.key = p.start.Format("2006-01")
+	}
+
+	sendAt := time.Date(p.end.Year(), p.end.Month(), p.end.Day(), config.DigestHour, 0, 0, 0, p.end.Location())
+	return p, !now.Before(sendAt) && now.Before(sendAt.Add(sendWindow))
+}
+
+func enabledFor(digests models.DigestPreferences, kind Kind) bool {
+	if kind == Monthly {
+		return digests.Monthly
+	}
+	return digests.Weekly
+}
+
+func sentFor(sent models.DigestsSent, kind Kind) string {
+	if kind == Monthly {
+		return sent.Monthly
+	}
+	return sent.Weekly
+}
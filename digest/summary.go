@@ -0,0 +1,233 @@
+package digest
+
+import (
+	"sort"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+)
+
+const (
+	transactionTypeExpense = "expense"
+
+	// noCard groups spending on transactions without a card
+	noCard = "Other"
+
+	// dateLayout formats the dates of a digest
+	dateLayout = "2 Jan 2006"
+)
+
+// build summarises the user's spending over p, reporting false if there is nothing to report
+func build(user models.User, p period) (email.DigestData, bool) {
+	var (
+		prefs    = user.EffectivePreferences()
+		printer  = message.NewPrinter(language.Make(prefs.Locale))
+		money    = moneyFormatter(printer, prefs.HomeCurrency)
+		cards    = make(map[string]models.Card, len(user.Cards))
+		expenses []models.Transaction
+		perCard  = make(map[string][]models.Transaction)
+
+		// the bonus cap counts spending since the start of the reward cycle, which may be before p
+		cycleStart, _ = prefs.PeriodBounds(models.Monthly, p.start)
+	)
+	for _, card := range user.Cards {
+		cards[card.ID] = card
+	}
+	for _, transaction := range user.Transactions {
+		if transaction.Type != transactionTypeExpense {
+			continue
+		}
+		if within(transaction.Date, p.start, p.end) {
+			expenses = append(expenses, transaction)
+		}
+		if _, ok := cards[transaction.CardID]; ok && within(transaction.Date, cycleStart, p.end) {
+			perCard[transaction.CardID] = append(perCard[transaction.CardID], transaction)
+		}
+	}
+
+	var (
+		total      float64
+		byCategory = make(map[string]float64)
+		byCard     = make(map[string]float64)
+	)
+	for _, transaction := range expenses {
+		total += transaction.Amount
+		byCategory[transaction.Category] += transaction.Amount
+		name := noCard
+		if card, ok := cards[transaction.CardID]; ok {
+			name = card.Name
+		}
+		byCard[name] += transaction.Amount
+	}
+
+	var miles float64
+	for cardID, transactions := range perCard {
+		miles += milesEarned(cards[cardID].Miles, transactions, prefs, p.start)
+	}
+
+	digest := email.DigestData{
+		Name:       user.FirstName,
+		Monthly:    p.kind == Monthly,
+		From:       p.start.Format(dateLayout),
+		To:         p.end.AddDate(0, 0, -1).Format(dateLayout),
+		Total:      money(total),
+		Categories: lines(byCategory, total, money),
+		Cards:      lines(byCard, total, money),
+		Budgets:    budgets(user, p, money),
+		Upcoming:   upcoming(user.Cards, prefs, p),
+	}
+	if len(byCard) == 1 && byCard[noCard] > 0 {
+		digest.Cards = nil // says nothing the total does not
+	}
+	if miles >= 1 {
+		digest.Miles = printer.Sprintf("%d", int64(miles))
+	}
+	return digest, len(expenses) > 0 || len(digest.Budgets) > 0 || len(digest.Upcoming) > 0
+}
+
+// moneyFormatter formats amounts in the user's currency and locale
+func moneyFormatter(printer *message.Printer, code string) func(float64) string {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return func(amount float64) string { return printer.Sprintf("%s %.2f", code, amount) }
+	}
+	return func(amount float64) string { return printer.Sprint(currency.ISO(unit.Amount(amount))) }
+}
+
+// lines lists amounts largest first with their share of total
+func lines(amounts map[string]float64, total float64, money func(float64) string) []email.DigestLine {
+	results := make([]email.DigestLine, 0, len(amounts))
+	for name, amount := range amounts {
+		results = append(results, email.DigestLine{Name: name, Amount: money(amount), Share: percent(amount, total)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if amounts[results[i].Name] != amounts[results[j].Name] {
+			return amounts[results[i].Name] > amounts[results[j].Name]
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// budgets reports the budgets running during p, with what was spent from their start to the end of p.
+// Budgets run from the start of their first day to the end of their last, in the user's timezone.
+func budgets(user models.User, p period, money func(float64) string) []email.DigestBudget {
+	var (
+		prefs   = user.EffectivePreferences()
+		results []email.DigestBudget
+	)
+	for _, budget := range user.Budgets {
+		start := prefs.StartOfDay(budget.StartDate)
+		end := prefs.StartOfDay(budget.EndDate).AddDate(0, 0, 1)
+		if budget.Amount <= 0 || !start.Before(p.end) || !end.After(p.start) {
+			continue
+		}
+		var spent float64
+		for _, transaction := range user.Transactions {
+			if transaction.Type == transactionTypeExpense && transaction.Category == budget.Category &&
+				within(transaction.Date, start, end) && transaction.Date.Before(p.end) {
+				spent += transaction.Amount
+			}
+		}
+		results = append(results, email.DigestBudget{
+			Category: budget.Category,
+			Spent:    money(spent),
+			Amount:   money(budget.Amount),
+			Percent:  percent(spent, budget.Amount),
+			Over:     spent > budget.Amount,
+		})
+	}
+	return results
+}
+
+// upcoming lists the statement and annual fee dates of cards within the next period the length of p
+func upcoming(cards []models.Card, prefs models.Preferences, p period) []email.DigestDate {
+	var (
+		from    = p.end
+		until   = from.Add(p.end.Sub(p.start))
+		results []email.DigestDate
+		dates   []time.Time
+	)
+	add := func(card models.Card, statement bool, on time.Time) {
+		if !on.Before(from) && on.Before(until) {
+			results = append(results, email.DigestDate{Card: card.Name, Statement: statement, On: on.Format(dateLayout)})
+			dates = append(dates, on)
+		}
+	}
+	for _, card := range cards {
+		if card.StatementDay > 0 {
+			add(card, true, card.NextStatementDate(from))
+		}
+		if card.AnnualFeeDue != nil {
+			add(card, false, card.NextAnnualFeeDue(prefs, from))
+		}
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return dates[order[i]].Before(dates[order[j]]) })
+	sorted := make([]email.DigestDate, 0, len(results))
+	for _, i := range order {
+		sorted = append(sorted, results[i])
+	}
+	return sorted
+}
+
+// milesEarned estimates the miles a card earned on the transactions from on. Spending in the card's bonus
+// categories earns the bonus multiplier up to BonusMultiplierCap spent in the reward cycle, if there is a
+// cap, and the local multiplier beyond it. Foreign spending earns the overseas multiplier and everything
+// else the local one. Transactions before from only count towards the cap.
+func milesEarned(miles models.Miles, transactions []models.Transaction, prefs models.Preferences,
+	from time.Time) float64 {
+	transactions = append([]models.Transaction(nil), transactions...)
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+
+	var (
+		earned     float64
+		bonusSpent = make(map[int64]float64) // by the Unix time the reward cycle starts
+	)
+	for _, transaction := range transactions {
+		var (
+			category, known = models.ParseSpendCategory(transaction.Category)
+			amount          float64
+		)
+		switch {
+		case known && miles.IsBonus(category):
+			cycle, _ := prefs.PeriodBounds(models.Monthly, transaction.Date)
+			bonus := transaction.Amount
+			if miles.BonusMultiplierCap > 0 {
+				bonus = max(0, min(bonus, miles.BonusMultiplierCap-bonusSpent[cycle.Unix()]))
+			}
+			bonusSpent[cycle.Unix()] += bonus
+			amount = bonus*miles.BonusMultiplier + (transaction.Amount-bonus)*float64(miles.LocalMultiplier)
+		case known && category == models.Foreign:
+			amount = transaction.Amount * float64(miles.OverseasMultiplier)
+		default:
+			amount = transaction.Amount * float64(miles.LocalMultiplier)
+		}
+		if !transaction.Date.Before(from) {
+			earned += amount
+		}
+	}
+	return earned
+}
+
+// within reports whether t is in [start, end)
+func within(t, start, end time.Time) bool {
+	return !t.Before(start) && t.Before(end)
+}
+
+// percent is part as a whole percentage of total
+func percent(part, total float64) int {
+	if total <= 0 {
+		return 0
+	}
+	return int(part / total * 100)
+}
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
 // AdminDeleteCardRequest is the request body to delete a card listed
 type AdminDeleteCardRequest struct {
 	Name       string `json:"name"`
@@ -12,3 +18,79 @@ type AdminUpdateCardRequest struct {
 	ID      string                 `binding:"required" json:"id"`
 	Updates map[string]interface{} `binding:"required" json:"updates"`
 }
+
+// AdminListUsersQuery is the query string for GET /admin/users, all filters are optional
+type AdminListUsersQuery struct {
+	Verified    *bool     `form:"verified"`
+	CreatedFrom time.Time `form:"createdFrom"` // RFC 3339, inclusive
+	CreatedTo   time.Time `form:"createdTo"`   // RFC 3339, exclusive
+	EmailPrefix string    `form:"email"`
+}
+
+// AdminUserSummary is a user as listed by GET /admin/users
+type AdminUserSummary struct {
+	ID         string     `json:"id"`
+	FirstName  string     `json:"firstName"`
+	LastName   string     `json:"lastName"`
+	Email      string     `json:"email"`
+	Username   string     `json:"username,omitempty"`
+	Verified   bool       `json:"verified"`
+	Role       string     `json:"role,omitempty"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// AdminUserResponse is the response body for GET /admin/users/:id, the user's own view plus account state
+type AdminUserResponse struct {
+	UserResponse
+	Role               string               `json:"role,omitempty"`
+	VerificationSentAt time.Time            `json:"verificationSentAt"`
+	DisabledAt         *time.Time           `json:"disabledAt,omitempty"`
+	DisabledReason     string               `json:"disabledReason,omitempty"`
+	DeletedAt          *time.Time           `json:"deletedAt,omitempty"`
+	PurgeAfter         *time.Time           `json:"purgeAfter,omitempty"`
+	KnownDevices       []models.KnownDevice `json:"knownDevices"`
+}
+
+// AdminReasonRequest is the request body for admin actions that must be justified for the audit log
+type AdminReasonRequest struct {
+	Reason string `binding:"required,max=500" json:"reason"`
+}
+
+// ImpersonationResponse is the response body for POST /admin/users/:id/impersonate
+type ImpersonationResponse struct {
+	SessionToken   string `json:"sessionToken"`
+	ExpiresIn      string `json:"expiresIn"`
+	ImpersonatedID string `json:"impersonatedUserID"`
+}
+
+// NewAdminUserSummary converts models.User for an admin user listing
+func NewAdminUserSummary(user models.User) AdminUserSummary {
+	return AdminUserSummary{
+		ID:         user.ID,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Username:   user.Username,
+		Verified:   user.Verified,
+		Role:       user.Role,
+		DisabledAt: user.DisabledAt,
+		DeletedAt:  user.DeletedAt,
+		CreatedAt:  user.CreatedAt,
+	}
+}
+
+// NewAdminUserResponse converts models.User for an admin viewing a single user
+func NewAdminUserResponse(user models.User) AdminUserResponse {
+	return AdminUserResponse{
+		UserResponse:       NewUserResponse(user),
+		Role:               user.Role,
+		VerificationSentAt: user.VerificationSentAt,
+		DisabledAt:         user.DisabledAt,
+		DisabledReason:     user.DisabledReason,
+		DeletedAt:          user.DeletedAt,
+		PurgeAfter:         user.PurgeAfter,
+		KnownDevices:       user.KnownDevices,
+	}
+}
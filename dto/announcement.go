@@ -0,0 +1,111 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// AnnouncementSegment selects the users an announcement is for, every condition given must hold
+type AnnouncementSegment struct {
+	CardIDs  []string `binding:"omitempty,max=20,dive,required" json:"cardIds"` // holders of any of these cards
+	Verified *bool    `json:"verified"`
+
+	// Timezones select a region, as users have none of their own: it is the IANA timezones in the region,
+	// e.g. ["Asia/Singapore"] or ["Asia/Kuala_Lumpur", "Asia/Kuching"], matched against users' chosen timezone
+	Timezones []string `binding:"omitempty,max=50,dive,timezone" json:"timezones"`
+}
+
+// CreateAnnouncementRequest is the request body for POST /admin/announcements.
+// The announcement is scheduled for SendAt if given, and otherwise kept as a draft.
+type CreateAnnouncementRequest struct {
+	Title   string              `binding:"required,max=120"  json:"title"`
+	Body    string              `binding:"required,max=5000" json:"body"` // paragraphs separated by blank lines
+	Segment AnnouncementSegment `json:"segment"`
+	SendAt  *time.Time          `json:"sendAt"`
+
+	// ServiceNotice delivers it to every holder of the segment's cards, whether or not they opted in to
+	// product updates. It needs a segment with cardIds.
+	ServiceNotice bool `json:"serviceNotice"`
+}
+
+// UpdateAnnouncementRequest is the request body for PATCH /admin/announcements/:id, omitted fields are left
+// unchanged. Only drafts and scheduled announcements can be changed.
+type UpdateAnnouncementRequest struct {
+	Title   *string              `binding:"omitempty,min=1,max=120"  json:"title"`
+	Body    *string              `binding:"omitempty,min=1,max=5000" json:"body"`
+	Segment *AnnouncementSegment `json:"segment"`
+
+	ServiceNotice *bool `json:"serviceNotice"` // see CreateAnnouncementRequest.ServiceNotice
+}
+
+// ScheduleAnnouncementRequest is the request body for POST /admin/announcements/:id/schedule,
+// an omitted or past SendAt sends it now
+type ScheduleAnnouncementRequest struct {
+	SendAt *time.Time `json:"sendAt"`
+}
+
+// AnnouncementPreviewRequest is the request body for POST /admin/announcements/preview
+type AnnouncementPreviewRequest struct {
+	AnnouncementSegment
+	ServiceNotice bool `json:"serviceNotice"` // see CreateAnnouncementRequest.ServiceNotice
+}
+
+// AnnouncementPreviewResponse is the response body for POST /admin/announcements/preview
+type AnnouncementPreviewResponse struct {
+	Matching   int64 `json:"matching"`   // active users in the segment
+	Recipients int64 `json:"recipients"` // those of them who are notified
+
+	// OptedOut are the users in the segment left out for not opting in to product updates, which is off by
+	// default. It is 0 for a service notice.
+	OptedOut int64 `json:"optedOut"`
+}
+
+// AnnouncementResponse is an announcement as shown to admins
+type AnnouncementResponse struct {
+	ID            string              `json:"id"`
+	Title         string              `json:"title"`
+	Body          string              `json:"body"`
+	Segment       AnnouncementSegment `json:"segment"`
+	ServiceNotice bool                `json:"serviceNotice"`
+	Status        string              `json:"status"`
+	SendAt        *time.Time          `json:"sendAt,omitempty"`
+	CreatedBy     string              `json:"createdBy"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	UpdatedAt     time.Time           `json:"updatedAt"`
+	StartedAt     *time.Time          `json:"startedAt,omitempty"`
+	CompletedAt   *time.Time          `json:"completedAt,omitempty"`
+	CancelledAt   *time.Time          `json:"cancelledAt,omitempty"`
+	Recipients    int                 `json:"recipients"`
+	Processed     int                 `json:"processed"`
+}
+
+// ToModel converts the segment for storage
+func (s AnnouncementSegment) ToModel() models.AnnouncementSegment {
+	return models.AnnouncementSegment{CardIDs: s.CardIDs, Verified: s.Verified, Timezones: s.Timezones}
+}
+
+// NewAnnouncementResponse converts models.Announcement for admins
+func NewAnnouncementResponse(a models.Announcement) AnnouncementResponse {
+	return AnnouncementResponse{
+		ID:    a.ID,
+		Title: a.Title,
+		Body:  a.Body,
+		Segment: AnnouncementSegment{
+			CardIDs:   a.Segment.CardIDs,
+			Verified:  a.Segment.Verified,
+			Timezones: a.Segment.Timezones,
+		},
+		ServiceNotice: a.ServiceNotice,
+		Status:        string(a.Status),
+		SendAt:        a.SendAt,
+		CreatedBy:     a.CreatedBy,
+		CreatedAt:     a.CreatedAt,
+		UpdatedAt:     a.UpdatedAt,
+		StartedAt:     a.StartedAt,
+		CompletedAt:   a.CompletedAt,
+		CancelledAt:   a.CancelledAt,
+		Recipients:    a.Recipients,
+		Processed:     a.Processed,
+	}
+}
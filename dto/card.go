@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// CardResponse is the client view of a models.Card returned by card endpoints
+type CardResponse struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	IssuerBank string                 `json:"issuerBank"`
+	Network    string                 `json:"network"`
+	Miles      MilesResponse          `json:"miles"`
+	AnnualFee  float64                `json:"annualFee"`
+	Image      string                 `json:"image"`
+	Other      map[string]interface{} `json:"other,omitempty"`
+
+	StatementDay int        `json:"statementDay,omitempty"` // only on the user's own cards
+	AnnualFeeDue *time.Time `json:"annualFeeDue,omitempty"`
+}
+
+// MilesResponse is the client view of models.Miles
+type MilesResponse struct {
+	BonusMultiplier    float64  `json:"bonusMultiplier"`
+	BonusMultiplierCap float64  `json:"bonusMultiplierCap"`
+	LocalMultiplier    float32  `json:"localMultiplier"`
+	OverseasMultiplier float32  `json:"overseasMultiplier"`
+	MinimumSpend       float32  `json:"minimumSpend"`
+	SpendCategories    []string `json:"spendCategories"`
+}
+
+// NewCardResponse converts a models.Card for the client
+func NewCardResponse(card models.Card) CardResponse {
+	categories := make([]string, 0, len(card.Miles.SpendCategories))
+	for _, category := range card.Miles.SpendCategories {
+		categories = append(categories, category.String())
+	}
+
+	return CardResponse{
+		ID:         card.ID,
+		Name:       card.Name,
+		IssuerBank: card.IssuerBank,
+		Network:    card.Network,
+		Miles: MilesResponse{
+			BonusMultiplier:    card.Miles.BonusMultiplier,
+			BonusMultiplierCap: card.Miles.BonusMultiplierCap,
+			LocalMultiplier:    card.Miles.LocalMultiplier,
+			OverseasMultiplier: card.Miles.OverseasMultiplier,
+			MinimumSpend:       card.Miles.MinimumSpend,
+			SpendCategories:    categories,
+		},
+		AnnualFee: card.AnnualFee,
+		Image:     card.Image,
+		Other:     card.Other,
+
+		StatementDay: card.StatementDay,
+		AnnualFeeDue: card.AnnualFeeDue,
+	}
+}
+
+// NewCardResponses converts a list of models.Card for the client
+func NewCardResponses(cards []models.Card) []CardResponse {
+	results := make([]CardResponse, 0, len(cards))
+	for _, card := range cards {
+		results = append(results, NewCardResponse(card))
+	}
+	return results
+}
+
+// CardSearchQuery is the query string for GET /cards/search, all filters are optional. Repeat network,
+// issuerBank or category to match any of the values given. Sort is name (the default), annualFee,
+// localMultiplier, overseasMultiplier or bonusMultiplier; multipliers are ordered highest first unless
+// Order is asc, and the rest lowest first unless Order is desc.
+type CardSearchQuery struct {
+	Query                 string   `binding:"omitempty,max=100"         form:"q"` // matched against name and issuer
+	Networks              []string `binding:"omitempty,max=20"          form:"network"`
+	IssuerBanks           []string `binding:"omitempty,max=20"          form:"issuerBank"`
+	Categories            []string `binding:"omitempty,max=20"          form:"category"` // spend category names
+	MinLocalMultiplier    float64  `binding:"omitempty,min=0"           form:"minLocalMultiplier"`
+	MinOverseasMultiplier float64  `binding:"omitempty,min=0"           form:"minOverseasMultiplier"`
+	MinAnnualFee          float64  `binding:"omitempty,min=0"           form:"minAnnualFee"`
+	MaxAnnualFee          *float64 `binding:"omitempty,min=0"           form:"maxAnnualFee"`
+	Sort                  string   `form:"sort"`
+	Order                 string   `binding:"omitempty,oneof=asc desc" form:"order"`
+}
+
+// CardSearchResponse is the response body for GET /cards/search
+type CardSearchResponse struct {
+	Items  []CardResponse `json:"items"`
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+	Total  int64          `json:"total"`
+	Facets CardFacets     `json:"facets"`
+}
+
+// CardFacets counts the cards matching a search for each value of each filter. The counts for a filter
+// apply every other filter but not its own, so they tell how many cards choosing another value would find.
+type CardFacets struct {
+	Networks            []FacetCount           `json:"networks"`
+	IssuerBanks         []FacetCount           `json:"issuerBanks"`
+	SpendCategories     []FacetCount           `json:"spendCategories"`
+	LocalMultipliers    []MultiplierFacetCount `json:"localMultipliers"`
+	OverseasMultipliers []MultiplierFacetCount `json:"overseasMultipliers"`
+	AnnualFees          []AnnualFeeFacetCount  `json:"annualFees"`
+}
+
+// FacetCount is how many cards have Value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// MultiplierFacetCount is how many cards earn exactly Value miles per dollar
+type MultiplierFacetCount struct {
+	Value float32 `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// AnnualFeeFacetCount is how many cards have an annual fee from Min up to but excluding Max, which is
+// omitted for the highest band
+type AnnualFeeFacetCount struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+}
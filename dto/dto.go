@@ -1,11 +1,35 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/models"
+)
+
 // RegisterUserRequest is the request body for /user/register.
 type RegisterUserRequest struct {
-	FirstName string `binding:"required,name"     json:"firstName"`
-	LastName  string `binding:"required,name"     json:"lastName"`
-	Email     string `binding:"required,email"    json:"email"`
-	Password  string `binding:"required,password" json:"password"`
+	FirstName string `binding:"required,name"      json:"firstName"`
+	LastName  string `binding:"required,name"      json:"lastName"`
+	Email     string `binding:"required,email"     json:"email"`
+	Username  string `binding:"omitempty,username" json:"username"` // optional, unique ignoring case
+	Password  string `binding:"required"           json:"password"` // checked against common.CheckPassword
+
+	// TermsVersion and PrivacyVersion are the policy versions the user was shown and accepted,
+	// required once a version of the document has been published
+	TermsVersion   string `json:"termsVersion"`
+	PrivacyVersion string `json:"privacyVersion"`
+}
+
+// Accepted returns the version of document the user accepted when registering
+func (r *RegisterUserRequest) Accepted(document models.PolicyDocument) string {
+	switch document {
+	case models.TermsOfService:
+		return r.TermsVersion
+	case models.PrivacyPolicy:
+		return r.PrivacyVersion
+	}
+	return ""
 }
 
 // UserEmailVerifyRequest is the request body for POST /user/email/verify
@@ -14,10 +38,17 @@ type UserEmailVerifyRequest struct {
 	VerificationCode string `binding:"required"       json:"verificationCode"`
 }
 
-// UserLoginRequest is the request body for POST /user/login
+// UserLoginRequest is the request body for POST /user/login, identifying the user by email or username
 type UserLoginRequest struct {
-	Email    string `binding:"required,email"    json:"email"`
-	Password string `binding:"required,password" json:"password"`
+	Email    string `binding:"required_without=Username,omitempty,email" json:"email"`
+	Username string `binding:"required_without=Email"                    json:"username"`
+	Password string `binding:"required"                                  json:"password"` // not policy-checked, existing passwords predate policy changes
+}
+
+// PasswordPolicyErrorResponse is returned when a new password fails one or more policy rules
+type PasswordPolicyErrorResponse struct {
+	Error      string                     `json:"error"`
+	Violations []common.PasswordViolation `json:"violations"`
 }
 
 // UserLoginResponse is the response body for POST /user/login
@@ -26,14 +57,95 @@ type UserLoginResponse struct {
 	ExpiresIn    string `json:"expiresIn"`
 }
 
-// AddCardToUserRequest is the request body for POST /user/card
+// MagicLinkRequest is the request body for POST /user/login/link
+type MagicLinkRequest struct {
+	Email string `binding:"required,email" json:"email"`
+}
+
+// PasswordResetLinkRequest is the request body for POST /user/password/reset/request
+type PasswordResetLinkRequest struct {
+	Email string `binding:"required,email" json:"email"`
+}
+
+// ResetPasswordRequest is the request body for POST /user/password/reset
+type ResetPasswordRequest struct {
+	Token    string `binding:"required" json:"token"`
+	Password string `binding:"required" json:"password"` // checked against common.CheckPassword
+}
+
+// ConsumeMagicLinkRequest is the request body for POST /user/login/link/consume
+type ConsumeMagicLinkRequest struct {
+	Token string `binding:"required" json:"token"`
+}
+
+// ChangeEmailRequest is the request body for POST /user/email. Users with a password re-enter it.
+type ChangeEmailRequest struct {
+	Email    string `binding:"required,email" json:"email"`
+	Password string `json:"password"`
+}
+
+// ConfirmEmailChangeRequest is the request body for POST /user/email/confirm, with the code sent to the new address
+type ConfirmEmailChangeRequest struct {
+	Code string `binding:"required" json:"code"`
+}
+
+// BeginPasskeyLoginRequest is the request body for POST /user/passkey/login/begin
+type BeginPasskeyLoginRequest struct {
+	Email string `binding:"required,email" json:"email"`
+}
+
+// PasskeyRegisteredResponse is the response body for POST /user/passkey/register/finish
+type PasskeyRegisteredResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DeleteUserRequest is the request body for DELETE /user. The password re-authenticates the user, or for
+// accounts without one the code emailed by POST /user/delete/code.
+type DeleteUserRequest struct {
+	Password string `binding:"required_without=Code"     json:"password"`
+	Code     string `binding:"required_without=Password" json:"code"`
+}
+
+// DeleteUserResponse is the response body for DELETE /user
+type DeleteUserResponse struct {
+	Message      string    `json:"message"`
+	RestoreUntil time.Time `json:"restoreUntil"`
+}
+
+// RestoreUserRequest is the request body for POST /user/restore, re-authenticated like DeleteUserRequest
+// with the code emailed by POST /user/restore/code
+type RestoreUserRequest struct {
+	Email    string `binding:"required,email"            json:"email"`
+	Password string `binding:"required_without=Code"     json:"password"`
+	Code     string `binding:"required_without=Password" json:"code"`
+}
+
+// RestoreCodeRequest is the request body for POST /user/restore/code
+type RestoreCodeRequest struct {
+	Email string `binding:"required,email" json:"email"`
+}
+
+// AddCardToUserRequest is the request body for POST /user/card.
+// StatementDay and AnnualFeeDue are optional, they feed the upcoming dates of spending digests.
 type AddCardToUserRequest struct {
-	CardID string `json:"cardID"`
+	CardID       string     `json:"cardID"`
+	StatementDay int        `binding:"omitempty,min=1,max=31" json:"statementDay"`
+	AnnualFeeDue *time.Time `json:"annualFeeDue"`
 }
 
-// UpdateMeRequest is the request body for PATCH /user/profile
+// UpdateMeRequest is the request body for PATCH /user/profile.
+// The profile picture is set through PUT /user/profile/picture instead.
 type UpdateMeRequest struct {
-	FirstName      *string `binding:"required,name"           json:"firstName"`
-	LastName       *string `binding:"required,name"           json:"lastName"`
-	ProfilePicture *string `binding:"required,profilePicture" json:"profilePicture"`
+	FirstName *string `binding:"omitempty,name"     json:"firstName"`
+	LastName  *string `binding:"omitempty,name"     json:"lastName"`
+	Username  *string `binding:"omitempty,username" json:"username"`
+}
+
+// UsernameAvailableResponse is the response body for GET /user/username/available.
+// Reason says why an unavailable username cannot be used: "invalid", "reserved" or "taken".
+type UsernameAvailableResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
 }
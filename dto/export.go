@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// DataExportResponse is the response body for POST /user/export and GET /user/export/:id
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"downloadURL,omitempty"` // single use, only set once the export is ready
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+}
+
+// NewDataExportResponse converts a models.DataExport for the client
+func NewDataExportResponse(export models.DataExport, downloadURL string) DataExportResponse {
+	return DataExportResponse{
+		ID:          export.ID,
+		Status:      string(export.Status),
+		Error:       export.Error,
+		DownloadURL: downloadURL,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+}
@@ -0,0 +1,195 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// CreateHouseholdRequest is the request body for POST /households
+type CreateHouseholdRequest struct {
+	Name string `binding:"required,max=100" json:"name"`
+}
+
+// InviteHouseholdMemberRequest is the request body for POST /households/:id/invitations
+type InviteHouseholdMemberRequest struct {
+	Email string               `binding:"required,email"               json:"email"`
+	Role  models.HouseholdRole `binding:"required,oneof=member viewer" json:"role"`
+}
+
+// AcceptHouseholdInvitationRequest is the request body for POST /households/invitations/accept
+type AcceptHouseholdInvitationRequest struct {
+	Token string `binding:"required" json:"token"`
+}
+
+// UpdateHouseholdMemberRequest is the request body for PATCH /households/:id/members/:userID
+type UpdateHouseholdMemberRequest struct {
+	Role models.HouseholdRole `binding:"required,oneof=owner member viewer" json:"role"`
+}
+
+// CreateHouseholdBudgetRequest is the request body for POST /households/:id/budgets
+type CreateHouseholdBudgetRequest struct {
+	Category  string    `binding:"required"                 json:"category"`
+	Amount    float64   `binding:"required,gt=0"            json:"amount"`
+	StartDate time.Time `binding:"required"                 json:"startDate"`
+	EndDate   time.Time `binding:"required,gtfield=StartDate" json:"endDate"`
+}
+
+// CreateHouseholdTransactionRequest is the request body for POST /households/:id/transactions.
+// SpentBy defaults to the member recording the transaction.
+type CreateHouseholdTransactionRequest struct {
+	Type        string    `binding:"required,oneof=expense income" json:"type"`
+	Category    string    `binding:"required"                      json:"category"`
+	Amount      float64   `binding:"required,gt=0"                 json:"amount"`
+	Date        time.Time `binding:"required"                      json:"date"`
+	Description string    `binding:"max=500"                       json:"description"`
+	SpentBy     string    `json:"spentBy"`
+}
+
+// HouseholdSummary is a household as listed by GET /households
+type HouseholdSummary struct {
+	ID      string               `json:"id"`
+	Name    string               `json:"name"`
+	Role    models.HouseholdRole `json:"role"` // the requesting user's role
+	Members int                  `json:"members"`
+}
+
+// HouseholdResponse is the response body for GET /households/:id
+type HouseholdResponse struct {
+	ID           string                         `json:"id"`
+	Name         string                         `json:"name"`
+	Role         models.HouseholdRole           `json:"role"` // the requesting user's role
+	Members      []HouseholdMemberResponse      `json:"members"`
+	Invitations  []HouseholdInvitationResponse  `json:"invitations,omitempty"` // owners only
+	Budgets      []HouseholdBudgetResponse      `json:"budgets"`
+	Transactions []HouseholdTransactionResponse `json:"transactions"`
+	CreatedAt    time.Time                      `json:"createdAt"`
+}
+
+// HouseholdMemberResponse is a member of a household with their public profile
+type HouseholdMemberResponse struct {
+	UserID    string               `json:"userID"`
+	FirstName string               `json:"firstName"`
+	LastName  string               `json:"lastName"`
+	Email     string               `json:"email"`
+	Role      models.HouseholdRole `json:"role"`
+	JoinedAt  time.Time            `json:"joinedAt"`
+}
+
+// HouseholdInvitationResponse is a pending invitation to a household
+type HouseholdInvitationResponse struct {
+	ID        string               `json:"id"`
+	Email     string               `json:"email"`
+	Role      models.HouseholdRole `json:"role"`
+	InvitedBy string               `json:"invitedBy"`
+	ExpiresAt time.Time            `json:"expiresAt"`
+}
+
+// HouseholdBudgetResponse is the client view of a models.HouseholdBudget
+type HouseholdBudgetResponse struct {
+	ID        string    `json:"id"`
+	Category  string    `json:"category"`
+	Amount    float64   `json:"amount"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// HouseholdTransactionResponse is the client view of a models.HouseholdTransaction
+type HouseholdTransactionResponse struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Category    string    `json:"category"`
+	Amount      float64   `json:"amount"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	SpentBy     string    `json:"spentBy"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NewHouseholdResponse converts a models.Household for a member with role. users maps member IDs to
+// their profiles, members missing from it are listed by ID only.
+func NewHouseholdResponse(household models.Household, role models.HouseholdRole,
+	users map[string]models.User) HouseholdResponse {
+	members := make([]HouseholdMemberResponse, 0, len(household.Members))
+	for _, member := range household.Members {
+		user := users[member.UserID]
+		members = append(members, HouseholdMemberResponse{
+			UserID:    member.UserID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Role:      member.Role,
+			JoinedAt:  member.JoinedAt,
+		})
+	}
+
+	var invitations []HouseholdInvitationResponse
+	if role == models.HouseholdRoleOwner {
+		invitations = make([]HouseholdInvitationResponse, 0, len(household.Invitations))
+		for _, invitation := range household.Invitations {
+			invitations = append(invitations, NewHouseholdInvitationResponse(invitation))
+		}
+	}
+
+	budgets := make([]HouseholdBudgetResponse, 0, len(household.Budgets))
+	for _, budget := range household.Budgets {
+		budgets = append(budgets, NewHouseholdBudgetResponse(budget))
+	}
+	transactions := make([]HouseholdTransactionResponse, 0, len(household.Transactions))
+	for _, transaction := range household.Transactions {
+		transactions = append(transactions, NewHouseholdTransactionResponse(transaction))
+	}
+
+	return HouseholdResponse{
+		ID:           household.ID,
+		Name:         household.Name,
+		Role:         role,
+		Members:      members,
+		Invitations:  invitations,
+		Budgets:      budgets,
+		Transactions: transactions,
+		CreatedAt:    household.CreatedAt,
+	}
+}
+
+// NewHouseholdInvitationResponse converts a models.HouseholdInvitation for the client
+func NewHouseholdInvitationResponse(invitation models.HouseholdInvitation) HouseholdInvitationResponse {
+	return HouseholdInvitationResponse{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		InvitedBy: invitation.InvitedBy,
+		ExpiresAt: invitation.ExpiresAt,
+	}
+}
+
+// NewHouseholdBudgetResponse converts a models.HouseholdBudget for the client
+func NewHouseholdBudgetResponse(budget models.HouseholdBudget) HouseholdBudgetResponse {
+	return HouseholdBudgetResponse{
+		ID:        budget.ID.Hex(),
+		Category:  budget.Category,
+		Amount:    budget.Amount,
+		StartDate: budget.StartDate,
+		EndDate:   budget.EndDate,
+		CreatedBy: budget.CreatedBy,
+		CreatedAt: budget.CreatedAt,
+	}
+}
+
+// NewHouseholdTransactionResponse converts a models.HouseholdTransaction for the client
+func NewHouseholdTransactionResponse(transaction models.HouseholdTransaction) HouseholdTransactionResponse {
+	return HouseholdTransactionResponse{
+		ID:          transaction.ID.Hex(),
+		Type:        transaction.Type,
+		Category:    transaction.Category,
+		Amount:      transaction.Amount,
+		Date:        transaction.Date,
+		Description: transaction.Description,
+		SpentBy:     transaction.SpentBy,
+		CreatedBy:   transaction.CreatedBy,
+		CreatedAt:   transaction.CreatedAt,
+	}
+}
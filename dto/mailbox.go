@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// MailboxQuery is the query string for GET /dev/mailbox
+type MailboxQuery struct {
+	To string `binding:"omitempty,email" form:"to"`
+}
+
+// MailboxMessageSummary is a captured email as listed by GET /dev/mailbox
+type MailboxMessageSummary struct {
+	ID        string    `json:"id"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MailboxMessageResponse is the response body for GET /dev/mailbox/:id
+type MailboxMessageResponse struct {
+	MailboxMessageSummary
+	From string `json:"from"`
+	Text string `json:"text"`
+	HTML string `json:"html"`
+}
+
+// NewMailboxMessageSummary converts models.MailboxMessage to MailboxMessageSummary
+func NewMailboxMessageSummary(msg models.MailboxMessage) MailboxMessageSummary {
+	return MailboxMessageSummary{
+		ID:        msg.ID,
+		To:        msg.To,
+		Subject:   msg.Subject,
+		CreatedAt: msg.CreatedAt,
+	}
+}
+
+// NewMailboxMessageResponse converts models.MailboxMessage to MailboxMessageResponse
+func NewMailboxMessageResponse(msg models.MailboxMessage) MailboxMessageResponse {
+	return MailboxMessageResponse{
+		MailboxMessageSummary: NewMailboxMessageSummary(msg),
+		From:                  msg.From,
+		Text:                  msg.Text,
+		HTML:                  msg.HTML,
+	}
+}
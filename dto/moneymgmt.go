@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// BudgetResponse is the client view of a models.Budget
+type BudgetResponse struct {
+	ID        string    `json:"id"`
+	Category  string    `json:"category"`
+	Amount    float64   `json:"amount"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TransactionResponse is the client view of a models.Transaction
+type TransactionResponse struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Category    string    `json:"category"`
+	Amount      float64   `json:"amount"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SavingsResponse is the client view of a models.Savings
+type SavingsResponse struct {
+	ID            string    `json:"id"`
+	Description   string    `json:"description"`
+	TargetAmount  float64   `json:"targetAmount"`
+	CurrentAmount float64   `json:"currentAmount"`
+	Deadline      time.Time `json:"deadline"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// NewBudgetResponse converts a models.Budget for the client
+func NewBudgetResponse(budget models.Budget) BudgetResponse {
+	return BudgetResponse{
+		ID:        budget.ID.Hex(),
+		Category:  budget.Category,
+		Amount:    budget.Amount,
+		StartDate: budget.StartDate,
+		EndDate:   budget.EndDate,
+		CreatedAt: budget.CreatedAt,
+		UpdatedAt: budget.UpdatedAt,
+	}
+}
+
+// NewTransactionResponse converts a models.Transaction for the client
+func NewTransactionResponse(transaction models.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:          transaction.ID.Hex(),
+		Type:        transaction.Type,
+		Category:    transaction.Category,
+		Amount:      transaction.Amount,
+		Date:        transaction.Date,
+		Description: transaction.Description,
+		CreatedAt:   transaction.CreatedAt,
+		UpdatedAt:   transaction.UpdatedAt,
+	}
+}
+
+// NewSavingsResponse converts a models.Savings for the client
+func NewSavingsResponse(savings models.Savings) SavingsResponse {
+	return SavingsResponse{
+		ID:            savings.ID.Hex(),
+		Description:   savings.Description,
+		TargetAmount:  savings.TargetAmount,
+		CurrentAmount: savings.CurrentAmount,
+		Deadline:      savings.Deadline,
+		CreatedAt:     savings.CreatedAt,
+		UpdatedAt:     savings.UpdatedAt,
+	}
+}
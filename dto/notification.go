@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// ListNotificationsQuery is the query string for GET /notifications
+type ListNotificationsQuery struct {
+	Unread bool `form:"unread"` // only list notifications not yet read
+}
+
+// NotificationResponse is a notification in the user's notification centre
+type NotificationResponse struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Category  string            `json:"category"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data,omitempty"`
+	Read      bool              `json:"read"`
+	ReadAt    *time.Time        `json:"readAt,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// NewNotificationResponse converts models.Notification to NotificationResponse
+func NewNotificationResponse(n models.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        n.ID,
+		Type:      string(n.Type),
+		Category:  string(n.Type.Category()),
+		Title:     n.Title,
+		Body:      n.Body,
+		Data:      n.Data,
+		Read:      n.ReadAt != nil,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// AdminListOutboxQuery is the query string for GET /admin/outbox, all filters are optional
+type AdminListOutboxQuery struct {
+	Status string `binding:"omitempty,oneof=pending processing done dead"       form:"status"`
+	Kind   string `binding:"omitempty,oneof=email generate_export webhook push" form:"kind"`
+}
+
+// OutboxEntryResponse is an outbox entry as listed for admins. Email bodies are never included.
+type OutboxEntryResponse struct {
+	ID             string     `json:"id"`
+	Kind           string     `json:"kind"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"lastError,omitempty"`
+	AvailableAt    time.Time  `json:"availableAt"`
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	EmailTo        string     `json:"emailTo,omitempty"`
+	EmailTemplate  string     `json:"emailTemplate,omitempty"`
+	ExportID       string     `json:"exportId,omitempty"`
+
+	WebhookDeliveryID  string `json:"webhookDeliveryId,omitempty"`
+	PushSubscriptionID string `json:"pushSubscriptionId,omitempty"`
+	NotificationID     string `json:"notificationId,omitempty"`
+}
+
+// NewOutboxEntryResponse converts models.OutboxEntry to OutboxEntryResponse
+func NewOutboxEntryResponse(entry models.OutboxEntry) OutboxEntryResponse {
+	response := OutboxEntryResponse{
+		ID:             entry.ID,
+		Kind:           string(entry.Kind),
+		Status:         string(entry.Status),
+		Attempts:       entry.Attempts,
+		LastError:      entry.LastError,
+		AvailableAt:    entry.AvailableAt,
+		LeaseExpiresAt: entry.LeaseExpiresAt,
+		CreatedAt:      entry.CreatedAt,
+		CompletedAt:    entry.CompletedAt,
+		ExportID:       entry.ExportID,
+
+		WebhookDeliveryID: entry.WebhookDeliveryID,
+	}
+	if entry.Email != nil {
+		response.EmailTo = entry.Email.To
+		response.EmailTemplate = entry.Email.Template
+	}
+	if entry.Push != nil {
+		response.PushSubscriptionID = entry.Push.SubscriptionID
+		response.NotificationID = entry.Push.NotificationID
+	}
+	return response
+}
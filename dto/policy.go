@@ -0,0 +1,116 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// ConsentRequiredCode is the error code of responses to users who have not accepted the current policies
+const ConsentRequiredCode = "consent_required"
+
+// ConsentRequiredResponse is returned with 428 Precondition Required by authenticated endpoints until the
+// user accepts the pending policy versions through POST /user/consents
+type ConsentRequiredResponse struct {
+	Error   string                  `json:"error"`
+	Code    string                  `json:"code"`
+	Pending []PolicyVersionResponse `json:"pending"`
+}
+
+// PolicyVersionResponse is the client view of a models.PolicyVersion
+type PolicyVersionResponse struct {
+	Document    models.PolicyDocument `json:"document"`
+	Version     string                `json:"version"`
+	URL         string                `json:"url"`
+	Summary     string                `json:"summary,omitempty"`
+	PublishedAt time.Time             `json:"publishedAt"`
+}
+
+// ConsentResponse is the client view of a models.Consent
+type ConsentResponse struct {
+	Document   models.PolicyDocument `json:"document"`
+	Version    string                `json:"version"`
+	AcceptedAt time.Time             `json:"acceptedAt"`
+}
+
+// ConsentsResponse is the response body for GET and POST /user/consents
+type ConsentsResponse struct {
+	Consents []ConsentResponse       `json:"consents"`
+	Pending  []PolicyVersionResponse `json:"pending"`
+}
+
+// AcceptPoliciesRequest is the request body for POST /user/consents
+type AcceptPoliciesRequest struct {
+	Accept []PolicyAcceptance `binding:"required,min=1,dive" json:"accept"`
+}
+
+// PolicyAcceptance names a policy version being accepted, it must be the current one
+type PolicyAcceptance struct {
+	Document models.PolicyDocument `binding:"required,oneof=terms privacy" json:"document"`
+	Version  string                `binding:"required"                     json:"version"`
+}
+
+// PublishPolicyRequest is the request body for POST /admin/policies
+type PublishPolicyRequest struct {
+	Document models.PolicyDocument `binding:"required,oneof=terms privacy" json:"document"`
+	Version  string                `binding:"required,max=50"              json:"version"`
+	URL      string                `binding:"required,url"                 json:"url"`
+	Summary  string                `binding:"max=1000"                     json:"summary"`
+}
+
+// PolicyAcceptanceReport is the response body for GET /admin/policies/:document/acceptance
+type PolicyAcceptanceReport struct {
+	Document       models.PolicyDocument     `json:"document"`
+	CurrentVersion string                    `json:"currentVersion"`
+	ActiveUsers    int64                     `json:"activeUsers"` // verified users not pending deletion or disabled
+	Versions       []PolicyVersionAcceptance `json:"versions"`    // newest first
+}
+
+// PolicyVersionAcceptance is how many active users accepted one version of a policy document
+type PolicyVersionAcceptance struct {
+	Version     string    `json:"version"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Accepted    int64     `json:"accepted"`
+	Rate        float64   `json:"rate"` // Accepted / ActiveUsers, 0 to 1
+}
+
+// NewPolicyVersionResponse converts a models.PolicyVersion for the client
+func NewPolicyVersionResponse(version models.PolicyVersion) PolicyVersionResponse {
+	return PolicyVersionResponse{
+		Document:    version.Document,
+		Version:     version.Version,
+		URL:         version.URL,
+		Summary:     version.Summary,
+		PublishedAt: version.PublishedAt,
+	}
+}
+
+// NewConsentRequiredResponse lists the pending policy versions a user must accept
+func NewConsentRequiredResponse(pending []models.PolicyVersion) ConsentRequiredResponse {
+	return ConsentRequiredResponse{
+		Error:   "Please review and accept the updated terms to continue",
+		Code:    ConsentRequiredCode,
+		Pending: newPolicyVersionResponses(pending),
+	}
+}
+
+// NewConsentsResponse converts a user's consents and pending policy versions for the client
+func NewConsentsResponse(consents []models.Consent, pending []models.PolicyVersion) ConsentsResponse {
+	results := make([]ConsentResponse, 0, len(consents))
+	for _, consent := range consents {
+		results = append(results, ConsentResponse{
+			Document:   consent.Document,
+			Version:    consent.Version,
+			AcceptedAt: consent.AcceptedAt,
+		})
+	}
+	return ConsentsResponse{Consents: results, Pending: newPolicyVersionResponses(pending)}
+}
+
+func newPolicyVersionResponses(versions []models.PolicyVersion) []PolicyVersionResponse {
+	results := make([]PolicyVersionResponse, 0, len(versions))
+	for _, version := range versions {
+		results = append(results, NewPolicyVersionResponse(version))
+	}
+	return results
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// PreferencesResponse is the response body for GET and PATCH /user/preferences
+type PreferencesResponse struct {
+	HomeCurrency    string                          `json:"homeCurrency"`
+	Timezone        string                          `json:"timezone"`
+	Locale          string                          `json:"locale"`
+	FirstDayOfWeek  time.Weekday                    `json:"firstDayOfWeek"` // 0 is Sunday
+	DefaultCardID   string                          `json:"defaultCardID,omitempty"`
+	DefaultCategory string                          `json:"defaultCategory,omitempty"`
+	Notifications   NotificationPreferencesResponse `json:"notifications"`
+	Digests         DigestPreferences               `json:"digests"`
+}
+
+// DigestPreferences are the spending digest opt-ins of PreferencesResponse
+type DigestPreferences struct {
+	Weekly  bool `json:"weekly"`
+	Monthly bool `json:"monthly"`
+}
+
+// NotificationPreferencesResponse is the notification opt-ins part of PreferencesResponse
+type NotificationPreferencesResponse struct {
+	SecurityAlerts bool `json:"securityAlerts"`
+	BudgetAlerts   bool `json:"budgetAlerts"`
+	FeeReminders   bool `json:"feeReminders"`
+	ProductUpdates bool `json:"productUpdates"`
+
+	// Channels are the channels each category is delivered through when opted in, keyed by category
+	Channels map[string]NotificationChannelsResponse `json:"channels"`
+}
+
+// NotificationChannelsResponse are the channels of one notification category
+type NotificationChannelsResponse struct {
+	InApp bool `json:"inApp"`
+	Email bool `json:"email"`
+	Push  bool `json:"push"`
+}
+
+// UpdatePreferencesRequest is the request body for PATCH /user/preferences, omitted fields are left unchanged.
+// DefaultCardID and DefaultCategory are cleared by sending an empty string.
+type UpdatePreferencesRequest struct {
+	HomeCurrency    *string                        `binding:"omitempty,currency"  json:"homeCurrency"`
+	Timezone        *string                        `binding:"omitempty,timezone"  json:"timezone"`
+	Locale          *string                        `binding:"omitempty,locale"    json:"locale"`
+	FirstDayOfWeek  *int                           `binding:"omitempty,min=0,max=6" json:"firstDayOfWeek"`
+	DefaultCardID   *string                        `json:"defaultCardID"`
+	DefaultCategory *string                        `json:"defaultCategory"`
+	Notifications   *UpdateNotificationPreferences `json:"notifications"`
+	Digests         *UpdateDigestPreferences       `json:"digests"`
+}
+
+// UpdateDigestPreferences is the spending digest opt-ins part of UpdatePreferencesRequest
+type UpdateDigestPreferences struct {
+	Weekly  *bool `json:"weekly"`
+	Monthly *bool `json:"monthly"`
+}
+
+// UpdateNotificationPreferences is the notification opt-ins part of UpdatePreferencesRequest
+type UpdateNotificationPreferences struct {
+	SecurityAlerts *bool                                 `json:"securityAlerts"`
+	BudgetAlerts   *bool                                 `json:"budgetAlerts"`
+	FeeReminders   *bool                                 `json:"feeReminders"`
+	ProductUpdates *bool                                 `json:"productUpdates"`
+	Channels       map[string]UpdateNotificationChannels `json:"channels"` // keyed by category
+}
+
+// UpdateNotificationChannels changes the channels of one notification category, omitted channels are unchanged
+type UpdateNotificationChannels struct {
+	InApp *bool `json:"inApp"`
+	Email *bool `json:"email"`
+	Push  *bool `json:"push"`
+}
+
+// NewPreferencesResponse converts models.Preferences for the client
+func NewPreferencesResponse(p models.Preferences) PreferencesResponse {
+	channels := make(map[string]NotificationChannelsResponse, len(models.NotificationCategories))
+	for _, category := range models.NotificationCategories {
+		c, ok := p.Notifications.Channels[category]
+		if !ok {
+			c = models.DefaultNotificationChannels(category)
+		}
+		channels[string(category)] = NotificationChannelsResponse{InApp: c.InApp, Email: c.Email, Push: c.Push}
+	}
+
+	return PreferencesResponse{
+		HomeCurrency:    p.HomeCurrency,
+		Timezone:        p.Timezone,
+		Locale:          p.Locale,
+		FirstDayOfWeek:  p.FirstDayOfWeek,
+		DefaultCardID:   p.DefaultCardID,
+		DefaultCategory: p.DefaultCategory,
+		Notifications: NotificationPreferencesResponse{
+			SecurityAlerts: p.Notifications.SecurityAlerts,
+			BudgetAlerts:   p.Notifications.BudgetAlerts,
+			FeeReminders:   p.Notifications.FeeReminders,
+			ProductUpdates: p.Notifications.ProductUpdates,
+			Channels:       channels,
+		},
+		Digests: DigestPreferences{Weekly: p.Digests.Weekly, Monthly: p.Digests.Monthly},
+	}
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// SubscribePushRequest is the request body for POST /push/subscriptions, the JSON of the browser's
+// PushSubscription as returned by its toJSON method
+type SubscribePushRequest struct {
+	Endpoint       string               `binding:"required,url,max=2048" json:"endpoint"`
+	ExpirationTime *int64               `json:"expirationTime"` // milliseconds since the epoch
+	Keys           PushSubscriptionKeys `binding:"required"              json:"keys"`
+}
+
+// PushSubscriptionKeys are the keys part of SubscribePushRequest, base64url encoded
+type PushSubscriptionKeys struct {
+	P256dh string `binding:"required,max=128" json:"p256dh"`
+	Auth   string `binding:"required,max=64"  json:"auth"`
+}
+
+// PushSubscriptionResponse is a device subscribed to push notifications. The endpoint is a capability URL,
+// so only its push service is shown.
+type PushSubscriptionResponse struct {
+	ID          string     `json:"id"`
+	PushService string     `json:"pushService"`
+	UserAgent   string     `json:"userAgent,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastPushAt  *time.Time `json:"lastPushAt,omitempty"`
+}
+
+// NewPushSubscriptionResponse converts models.PushSubscription for its user
+func NewPushSubscriptionResponse(s models.PushSubscription) PushSubscriptionResponse {
+	var service string
+	if u, err := url.Parse(s.Endpoint); err == nil {
+		service = u.Host
+	}
+	return PushSubscriptionResponse{
+		ID:          s.ID,
+		PushService: service,
+		UserAgent:   s.UserAgent,
+		ExpiresAt:   s.ExpiresAt,
+		CreatedAt:   s.CreatedAt,
+		LastPushAt:  s.LastPushAt,
+	}
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// SecurityEventResponse is an entry of GET /user/security/events
+type SecurityEventResponse struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"`
+	Description string            `json:"description"`
+	IP          string            `json:"ip"`
+	UserAgent   string            `json:"userAgent"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+// NewSecurityEventResponse converts a models.SecurityEvent for the client
+func NewSecurityEventResponse(event models.SecurityEvent) SecurityEventResponse {
+	return SecurityEventResponse{
+		ID:          event.ID,
+		Type:        string(event.Type),
+		Description: event.Type.String(),
+		IP:          event.IP,
+		UserAgent:   event.UserAgent,
+		Metadata:    event.Metadata,
+		CreatedAt:   event.CreatedAt,
+	}
+}
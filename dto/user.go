@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/storage"
+)
+
+// UserResponse is the client-safe view of a models.User returned by user endpoints.
+// Credentials, verification codes and sign-in challenges are deliberately not part of it.
+type UserResponse struct {
+	ID             string              `json:"id"`
+	FirstName      string              `json:"firstName"`
+	LastName       string              `json:"lastName"`
+	Email          string              `json:"email"`
+	Username       string              `json:"username,omitempty"`
+	Verified       bool                `json:"verified"`
+	ProfilePicture string              `json:"profilePicture"` // signed URL, expires
+	ProfileThumb   string              `json:"profilePictureThumbnail"`
+	Cards          []CardResponse      `json:"cards"`
+	Passkeys       []PasskeyResponse   `json:"passkeys"`
+	Preferences    PreferencesResponse `json:"preferences"`
+	CreatedAt      time.Time           `json:"createdAt"`
+	UpdatedAt      time.Time           `json:"updatedAt"`
+}
+
+// PasskeyResponse is the client-safe view of a models.Passkey
+type PasskeyResponse struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// ProfilePictureResponse is the response body for PUT /user/profile/picture
+type ProfilePictureResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailURL"`
+}
+
+// NewUserResponse converts a models.User for the client
+func NewUserResponse(user models.User) UserResponse {
+	passkeys := make([]PasskeyResponse, 0, len(user.Passkeys))
+	for _, passkey := range user.Passkeys {
+		passkeys = append(passkeys, PasskeyResponse{
+			ID:         base64.RawURLEncoding.EncodeToString(passkey.ID),
+			Name:       passkey.Name,
+			CreatedAt:  passkey.CreatedAt,
+			LastUsedAt: passkey.LastUsedAt,
+		})
+	}
+
+	return UserResponse{
+		ID:             user.ID,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Email:          user.Email,
+		Username:       user.Username,
+		Verified:       user.Verified,
+		ProfilePicture: storage.SignedURL(user.ProfilePicture),
+		ProfileThumb:   storage.SignedURL(user.ProfilePictureThumbnail),
+		Cards:          NewCardResponses(user.Cards),
+		Passkeys:       passkeys,
+		Preferences:    NewPreferencesResponse(user.EffectivePreferences()),
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+}
@@ -0,0 +1,109 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// secrets are stored on a user but must never appear in a JSON response
+var secrets = map[string]string{
+	"password":            "argon2id-hash-of-the-password",
+	"verification code":   "verification-code-123456",
+	"magic link ID":       "magic-link-jti",
+	"password reset ID":   "password-reset-jti",
+	"pending email code":  "pending-email-code-654321",
+	"account code":        "account-code-246810",
+	"passkey challenge":   "passkey-challenge-session",
+	"normalized username": "ada-lowercased-handle",
+}
+
+func populatedUser() models.User {
+	now := time.Now()
+	return models.User{
+		ID:                         "user-1",
+		FirstName:                  "Ada",
+		LastName:                   "Lovelace",
+		Password:                   secrets["password"],
+		Username:                   "Ada",
+		UsernameLower:              secrets["normalized username"],
+		CreatedAt:                  now,
+		UpdatedAt:                  now,
+		Role:                       models.RoleAdmin,
+		DisabledAt:                 &now,
+		DisabledReason:             "testing",
+		DeletedAt:                  &now,
+		PurgeAfter:                 &now,
+		Email:                      "ada@example.com",
+		Verified:                   true,
+		VerificationCode:           secrets["verification code"],
+		VerificationSentAt:         now,
+		VerificationReminderSentAt: &now,
+		MagicLinkID:                secrets["magic link ID"],
+		MagicLinkExpiresAt:         now,
+		PasswordResetID:            secrets["password reset ID"],
+		PasswordResetExpiresAt:     now,
+		PendingEmail:               "ada@example.org",
+		PendingEmailCode:           secrets["pending email code"],
+		PendingEmailExpiresAt:      now,
+		AccountCode:                secrets["account code"],
+		AccountCodeExpiresAt:       now,
+		Passkeys: []models.Passkey{{
+			ID: []byte("credential-id"), Name: "Laptop", PublicKey: []byte("public-key"), CreatedAt: now,
+		}},
+		PasskeyChallenge: &models.PasskeyChallenge{
+			Ceremony: "login", Session: []byte(secrets["passkey challenge"]), ExpiresAt: now,
+		},
+		KnownDevices: []models.KnownDevice{{
+			Fingerprint: "fingerprint", IPRange: "203.0.113.0/24", UserAgent: "Firefox",
+			FirstSeenAt: now, LastSeenAt: now,
+		}},
+		Consents: []models.Consent{},
+	}
+}
+
+func TestUserJSONHasNoSecrets(t *testing.T) {
+	user := populatedUser()
+	for name, value := range map[string]interface{}{
+		"models.User":       user,
+		"UserResponse":      NewUserResponse(user),
+		"AdminUserResponse": NewAdminUserResponse(user),
+	} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for secret, text := range secrets {
+			// []byte fields are base64 encoded, so look for the raw value and its encoding
+			encodedText, _ := json.Marshal([]byte(text))
+			if strings.Contains(string(encoded), text) ||
+				strings.Contains(string(encoded), strings.Trim(string(encodedText), `"`)) {
+				t.Errorf("%s JSON contains the %s: %s", name, secret, encoded)
+			}
+		}
+	}
+}
+
+func TestAdminUserResponseKnownDevicesAreCamelCase(t *testing.T) {
+	encoded, err := json.Marshal(NewAdminUserResponse(populatedUser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		KnownDevices []map[string]interface{} `json:"knownDevices"`
+	}
+	if err = json.Unmarshal(encoded, &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.KnownDevices) != 1 {
+		t.Fatalf("knownDevices = %v, want one device", response.KnownDevices)
+	}
+	for _, key := range []string{"fingerprint", "ipRange", "userAgent", "firstSeenAt", "lastSeenAt"} {
+		if _, ok := response.KnownDevices[0][key]; !ok {
+			t.Errorf("known device %v has no %q", response.KnownDevices[0], key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// CreateWebhookRequest is the request body for POST /webhooks and /admin/webhooks
+type CreateWebhookRequest struct {
+	URL         string   `binding:"required,url,max=2048"             json:"url"`
+	Description string   `binding:"max=200"                           json:"description"`
+	Events      []string `binding:"required,min=1,dive,webhook_event" json:"events"`
+}
+
+// UpdateWebhookRequest is the request body for PATCH /webhooks/:id, omitted fields are left unchanged.
+// Enabling a disabled webhook clears its run of failures.
+type UpdateWebhookRequest struct {
+	URL         *string  `binding:"omitempty,url,max=2048"             json:"url"`
+	Description *string  `binding:"omitempty,max=200"                  json:"description"`
+	Events      []string `binding:"omitempty,min=1,dive,webhook_event" json:"events"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// WebhookResponse is a webhook as shown to its owner. The secret is only included when it is created.
+type WebhookResponse struct {
+	ID                  string     `json:"id"`
+	URL                 string     `json:"url"`
+	Description         string     `json:"description,omitempty"`
+	Events              []string   `json:"events"`
+	Global              bool       `json:"global"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledReason      string     `json:"disabledReason,omitempty"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	Secret              string     `json:"secret,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+// WebhookDeliveryResponse is an entry of a webhook's delivery log
+type WebhookDeliveryResponse struct {
+	ID           string                   `json:"id"`
+	EventID      string                   `json:"eventId"`
+	Event        string                   `json:"event"`
+	Status       string                   `json:"status"`
+	Payload      string                   `json:"payload"`
+	Attempts     []WebhookAttemptResponse `json:"attempts"`
+	RedeliveryOf string                   `json:"redeliveryOf,omitempty"`
+	CreatedAt    time.Time                `json:"createdAt"`
+	CompletedAt  *time.Time               `json:"completedAt,omitempty"`
+}
+
+// WebhookAttemptResponse is one request of a WebhookDeliveryResponse
+type WebhookAttemptResponse struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// NewWebhookResponse converts models.Webhook to WebhookResponse, without its secret
+func NewWebhookResponse(w models.Webhook) WebhookResponse {
+	events := make([]string, 0, len(w.Events))
+	for _, event := range w.Events {
+		events = append(events, string(event))
+	}
+	return WebhookResponse{
+		ID:                  w.ID,
+		URL:                 w.URL,
+		Description:         w.Description,
+		Events:              events,
+		Global:              w.Global,
+		Enabled:             w.Enabled,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		DisabledReason:      w.DisabledReason,
+		DisabledAt:          w.DisabledAt,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+}
+
+// NewWebhookDeliveryResponse converts models.WebhookDelivery to WebhookDeliveryResponse
+func NewWebhookDeliveryResponse(d models.WebhookDelivery) WebhookDeliveryResponse {
+	attempts := make([]WebhookAttemptResponse, 0, len(d.Attempts))
+	for _, attempt := range d.Attempts {
+		attempts = append(attempts, WebhookAttemptResponse{
+			At:         attempt.At,
+			StatusCode: attempt.StatusCode,
+			Response:   attempt.Response,
+			Error:      attempt.Error,
+			DurationMs: attempt.Duration.Milliseconds(),
+		})
+	}
+	return WebhookDeliveryResponse{
+		ID:           d.ID,
+		EventID:      d.EventID,
+		Event:        string(d.Event),
+		Status:       string(d.Status),
+		Payload:      d.Payload,
+		Attempts:     attempts,
+		RedeliveryOf: d.RedeliveryOf,
+		CreatedAt:    d.CreatedAt,
+		CompletedAt:  d.CompletedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/models"
+)
+
+// MailboxMailer captures emails in a MongoDB collection instead of sending them, so development tools and
+// the integration tests can read them through the /dev/mailbox API. It must never be used outside development.
+type MailboxMailer struct {
+	messages *mongo.Collection
+	from     string
+}
+
+// NewMailboxMailer returns a MailboxMailer storing messages in the given collection
+func NewMailboxMailer(messages *mongo.Collection, from string) *MailboxMailer {
+	return &MailboxMailer{messages: messages, from: from}
+}
+
+// Send implements Mailer
+func (m *MailboxMailer) Send(ctx context.Context, msg Message) error {
+	env, err := newEnvelope(m.from, msg.To)
+	if err != nil {
+		return err
+	}
+	_, err = m.messages.InsertOne(ctx, models.MailboxMessage{
+		ID:        uuid.New().String(),
+		From:      env.from.String(),
+		To:        env.to.Address,
+		Subject:   msg.Subject,
+		Text:      msg.Text,
+		HTML:      msg.HTML,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
@@ -0,0 +1,55 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// ErrInvalidRecipient is returned for messages without a single valid To address
+var ErrInvalidRecipient = errors.New("invalid email recipient")
+
+// Message is a rendered email to a single recipient, with plain text and HTML alternatives
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	// Send delivers msg, returning once the receiving side has accepted it
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Mailer selected by config.MailDriver. mailbox is only used by the mailbox driver.
+func New(mailbox *mongo.Collection) (Mailer, error) {
+	switch config.MailDriver {
+	case config.MailDriverSMTP:
+		if config.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST is required for the smtp mail driver")
+		}
+		return &SMTPMailer{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			TLS:      config.SMTPTLS,
+			From:     config.MailFrom,
+			Timeout:  config.SMTPTimeout,
+		}, nil
+	case config.MailDriverFile:
+		return NewFileMailer(config.MailDir, config.MailFrom)
+	case config.MailDriverMailbox:
+		return NewMailboxMailer(mailbox, config.MailFrom), nil
+	case config.MailDriverLog:
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.MailDriver)
+	}
+}
@@ -0,0 +1,95 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// envelope holds the addresses of a message, parsed so headers cannot be injected through them
+type envelope struct {
+	from *mail.Address
+	to   *mail.Address
+}
+
+func newEnvelope(from, to string) (envelope, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return envelope{}, fmt.Errorf("invalid sender: %w", err)
+	}
+	toAddr, err := mail.ParseAddress(to)
+	if err != nil {
+		return envelope{}, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+	return envelope{from: fromAddr, to: toAddr}, nil
+}
+
+// encode renders msg as a multipart/alternative RFC 5322 message, text part first so clients prefer HTML
+func (e envelope) encode(msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := []string{
+		"From: " + e.from.String(),
+		"To: " + e.to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", singleLine(msg.Subject)),
+		"Date: " + now.Format(time.RFC1123Z),
+		"Message-ID: " + messageID(e.from.Address),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + body.Boundary(),
+	}
+	var out bytes.Buffer
+	out.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	if err := writePart(body, "text/plain; charset=utf-8", msg.Text); err != nil {
+		return nil, err
+	}
+	if msg.HTML != "" {
+		if err := writePart(body, "text/html; charset=utf-8", msg.HTML); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+func writePart(body *multipart.Writer, contentType, content string) error {
+	part, err := body.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err = qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// singleLine folds any line breaks out of a header value
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
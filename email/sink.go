@@ -0,0 +1,60 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogMailer writes emails to the log instead of sending them, for development
+type LogMailer struct{}
+
+// Send implements Mailer
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if _, err := newEnvelope("noreply@localhost", msg.To); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Email not sent, logged by the log mail driver",
+		"to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// FileMailer writes each email as an .eml file in a directory instead of sending it, for development.
+// The files open in any mail client, which makes it easy to check the HTML rendering.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer returns a FileMailer writing to dir, creating the directory if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send implements Mailer
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	env, err := newEnvelope(m.from, msg.To)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	raw, err := env.encode(msg, now)
+	if err != nil {
+		return err
+	}
+
+	name := now.UTC().Format("20060102T150405.000Z") + "-" + uuid.New().String() + ".eml"
+	path := filepath.Join(m.dir, name)
+	if err = os.WriteFile(path, raw, 0o600); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Email written by the file mail driver", "to", msg.To, "path", path)
+	return nil
+}
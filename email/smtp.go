@@ -0,0 +1,119 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// ErrStartTLSUnsupported is returned when STARTTLS is required but the server does not offer it.
+// Falling back to plaintext would send credentials and mail in the clear.
+var ErrStartTLSUnsupported = errors.New("smtp server does not support STARTTLS")
+
+// SMTPMailer delivers emails through an SMTP relay, opening a connection per message
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string // empty skips authentication
+	Password string
+	TLS      string // config.SMTPTLSStartTLS, config.SMTPTLSImplicit or config.SMTPTLSNone
+	From     string
+	Timeout  time.Duration
+
+	// TLSConfig overrides the TLS settings, e.g. to trust a private CA. ServerName defaults to Host.
+	TLSConfig *tls.Config
+}
+
+// Send implements Mailer
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	env, err := newEnvelope(m.From, msg.To)
+	if err != nil {
+		return err
+	}
+	raw, err := env.encode(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	// net/smtp has no context support, a deadline on the connection bounds the whole conversation
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.TLS == config.SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+		if err = client.StartTLS(m.tlsConfig()); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if m.Username != "" {
+		// PlainAuth itself refuses to send credentials over an unencrypted connection to a remote host
+		if err = client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err = client.Mail(env.from.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(env.to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(raw); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *SMTPMailer) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	if m.TLS == config.SMTPTLSImplicit {
+		dialer := &tls.Dialer{Config: m.tlsConfig()}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (m *SMTPMailer) tlsConfig() *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if m.TLSConfig != nil {
+		cfg = m.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = m.Host
+	}
+	return cfg
+}
@@ -0,0 +1,280 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// fakeSMTPServer is an SMTP relay on a loopback listener that accepts every message, recording what it
+// was sent
+type fakeSMTPServer struct {
+	listener  net.Listener
+	startTLS  bool        // offer STARTTLS
+	tlsConfig *tls.Config // the certificate STARTTLS upgrades with
+
+	received chan smtpSession
+}
+
+// smtpSession is what a client sent over one connection
+type smtpSession struct {
+	tls      bool   // the connection was upgraded before MAIL
+	auth     string // the AUTH command, empty if the client did not authenticate
+	from, to string
+	data     []byte
+}
+
+func newFakeSMTPServer(t *testing.T, startTLS bool) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{
+		listener:  listener,
+		startTLS:  startTLS,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}},
+		received:  make(chan smtpSession, 1),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+// mailer returns an SMTPMailer for the server that trusts its certificate
+func (s *fakeSMTPServer) mailer(tlsMode string) *SMTPMailer {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.tlsConfig.Certificates[0].Leaf)
+	return &SMTPMailer{
+		Host:      "127.0.0.1",
+		Port:      s.listener.Addr().(*net.TCPAddr).Port,
+		TLS:       tlsMode,
+		From:      "MoneyFly <no-reply@moneyfly.test>",
+		Timeout:   5 * time.Second,
+		TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	}
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var (
+		session smtpSession
+		text    = textproto.NewConn(conn)
+	)
+	reply := func(line string) { _ = text.PrintfLine("%s", line) }
+	reply("220 fake.smtp.test ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			extensions := []string{"250-fake.smtp.test", "250 AUTH PLAIN"}
+			if s.startTLS && !session.tls {
+				extensions = []string{"250-fake.smtp.test", "250-STARTTLS", "250 AUTH PLAIN"}
+			}
+			for _, extension := range extensions {
+				reply(extension)
+			}
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err = tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, text, session.tls = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			session.auth = line
+			reply("235 authenticated")
+		case "MAIL":
+			session.from = arg
+			reply("250 ok")
+		case "RCPT":
+			session.to = arg
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			if session.data, err = text.ReadDotBytes(); err != nil {
+				return
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.received <- session
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// session waits for the message the server received
+func (s *fakeSMTPServer) session(t *testing.T) smtpSession {
+	t.Helper()
+	select {
+	case session := <-s.received:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server received no message")
+		return smtpSession{}
+	}
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake.smtp.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+var testMessage = Message{
+	To:      "Ada Lovelace <ada@example.com>",
+	Subject: "Your code — 123456",
+	Text:    "Hi Ada, your code is 123456. Café prices are up.",
+	HTML:    `<p style="color: #333">Hi Ada, your code is <b>123456</b>.</p>`,
+}
+
+func TestSMTPMailerStartTLSRequired(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+	mailer := server.mailer(config.SMTPTLSStartTLS)
+	mailer.Username, mailer.Password = "relay-user", "relay-password"
+
+	if err := mailer.Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	session := server.session(t)
+	if !session.tls {
+		t.Fatal("the message was sent before upgrading to TLS")
+	}
+	if session.from != "FROM:<no-reply@moneyfly.test>" || session.to != "TO:<ada@example.com>" {
+		t.Errorf("envelope = %q, %q", session.from, session.to)
+	}
+
+	// AUTH PLAIN carries base64 of "\x00username\x00password"
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00relay-user\x00relay-password"))
+	if session.auth != "AUTH PLAIN "+credentials {
+		t.Errorf("auth = %q, want PLAIN with the configured credentials", session.auth)
+	}
+}
+
+func TestSMTPMailerStartTLSAbsent(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	mailer := server.mailer(config.SMTPTLSStartTLS)
+	mailer.Username, mailer.Password = "relay-user", "relay-password"
+
+	err := mailer.Send(context.Background(), testMessage)
+	if !errors.Is(err, ErrStartTLSUnsupported) {
+		t.Fatalf("Send = %v, want ErrStartTLSUnsupported rather than a plaintext fallback", err)
+	}
+	select {
+	case session := <-server.received:
+		t.Fatalf("the server received a message in plaintext: %+v", session)
+	default:
+	}
+}
+
+func TestSMTPMailerWithoutAuth(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	if err := server.mailer(config.SMTPTLSNone).Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if session := server.session(t); session.auth != "" {
+		t.Errorf("auth = %q, want none without a username", session.auth)
+	}
+}
+
+func TestSMTPMailerEncodesMultipart(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	if err := server.mailer(config.SMTPTLSNone).Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(server.session(t).data))))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != testMessage.Subject {
+		t.Errorf("subject = %q (%v), want %q", subject, err, testMessage.Subject)
+	}
+	if to := msg.Header.Get("To"); to != `"Ada Lovelace" <ada@example.com>` {
+		t.Errorf("To = %q", to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v), want multipart/alternative", mediaType, err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", testMessage.Text},
+		{"text/html; charset=utf-8", testMessage.HTML},
+	} {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("%s part: %v", want.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part content type = %q, want %q", got, want.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("%s transfer encoding = %q, want quoted-printable", want.contentType, got)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s body = %q, want %q", want.contentType, body, want.body)
+		}
+	}
+	if _, err = reader.NextPart(); err != io.EOF {
+		t.Errorf("want exactly two parts, next part: %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names. Each is rendered from templates/<name>/<version>/<locale>.txt, which also defines the
+// "subject" template, and templates/<name>/<version>/<locale>.html.
+const (
+	TemplateVerification         = "verification"
+	TemplateVerificationReminder = "verification_reminder"
+	TemplateMagicLink            = "magic_link"
+	TemplatePasswordReset        = "password_reset"
+	TemplateSecurityAlert        = "security_alert"
+	TemplateHouseholdInvitation  = "household_invitation"
+	TemplateBudgetAlert          = "budget_alert"
+	TemplateDigest               = "digest"
+	TemplateAnnouncement         = "announcement"
+	TemplateCapWarning           = "cap_warning"
+	TemplateFeeReminder          = "fee_reminder"
+	TemplateAccountCode          = "account_code"
+)
+
+// defaultLocale is used when no variant exists for the recipient's locale or its language
+const defaultLocale = "en"
+
+// currentVersions are the template versions emails are sent with. Changing the copy means adding a new
+// version directory and bumping it here, so what any earlier email said stays in the tree.
+var currentVersions = map[string]string{
+	TemplateVerification:         "v1",
+	TemplateVerificationReminder: "v1",
+	TemplateMagicLink:            "v1",
+	TemplatePasswordReset:        "v1",
+	TemplateSecurityAlert:        "v1",
+	TemplateHouseholdInvitation:  "v1",
+	TemplateBudgetAlert:          "v2",
+	TemplateDigest:               "v1",
+	TemplateAnnouncement:         "v1",
+	TemplateCapWarning:           "v1",
+	TemplateFeeReminder:          "v1",
+	TemplateAccountCode:          "v1",
+}
+
+// VerificationData renders TemplateVerification
+type VerificationData struct {
+	Name string
+	Code string
+}
+
+// VerificationReminderData renders TemplateVerificationReminder
+type VerificationReminderData struct {
+	Name     string
+	Code     string
+	DeleteOn string // formatted in the recipient's timezone
+}
+
+// AccountCodeData renders TemplateAccountCode
+type AccountCodeData struct {
+	Name     string
+	Code     string
+	Restore  bool // restoring a deleted account rather than deleting it
+	ValidFor time.Duration
+}
+
+// MagicLinkData renders TemplateMagicLink
+type MagicLinkData struct {
+	Link     string
+	ValidFor time.Duration
+}
+
+// PasswordResetData renders TemplatePasswordReset
+type PasswordResetData struct {
+	Link     string
+	ValidFor time.Duration
+}
+
+// SecurityAlertData renders TemplateSecurityAlert
+type SecurityAlertData struct {
+	EventType   string // models.SecurityEventType, for locales that describe events themselves
+	Description string // English description of the event
+	At          string // formatted in the recipient's timezone
+	IP          string
+	UserAgent   string
+}
+
+// HouseholdInvitationData renders TemplateHouseholdInvitation
+type HouseholdInvitationData struct {
+	Household string
+	Link      string
+	ValidFor  time.Duration
+}
+
+// BudgetAlertData renders TemplateBudgetAlert
+type BudgetAlertData struct {
+	Household string // empty for the recipient's own budget
+	Category  string
+	Spent     string // formatted amounts
+	Amount    string
+	Percent   int
+	Exceeded  bool // over budget rather than approaching it
+}
+
+// DigestData renders TemplateDigest. Amounts are formatted with their currency, dates in the recipient's
+// timezone. Empty sections are left out.
+type DigestData struct {
+	Name       string
+	Monthly    bool // a monthly rather than weekly digest
+	From       string
+	To         string
+	Total      string
+	Categories []DigestLine
+	Cards      []DigestLine
+	Budgets    []DigestBudget
+	Miles      string // earned over the period, empty if none
+	Upcoming   []DigestDate
+}
+
+// DigestLine is the spending of one category or card in a DigestData
+type DigestLine struct {
+	Name   string
+	Amount string
+	Share  int // percentage of the total
+}
+
+// DigestBudget is the status of one budget in a DigestData
+type DigestBudget struct {
+	Category string
+	Spent    string
+	Amount   string
+	Percent  int
+	Over     bool
+}
+
+// DigestDate is an upcoming card date in a DigestData
+type DigestDate struct {
+	Card      string
+	Statement bool // a statement date rather than an annual fee
+	On        string
+}
+
+// CapWarningData renders TemplateCapWarning
+type CapWarningData struct {
+	Card    string
+	Spent   string // formatted amounts, of bonus category spending in the reward cycle
+	Cap     string
+	Percent int
+	Reached bool   // the cap is used up rather than nearly
+	ResetOn string // when the next reward cycle starts, formatted in the recipient's timezone
+}
+
+// FeeReminderData renders TemplateFeeReminder
+type FeeReminderData struct {
+	Card      string
+	Statement bool   // a statement date rather than an annual fee
+	On        string // formatted in the recipient's timezone
+	Days      int    // from today until On
+	Fee       string // formatted annual fee, empty for a statement date or a card without one
+}
+
+// AnnouncementData renders TemplateAnnouncement, an admin's announcement split into paragraphs at blank lines
+type AnnouncementData struct {
+	Name       string
+	Title      string
+	Paragraphs []string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var funcs = map[string]interface{}{
+	"minutes": func(d time.Duration) int { return int(math.Max(1, math.Ceil(d.Minutes()))) },
+	"days":    func(d time.Duration) int { return int(math.Max(1, math.Ceil(d.Hours()/24))) },
+}
+
+// localized is one locale variant of a template version
+type localized struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates are keyed by "<name>/<version>/<locale>". The templates are embedded, so failing to parse
+// them is a programming error and panics at startup rather than on the first email.
+var templates = mustParseTemplates()
+
+func mustParseTemplates() map[string]localized {
+	parsed := make(map[string]localized)
+	textFiles, err := fs.Glob(templateFS, "templates/*/*/*.txt")
+	if err != nil {
+		panic(err)
+	}
+	for _, textFile := range textFiles {
+		key := strings.TrimSuffix(strings.TrimPrefix(textFile, "templates/"), ".txt")
+		text := texttemplate.Must(texttemplate.New(path.Base(textFile)).Funcs(funcs).ParseFS(templateFS, textFile))
+		if text.Lookup("subject") == nil {
+			panic(fmt.Sprintf("email template %s does not define a subject", textFile))
+		}
+		htmlFile := strings.TrimSuffix(textFile, ".txt") + ".html"
+		html := htmltemplate.Must(htmltemplate.New(path.Base(htmlFile)).Funcs(funcs).ParseFS(templateFS, htmlFile))
+		parsed[key] = localized{text: text, html: html}
+	}
+	for name, version := range currentVersions {
+		if _, ok := parsed[name+"/"+version+"/"+defaultLocale]; !ok {
+			panic(fmt.Sprintf("email template %s %s has no %q variant", name, version, defaultLocale))
+		}
+	}
+	return parsed
+}
+
+// Render renders the current version of the named template for locale, a BCP 47 tag such as "en-SG".
+// It falls back to the locale's language, then to the default locale. The returned Message has no recipient.
+func Render(name, locale string, data interface{}) (Message, error) {
+	version, ok := currentVersions[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var tmpl localized
+	for _, candidate := range localeCandidates(locale) {
+		if tmpl, ok = templates[name+"/"+version+"/"+candidate]; ok {
+			break
+		}
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: singleLine(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// localeCandidates returns the variants to try for locale, most specific first, e.g. "en-sg", "en", "en"
+func localeCandidates(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := make([]string, 0, 3)
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if language, _, found := strings.Cut(locale, "-"); found {
+			candidates = append(candidates, language)
+		}
+	}
+	return append(candidates, defaultLocale)
+}
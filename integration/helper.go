@@ -5,10 +5,55 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"regexp"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
+const (
+	// mailboxAttempts and mailboxPollInterval allow for the outbox worker delivering emails asynchronously
+	mailboxAttempts     = 20
+	mailboxPollInterval = 500 * time.Millisecond
+)
+
+var verificationCodePattern = regexp.MustCompile(`\b\d{8}\b`)
+
+type mailboxList struct {
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+}
+
+type mailboxMessage struct {
+	Text string `json:"text"`
+}
+
+// waitForVerificationCode polls the development mailbox for the latest email sent to address and returns the
+// verification code in it, or an empty string if none arrives in time
+func waitForVerificationCode(address string) string {
+	for attempt := 0; attempt < mailboxAttempts; attempt++ {
+		var list mailboxList
+		resp, err := client.R().EnableTrace().
+			SetQueryParams(map[string]string{"to": address, "limit": "1"}).
+			SetResult(&list).
+			Get(baseURL + "/dev/mailbox")
+		if err == nil && resp.IsSuccess() && len(list.Messages) > 0 {
+			printTest(resp, err)
+
+			var msg mailboxMessage
+			resp, err = client.R().EnableTrace().
+				SetResult(&msg).
+				Get(baseURL + "/dev/mailbox/" + list.Messages[0].ID)
+			printTest(resp, err)
+			return verificationCodePattern.FindString(msg.Text)
+		}
+		time.Sleep(mailboxPollInterval)
+	}
+	slog.Error("No verification email arrived in the development mailbox", "to", address)
+	return ""
+}
+
 func getField(resp *resty.Response, s string) string {
 	var respBody map[string]string
 	err := json.Unmarshal(resp.Body(), &respBody)
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// pushWaitTimeout allows for the outbox worker delivering pushes asynchronously
+const pushWaitTimeout = 10 * time.Second
+
+// pushRequest is a push the stand-in push service received
+type pushRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// pushService is a local stand-in for a browser's push service. It accepts every push, or responds
+// with status once it is set, e.g. 410 Gone to expire the subscriptions pushed to.
+type pushService struct {
+	URL    string
+	pushes chan pushRequest
+	status atomic.Int32
+}
+
+// startPushService serves a pushService on a random local port until the program exits
+func startPushService() *pushService {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	service := &pushService{URL: "http://" + listener.Addr().String(), pushes: make(chan pushRequest, 10)}
+	service.status.Store(http.StatusCreated)
+
+	go func() {
+		_ = http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			service.pushes <- pushRequest{Header: r.Header, Body: body}
+			w.WriteHeader(int(service.status.Load()))
+		}))
+	}()
+	return service
+}
+
+// waitForPush returns the next push the service receives, or false if none arrives in time
+func (s *pushService) waitForPush() (pushRequest, bool) {
+	select {
+	case push := <-s.pushes:
+		fmt.Println("Push received:")
+		for _, header := range []string{"TTL", "Urgency", "Content-Encoding", "Authorization"} {
+			fmt.Printf("  %-16s: %s\n", header, push.Header.Get(header))
+		}
+		fmt.Println("  Body size       :", len(push.Body))
+		fmt.Println()
+		return push, true
+	case <-time.After(pushWaitTimeout):
+		slog.Error("No push arrived at the push service stand-in")
+		return pushRequest{}, false
+	}
+}
+
+// pushDevice holds the keys a browser generates when it subscribes to push notifications
+type pushDevice struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newPushDevice() pushDevice {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	auth := make([]byte, 16)
+	if _, err = rand.Read(auth); err != nil {
+		panic(err)
+	}
+	return pushDevice{private: private, auth: auth}
+}
+
+// subscription returns the JSON a browser sends for its PushSubscription at endpoint
+func (d pushDevice) subscription(endpoint string) string {
+	return fmt.Sprintf(`{"endpoint": "%s", "expirationTime": null, "keys": {"p256dh": "%s", "auth": "%s"}}`,
+		endpoint,
+		base64.RawURLEncoding.EncodeToString(d.private.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(d.auth))
+}
+
+// decrypt decrypts the aes128gcm body of a push as the browser does, see RFC 8291
+func (d pushDevice) decrypt(body []byte) (string, error) {
+	if len(body) < 21 || len(body) < 21+int(body[20]) {
+		return "", errors.New("push body is shorter than its header")
+	}
+	var (
+		salt       = body[:16]
+		recordSize = binary.BigEndian.Uint32(body[16:20])
+		keyID      = body[21 : 21+int(body[20])]
+		ciphertext = body[21+int(body[20]):]
+	)
+	if len(ciphertext) > int(recordSize) {
+		return "", errors.New("push body spans more than one record")
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	shared, err := d.private.ECDH(asPublic)
+	if err != nil {
+		return "", err
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), d.private.PublicKey().Bytes()...), keyID...)
+	ikm := expand(d.auth, shared, keyInfo, 32)
+	cek := expand(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := expand(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	// the payload is followed by the last record delimiter and optional zero padding
+	record = []byte(strings.TrimRight(string(record), "\x00"))
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		return "", errors.New("push record has no last record delimiter")
+	}
+	return string(record[:len(record)-1]), nil
+}
+
+func expand(salt, secret, info []byte, length int) []byte {
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		panic(err)
+	}
+	return key
+}
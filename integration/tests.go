@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -29,21 +31,18 @@ func main() {
 	// details for user's registration
 	resp, err = client.R().EnableTrace().
 		SetBody(fmt.Sprintf(`{
-			"username": "%s_username",
+			"username": "u_%s",
 			"firstName": "%s",
 			"lastName": "%s",
-			"password": "12345678",
+			"password": "%s-Expense-Passphrase",
 			"email": "%s@gmail.com"
-		}`, hash, hash, hash, hash)).
+		}`, hash, hash, hash, hash, hash)).
 		Post(baseURL + "/user/register")
 	printTest(resp, err)
 
-	// internal endpoint to get OTP for email
-	resp, err = client.R().EnableTrace().
-		SetQueryParams(map[string]string{"email": hash + "@gmail.com"}).
-		Get(baseURL + "/admin/user/otp")
-	printTest(resp, err)
-	otp := getField(resp, "otp")
+	// the verification email is captured by the development mailbox,
+	// so the service must run with MODE=development and MAIL_DRIVER=mailbox
+	otp := waitForVerificationCode(hash + "@gmail.com")
 
 	resp, err = client.R().EnableTrace().
 		SetBody(fmt.Sprintf(`{"email": "%s@gmail.com","verificationCode" : "%s"}`, hash, otp)).
@@ -55,4 +54,48 @@ func main() {
 		SetHeader("Authorization", sessionToken).
 		Patch(baseURL + "/user/profile")
 	printTest(resp, err)
+
+	// pushes go to a local stand-in for a browser's push service,
+	// so the service must also run with PUSH_ALLOW_INSECURE=true
+	pushes := startPushService()
+	device := newPushDevice()
+
+	resp, err = client.R().EnableTrace().
+		Get(baseURL + "/push/vapid-key")
+	printTest(resp, err)
+
+	resp, err = client.R().EnableTrace().
+		SetHeader("Authorization", sessionToken).
+		SetBody(device.subscription(pushes.URL + "/push/" + hash)).
+		Post(baseURL + "/push/subscriptions")
+	printTest(resp, err)
+
+	// signing in from another browser is a security alert, which is pushed
+	login := fmt.Sprintf(`{"email": "%s@gmail.com", "password": "%s-Expense-Passphrase"}`, hash, hash)
+	resp, err = client.R().EnableTrace().
+		SetHeader("User-Agent", "integration-browser-2").
+		SetBody(login).
+		Post(baseURL + "/user/login")
+	printTest(resp, err)
+
+	if push, ok := pushes.waitForPush(); ok {
+		payload, err := device.decrypt(push.Body)
+		fmt.Println("Decrypted push:", payload, err)
+		fmt.Println()
+	}
+
+	// a push service responding 410 Gone expires the subscription
+	pushes.status.Store(http.StatusGone)
+	resp, err = client.R().EnableTrace().
+		SetHeader("User-Agent", "integration-browser-3").
+		SetBody(login).
+		Post(baseURL + "/user/login")
+	printTest(resp, err)
+	pushes.waitForPush()
+	time.Sleep(mailboxPollInterval) // for the outbox worker to delete the subscription
+
+	resp, err = client.R().EnableTrace().
+		SetHeader("Authorization", sessionToken).
+		Get(baseURL + "/push/subscriptions")
+	printTest(resp, err)
 }
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs fn every interval until ctx is cancelled, starting after the first interval.
+// Runs never overlap: a slow run delays the next one instead of stacking up.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context)) {
+	log := slog.With("job", name, "interval", interval)
+	log.Info("Scheduling job")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping job")
+			return
+		case <-ticker.C:
+			run(ctx, log, fn)
+		}
+	}
+}
+
+func run(ctx context.Context, log *slog.Logger, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Job panicked", "panic", r)
+		}
+	}()
+	start := time.Now()
+	fn(ctx)
+	log.Debug("Job finished", "took", time.Since(start))
+}
@@ -3,20 +3,56 @@ package main
 import (
 	"context"
 	log "log/slog"
+	_ "time/tzdata" // user timezones must resolve even where the host has no zoneinfo
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/harisnkr/expense/announcement"
 	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
 	"github.com/harisnkr/expense/controllers"
+	announcementcontroller "github.com/harisnkr/expense/controllers/announcement"
+	"github.com/harisnkr/expense/controllers/blob"
 	"github.com/harisnkr/expense/controllers/card"
+	"github.com/harisnkr/expense/controllers/household"
+	"github.com/harisnkr/expense/controllers/mailbox"
+	"github.com/harisnkr/expense/controllers/notification"
+	outboxcontroller "github.com/harisnkr/expense/controllers/outbox"
+	"github.com/harisnkr/expense/controllers/policy"
+	pushcontroller "github.com/harisnkr/expense/controllers/push"
 	"github.com/harisnkr/expense/controllers/user"
+	webhookcontroller "github.com/harisnkr/expense/controllers/webhook"
 	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/digest"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/jobs"
 	"github.com/harisnkr/expense/middleware"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+	"github.com/harisnkr/expense/outbox"
+	"github.com/harisnkr/expense/push"
+	"github.com/harisnkr/expense/reminders"
+	"github.com/harisnkr/expense/storage"
+	"github.com/harisnkr/expense/webhook"
 )
 
 var (
-	cardAPI card.API
-	userAPI user.API
+	announcementAPI announcementcontroller.API
+	blobAPI         blob.API
+	cardAPI         card.API
+	householdAPI    household.API
+	mailboxAPI      mailbox.API
+	notificationAPI notification.API
+	outboxAPI       outboxcontroller.API
+	policyAPI       policy.API
+	pushAPI         pushcontroller.API
+	userAPI         user.API
+	webhookAPI      webhookcontroller.API
+
+	authMiddleware  gin.HandlerFunc
+	adminMiddleware gin.HandlerFunc
+	// pendingConsentMiddleware authenticates the endpoints users can reach before accepting changed policies
+	pendingConsentMiddleware gin.HandlerFunc
 )
 
 func main() {
@@ -27,13 +63,124 @@ func main() {
 	// TODO: add app config?
 	client, collections := data.InitDatabase(context.Background())
 
-	cardAPI = card.New(client, collections)
-	userAPI = user.New(client, collections)
+	blobStore, err := storage.NewLocalStore(config.BlobDir)
+	if err != nil {
+		log.Error("Failed to initialise blob store", "err", err)
+		panic(err)
+	}
+
+	mailer, err := email.New(collections.Mailbox)
+	if err != nil {
+		log.Error("Failed to initialise mailer", "err", err)
+		panic(err)
+	}
+
+	if err = data.EnsureUnverifiedUserTTL(context.Background(), collections.Users, user.UnverifiedUserTTL()); err != nil {
+		log.Error("Failed to ensure unverified user TTL index", "err", err)
+	}
+	if err = data.EnsureUsernameIndex(context.Background(), collections.Users); err != nil {
+		// usernames are only unique through this index
+		log.Error("Failed to ensure username index", "err", err)
+		panic(err)
+	}
+	if err = data.EnsurePolicyVersionIndex(context.Background(), collections.Policies); err != nil {
+		log.Error("Failed to ensure policy version index", "err", err)
+	}
+	if common.DevMailboxEnabled() {
+		if err = data.EnsureMailboxTTL(context.Background(), collections.Mailbox, config.DevMailboxTTL); err != nil {
+			log.Error("Failed to ensure mailbox TTL index", "err", err)
+		}
+	}
+	if err = data.EnsureOutboxIndexes(context.Background(), collections.Outbox, config.OutboxRetention); err != nil {
+		log.Error("Failed to ensure outbox indexes", "err", err)
+	}
+	err = data.EnsureNotificationIndexes(context.Background(), collections.Notifications, config.NotificationRetention)
+	if err != nil {
+		// notifications with a DedupKey are only sent once through its unique index
+		log.Error("Failed to ensure notification indexes", "err", err)
+		panic(err)
+	}
+	err = data.EnsureWebhookIndexes(context.Background(), collections.Webhooks, collections.WebhookDeliveries,
+		config.WebhookDeliveryRetention)
+	if err != nil {
+		log.Error("Failed to ensure webhook indexes", "err", err)
+	}
+	if err = data.EnsurePushIndexes(context.Background(), collections.PushSubscriptions); err != nil {
+		log.Error("Failed to ensure push subscription indexes", "err", err)
+	}
+	if err = data.EnsureAnnouncementIndex(context.Background(), collections.Announcements); err != nil {
+		log.Error("Failed to ensure announcement index", "err", err)
+	}
+
+	box := outbox.New(collections.Outbox)
+	box.Handle(models.OutboxEmail, config.SMTPTimeout, outbox.EmailHandler(mailer))
+	box.Handle(models.OutboxGenerateExport, user.ExportTimeout, user.ExportHandler(collections, blobStore))
+	box.Handle(models.OutboxWebhook, webhook.HandlerTimeout(),
+		webhook.DeliveryHandler(collections.Webhooks, collections.WebhookDeliveries, webhook.NewClient()))
+	box.Handle(models.OutboxPush, push.HandlerTimeout(),
+		push.DeliveryHandler(collections.PushSubscriptions, collections.Notifications, push.NewClient()))
+	notifier := notify.New(collections.Notifications, collections.Users, collections.PushSubscriptions, box)
+	publisher := webhook.New(collections.Webhooks, collections.WebhookDeliveries, box)
+
+	authMiddleware = middleware.Auth(collections.Users, collections.Policies)
+	pendingConsentMiddleware = middleware.AuthPendingConsent(collections.Users)
+	adminMiddleware = middleware.Admin(collections.Users)
+	announcementAPI = announcementcontroller.New(client, collections)
+	blobAPI = blob.New(blobStore)
+	cardAPI = card.New(client, collections, publisher)
+	householdAPI = household.New(client, collections, box, notifier, publisher)
+	mailboxAPI = mailbox.New(client, collections)
+	notificationAPI = notification.New(client, collections)
+	outboxAPI = outboxcontroller.New(client, collections)
+	policyAPI = policy.New(client, collections)
+	pushAPI = pushcontroller.New(client, collections)
+	userAPI = user.New(client, collections, blobStore, box, notifier, publisher)
+	webhookAPI = webhookcontroller.New(client, collections, publisher)
+	user.SeedDevUser(context.Background(), collections)
+
+	go jobs.Every(context.Background(), "purge-deleted-users", config.PurgeInterval, func(ctx context.Context) {
+		user.PurgeDeletedUsers(ctx, collections, blobStore)
+	})
+	go jobs.Every(context.Background(), "remind-unverified-users", config.PurgeInterval, func(ctx context.Context) {
+		user.RemindUnverifiedUsers(ctx, collections, box)
+	})
+	go jobs.Every(context.Background(), "purge-unverified-users", config.PurgeInterval, func(ctx context.Context) {
+		user.PurgeUnverifiedUsers(ctx, collections, blobStore)
+	})
+	kinds := []models.OutboxKind{models.OutboxEmail, models.OutboxGenerateExport, models.OutboxWebhook, models.OutboxPush}
+	for _, kind := range kinds {
+		go jobs.Every(context.Background(), "outbox-"+string(kind), config.OutboxPollInterval, func(ctx context.Context) {
+			box.Process(ctx, kind)
+		})
+	}
+	go jobs.Every(context.Background(), "expire-exports", config.PurgeInterval, func(ctx context.Context) {
+		user.ExpireExports(ctx, collections, blobStore)
+	})
+	go jobs.Every(context.Background(), "send-digests", config.DigestInterval, func(ctx context.Context) {
+		digest.SendDue(ctx, collections, box)
+	})
+	go jobs.Every(context.Background(), "send-announcements", config.AnnouncementInterval, func(ctx context.Context) {
+		announcement.SendDue(ctx, collections, notifier)
+	})
+	go jobs.Every(context.Background(), "send-reminders", config.ReminderInterval, func(ctx context.Context) {
+		reminders.SendDue(ctx, collections, notifier)
+	})
 
 	r.GET("/health", controllers.Health)
+	r.GET("/blobs/*key", blobAPI.GetBlob) // authenticated by the signed URL
 
 	registerCardRoutes(r, cardAPI)
 	registerUserRoutes(r, userAPI)
+	registerHouseholdRoutes(r, householdAPI, collections)
+	registerPolicyRoutes(r, policyAPI)
+	registerNotificationRoutes(r, notificationAPI)
+	registerPushRoutes(r, pushAPI)
+	registerWebhookRoutes(r, webhookAPI)
+	registerOutboxRoutes(r, outboxAPI)
+	registerAnnouncementRoutes(r, announcementAPI)
+	if common.DevMailboxEnabled() {
+		registerMailboxRoutes(r, mailboxAPI)
+	}
 
 	if err := r.Run(); err != nil {
 		log.Error("Failed to start server, %w", err)
@@ -44,29 +191,176 @@ func main() {
 func registerUserRoutes(r *gin.Engine, userAPI user.API) {
 	adminRouter := r.Group("/admin")
 	{
-		adminRouter.GET("user/otp", userAPI.GetEmailOTP)
+		adminRouter.GET("/users", authMiddleware, adminMiddleware, userAPI.AdminListUsers)
+		adminRouter.GET("/users/:id", authMiddleware, adminMiddleware, userAPI.AdminGetUser)
+		adminRouter.POST("/users/:id/verify", authMiddleware, adminMiddleware, userAPI.AdminVerifyUser)
+		adminRouter.POST("/users/:id/disable", authMiddleware, adminMiddleware, userAPI.AdminDisableUser)
+		adminRouter.POST("/users/:id/enable", authMiddleware, adminMiddleware, userAPI.AdminEnableUser)
+		adminRouter.POST("/users/:id/password-reset", authMiddleware, adminMiddleware, userAPI.AdminResetPassword)
+		adminRouter.POST("/users/:id/impersonate", authMiddleware, adminMiddleware, userAPI.AdminImpersonateUser)
 	}
 
 	userRouter := r.Group("/user")
 	{
 		userRouter.POST("/register", userAPI.RegisterUser)
+		userRouter.GET("/username/available", userAPI.UsernameAvailable)
+		userRouter.DELETE("", pendingConsentMiddleware, userAPI.DeleteUser)
+		userRouter.POST("/delete/code", pendingConsentMiddleware, userAPI.SendDeleteCode)
+		userRouter.POST("/restore", userAPI.RestoreUser)
+		userRouter.POST("/restore/code", userAPI.SendRestoreCode)
 		userRouter.POST("/email/verify", userAPI.VerifyEmail)
-		userRouter.PATCH("/profile", middleware.Auth(), userAPI.UpdateProfile)
+		userRouter.GET("/me", pendingConsentMiddleware, userAPI.GetMe)
+		userRouter.GET("/consents", pendingConsentMiddleware, userAPI.GetConsents)
+		userRouter.POST("/consents", pendingConsentMiddleware, userAPI.AcceptPolicies)
+		userRouter.PATCH("/profile", authMiddleware, userAPI.UpdateProfile)
+		userRouter.POST("/email", authMiddleware, userAPI.ChangeEmail)
+		userRouter.POST("/email/confirm", authMiddleware, userAPI.ConfirmEmailChange)
+		userRouter.GET("/preferences", authMiddleware, userAPI.GetPreferences)
+		userRouter.PATCH("/preferences", authMiddleware, userAPI.UpdatePreferences)
+		userRouter.PUT("/profile/picture", authMiddleware, userAPI.UploadProfilePicture)
+		userRouter.DELETE("/profile/picture", authMiddleware, userAPI.DeleteProfilePicture)
 		userRouter.POST("/login", userAPI.Login)
+		userRouter.POST("/login/link", userAPI.SendMagicLink)
+		userRouter.POST("/login/link/consume", userAPI.ConsumeMagicLink)
+		userRouter.POST("/password/reset/request", userAPI.RequestPasswordReset)
+		userRouter.POST("/password/reset", userAPI.ResetPassword)
+		userRouter.POST("/passkey/register/begin", authMiddleware, userAPI.BeginPasskeyRegistration)
+		userRouter.POST("/passkey/register/finish", authMiddleware, userAPI.FinishPasskeyRegistration)
+		userRouter.POST("/passkey/login/begin", userAPI.BeginPasskeyLogin)
+		userRouter.POST("/passkey/login/finish", userAPI.FinishPasskeyLogin)
+		userRouter.DELETE("/passkey/:id", authMiddleware, userAPI.RemovePasskey)
+		userRouter.GET("/security/events", authMiddleware, userAPI.GetSecurityEvents)
+		userRouter.POST("/export", pendingConsentMiddleware, userAPI.RequestExport)
+		userRouter.GET("/export/download", userAPI.DownloadExport) // authenticated by the signed link
+		userRouter.GET("/export/:id", pendingConsentMiddleware, userAPI.GetExport)
 	}
 }
 
-func registerCardRoutes(r *gin.Engine, cardAPI card.API) {
+func registerHouseholdRoutes(r *gin.Engine, householdAPI household.API, collections *data.Collections) {
+	var (
+		viewer = middleware.HouseholdRole(collections.Households, models.HouseholdRoleViewer)
+		member = middleware.HouseholdRole(collections.Households, models.HouseholdRoleMember)
+		owner  = middleware.HouseholdRole(collections.Households, models.HouseholdRoleOwner)
+	)
+
+	householdRouter := r.Group("/households", authMiddleware)
+	{
+		householdRouter.POST("", householdAPI.CreateHousehold)
+		householdRouter.GET("", householdAPI.GetHouseholds)
+		householdRouter.POST("/invitations/accept", householdAPI.AcceptInvitation)
+		householdRouter.GET("/:id", viewer, householdAPI.GetHousehold)
+		householdRouter.DELETE("/:id", owner, householdAPI.DeleteHousehold)
+		householdRouter.POST("/:id/invitations", owner, householdAPI.InviteMember)
+		householdRouter.DELETE("/:id/invitations/:invitationID", owner, householdAPI.RevokeInvitation)
+		householdRouter.PATCH("/:id/members/:userID", owner, householdAPI.UpdateMember)
+		householdRouter.DELETE("/:id/members/:userID", viewer, householdAPI.RemoveMember) // members may leave
+		householdRouter.POST("/:id/budgets", member, householdAPI.AddBudget)
+		householdRouter.DELETE("/:id/budgets/:itemID", member, householdAPI.DeleteBudget)
+		householdRouter.POST("/:id/transactions", member, householdAPI.AddTransaction)
+		householdRouter.DELETE("/:id/transactions/:itemID", member, householdAPI.DeleteTransaction)
+	}
+}
+
+func registerPolicyRoutes(r *gin.Engine, policyAPI policy.API) {
 	adminRouter := r.Group("/admin")
+	{
+		adminRouter.POST("/policies", authMiddleware, adminMiddleware, policyAPI.AdminPublishPolicy)
+		adminRouter.GET("/policies/:document/acceptance", authMiddleware, adminMiddleware,
+			policyAPI.AdminGetPolicyAcceptance)
+	}
+
+	r.GET("/policies/current", policyAPI.GetCurrentPolicies)
+}
+
+func registerNotificationRoutes(r *gin.Engine, notificationAPI notification.API) {
+	notificationRouter := r.Group("/notifications", authMiddleware)
+	{
+		notificationRouter.GET("", notificationAPI.ListNotifications)
+		notificationRouter.GET("/unread-count", notificationAPI.UnreadCount)
+		notificationRouter.POST("/read-all", notificationAPI.MarkAllRead)
+		notificationRouter.POST("/:id/read", notificationAPI.MarkRead)
+	}
+}
+
+func registerPushRoutes(r *gin.Engine, pushAPI pushcontroller.API) {
+	r.GET("/push/vapid-key", pushAPI.GetVAPIDKey)
+
+	pushRouter := r.Group("/push/subscriptions", authMiddleware)
+	{
+		pushRouter.POST("", pushAPI.Subscribe)
+		pushRouter.GET("", pushAPI.ListSubscriptions)
+		pushRouter.DELETE("/:id", pushAPI.Unsubscribe)
+	}
+}
+
+func registerWebhookRoutes(r *gin.Engine, webhookAPI webhookcontroller.API) {
+	adminRouter := r.Group("/admin/webhooks", authMiddleware, adminMiddleware)
+	{
+		adminRouter.POST("", webhookAPI.AdminCreateWebhook)
+		adminRouter.GET("", webhookAPI.AdminListWebhooks)
+		adminRouter.GET("/:id", webhookAPI.AdminGetWebhook)
+		adminRouter.PATCH("/:id", webhookAPI.AdminUpdateWebhook)
+		adminRouter.DELETE("/:id", webhookAPI.AdminDeleteWebhook)
+		adminRouter.GET("/:id/deliveries", webhookAPI.AdminListDeliveries)
+		adminRouter.POST("/:id/deliveries/:deliveryID/redeliver", webhookAPI.AdminRedeliver)
+	}
+
+	webhookRouter := r.Group("/webhooks", authMiddleware)
+	{
+		webhookRouter.GET("/events", webhookAPI.ListEvents)
+		webhookRouter.POST("", webhookAPI.CreateWebhook)
+		webhookRouter.GET("", webhookAPI.ListWebhooks)
+		webhookRouter.GET("/:id", webhookAPI.GetWebhook)
+		webhookRouter.PATCH("/:id", webhookAPI.UpdateWebhook)
+		webhookRouter.DELETE("/:id", webhookAPI.DeleteWebhook)
+		webhookRouter.GET("/:id/deliveries", webhookAPI.ListDeliveries)
+		webhookRouter.POST("/:id/deliveries/:deliveryID/redeliver", webhookAPI.Redeliver)
+	}
+}
+
+func registerAnnouncementRoutes(r *gin.Engine, announcementAPI announcementcontroller.API) {
+	adminRouter := r.Group("/admin/announcements", authMiddleware, adminMiddleware)
+	{
+		adminRouter.POST("", announcementAPI.AdminCreateAnnouncement)
+		adminRouter.GET("", announcementAPI.AdminListAnnouncements)
+		adminRouter.POST("/preview", announcementAPI.AdminPreviewSegment)
+		adminRouter.GET("/:id", announcementAPI.AdminGetAnnouncement)
+		adminRouter.PATCH("/:id", announcementAPI.AdminUpdateAnnouncement)
+		adminRouter.POST("/:id/schedule", announcementAPI.AdminScheduleAnnouncement)
+		adminRouter.POST("/:id/cancel", announcementAPI.AdminCancelAnnouncement)
+	}
+}
+
+func registerOutboxRoutes(r *gin.Engine, outboxAPI outboxcontroller.API) {
+	adminRouter := r.Group("/admin", authMiddleware, adminMiddleware)
+	{
+		adminRouter.GET("/outbox", outboxAPI.AdminListOutbox)
+		adminRouter.POST("/outbox/:id/retry", outboxAPI.AdminRetryOutboxEntry)
+	}
+}
+
+// registerMailboxRoutes exposes captured emails without authentication, so it is only called in development
+func registerMailboxRoutes(r *gin.Engine, mailboxAPI mailbox.API) {
+	devRouter := r.Group("/dev")
+	{
+		devRouter.GET("/mailbox", mailboxAPI.ListMessages)
+		devRouter.GET("/mailbox/:id", mailboxAPI.GetMessage)
+		devRouter.DELETE("/mailbox", mailboxAPI.ClearMessages)
+	}
+}
+
+func registerCardRoutes(r *gin.Engine, cardAPI card.API) {
+	adminRouter := r.Group("/admin", authMiddleware, adminMiddleware)
 	{
 		adminRouter.POST("/card", cardAPI.AdminCreateCard)
 		adminRouter.PUT("/card/:id", cardAPI.AdminUpdateCard)
 		adminRouter.DELETE("/card", cardAPI.AdminDeleteCard)
 	}
 
-	r.GET("/cards", middleware.Auth(), cardAPI.GetAllCards)
-	r.GET("/card/:name", middleware.Auth(), cardAPI.GetCard)
-	r.POST("/user/card", middleware.Auth(), cardAPI.AddCardToUser)
+	r.GET("/cards", authMiddleware, cardAPI.GetAllCards)
+	r.GET("/cards/search", authMiddleware, cardAPI.SearchCards)
+	r.GET("/card/:name", authMiddleware, cardAPI.GetCard)
+	r.POST("/user/card", authMiddleware, cardAPI.AddCardToUser)
 }
 
 func init() {
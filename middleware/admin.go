@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/models"
+)
+
+// Admin is a middleware allowing only admins through, it must run after Auth.
+// Impersonation sessions are never admins, even when the admin behind them is.
+func Admin(users *mongo.Collection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString(common.UserID)
+		log := slog.With(common.RequestID, c.MustGet(common.RequestID), common.UserID, userID)
+
+		if c.GetString(common.ImpersonatorID) != "" {
+			log.Warn("Impersonation session refused admin access")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		opts := options.FindOne().SetProjection(bson.M{"role": 1})
+		if err := users.FindOne(c, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+			log.Warn("Failed to look up user role", "err", err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		if user.Role != models.RoleAdmin {
+			log.Warn("Non-admin refused admin access")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/dto"
+	"github.com/harisnkr/expense/models"
+)
+
+// policyCacheTTL bounds how long after publishing a new policy version other instances start asking for it
+const policyCacheTTL = time.Minute
+
+// policyCache holds the current policy versions, so authenticating a request does not look them up every time
+type policyCache struct {
+	policies *mongo.Collection
+
+	mu        sync.Mutex
+	current   map[models.PolicyDocument]models.PolicyVersion
+	fetchedAt time.Time
+}
+
+func (p *policyCache) get(ctx context.Context) (map[models.PolicyDocument]models.PolicyVersion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != nil && time.Since(p.fetchedAt) < policyCacheTTL {
+		return p.current, nil
+	}
+
+	current, err := data.CurrentPolicyVersions(ctx, p.policies)
+	if err != nil {
+		if p.current != nil {
+			// the versions last seen are still better than letting every request through
+			slog.Warn("Failed to refresh policy versions, using the cached ones", "err", err,
+				"fetchedAt", p.fetchedAt)
+			return p.current, nil
+		}
+		return nil, err
+	}
+	p.current, p.fetchedAt = current, time.Now()
+	return current, nil
+}
+
+// consented checks user accepted the current policy versions, aborting with 428 and
+// dto.ConsentRequiredCode if not. When the versions cannot be looked up and none were cached, the request
+// is aborted with 503 rather than let through unchecked.
+func consented(c *gin.Context, log *slog.Logger, policies *policyCache, user models.User) bool {
+	current, err := policies.get(c)
+	if err != nil {
+		log.Error("Failed to look up current policy versions", "err", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable, please try again"})
+		c.Abort()
+		return false
+	}
+
+	pending := user.PendingPolicies(current)
+	if len(pending) == 0 {
+		return true
+	}
+	c.JSON(http.StatusPreconditionRequired, dto.NewConsentRequiredResponse(pending))
+	c.Abort()
+	return false
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/models"
+)
+
+// HouseholdRole is a middleware allowing through members of the household in the :id route parameter
+// whose role is at least required. It must run after Auth, and sets common.Household for the handler.
+// Non-members get a 404 so that household IDs cannot be probed.
+func HouseholdRole(households *mongo.Collection, required models.HouseholdRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			userID      = c.GetString(common.UserID)
+			householdID = c.Param("id")
+			log         = slog.With(common.RequestID, c.MustGet(common.RequestID), common.UserID, userID,
+				"householdID", householdID)
+		)
+
+		var household models.Household
+		if err := households.FindOne(c, bson.M{"_id": householdID}).Decode(&household); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Household not found"})
+				c.Abort()
+				return
+			}
+			log.Error("Failed to find household", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+			c.Abort()
+			return
+		}
+
+		member, ok := household.Member(userID)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Household not found"})
+			c.Abort()
+			return
+		}
+		if !member.Role.AtLeast(required) {
+			log.Info("Household role too low", "role", member.Role, "required", required)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Household role " + string(required) + " required"})
+			c.Abort()
+			return
+		}
+
+		c.Set(common.Household, household)
+		c.Next()
+	}
+}
@@ -1,34 +1,59 @@
 package middleware
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/harisnkr/expense/common"
 	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/models"
 )
 
 // Claims structure to hold the information in the JWT token
 type Claims struct {
 	Email string `json:"email"`
+	// Purpose is only set on single-purpose tokens (see common.PurposeClaims), which are not sessions
+	Purpose string `json:"purpose,omitempty"`
+	// Actor is set when an admin is acting as the subject, see user.AdminImpersonateUser
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// Auth is a middleware to verify session tokens issued
-func Auth() gin.HandlerFunc {
-	if common.GetMode() == common.Development {
-		// skip auth middleware if development environment
-		return func(c *gin.Context) {
-			slog.Warn("skipping auth middleware in development env")
-			c.Set(common.UserID, "")
-			c.Next()
-		}
-	}
+// Actor identifies who is really behind a session token acting as another user (RFC 8693 "act" claim)
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// Auth is a middleware to verify session tokens issued, that the token's user is still active, and that
+// they have accepted the current version of every policy document
+func Auth(users, policies *mongo.Collection) gin.HandlerFunc {
+	return auth(users, &policyCache{policies: policies})
+}
+
+// AuthPendingConsent is Auth without the policy consent check, for the endpoints a user still needs before
+// accepting changed policies: reviewing and accepting them, and exporting or deleting their data
+func AuthPendingConsent(users *mongo.Collection) gin.HandlerFunc {
+	return auth(users, nil)
+}
+
+func auth(users *mongo.Collection, policies *policyCache) gin.HandlerFunc {
+	devAuth := common.DevAuthEnabled()
 	return func(c *gin.Context) {
 		log := slog.With(common.RequestID, c.MustGet(common.RequestID))
+		if devUserID := c.GetHeader(common.DevUserHeader); devAuth && devUserID != "" {
+			log.Warn("authenticating with development identity", common.UserID, devUserID)
+			c.Set(common.UserID, devUserID)
+			c.Next()
+			return
+		}
+
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -40,15 +65,29 @@ func Auth() gin.HandlerFunc {
 			return &config.ECDSAKey.PublicKey, nil
 		})
 		if err != nil {
-			log.Error("jwt.ParseWithClaims failed", err)
+			log.Error("jwt.ParseWithClaims failed", "err", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token (ParseWithClaims)"})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Purpose == "" {
+			user, ok := userActive(c, log, users, claims)
+			if !ok {
+				return
+			}
+			// admins impersonating a user must not accept policies on their behalf
+			if policies != nil && claims.Actor == nil && !consented(c, log, policies, user) {
+				return
+			}
 			c.Set("email", claims.Email)
 			c.Set("userID", claims.Subject)
+			if claims.Actor != nil {
+				c.Set(common.ImpersonatorID, claims.Actor.Subject)
+				c.Header(common.ImpersonatedByHeader, claims.Actor.Subject)
+				log.With(common.UserID, claims.Subject, common.ImpersonatorID, claims.Actor.Subject).
+					Warn("Admin impersonating user")
+			}
 			log.With(common.Email, claims.Email, common.UserID, claims.Subject).
 				Info("User authenticated")
 			c.Next()
@@ -59,3 +98,45 @@ func Auth() gin.HandlerFunc {
 		}
 	}
 }
+
+// userActive checks the user still exists, has not deleted their account, is not disabled and has not
+// ended their sessions since the token was issued, aborting the request if not. The returned user only has
+// the fields needed for these checks.
+func userActive(c *gin.Context, log *slog.Logger, users *mongo.Collection, claims *Claims) (models.User, bool) {
+	var (
+		user   models.User
+		userID = claims.Subject
+	)
+	opts := options.FindOne().SetProjection(bson.M{
+		"deleted_at": 1, "disabled_at": 1, "sessions_valid_after": 1, "consents": 1,
+	})
+	if err := users.FindOne(c, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return user, false
+		}
+		log.Error("Failed to look up user status", "err", err, common.UserID, userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unknown error"})
+		c.Abort()
+		return user, false
+	}
+
+	if user.DeletedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is scheduled for deletion"})
+		c.Abort()
+		return user, false
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		c.Abort()
+		return user, false
+	}
+	// iat has whole seconds, so a session issued in the same second as the cutoff may predate it and ends too
+	if user.SessionsValidAfter != nil && (claims.IssuedAt == nil || !claims.IssuedAt.After(*user.SessionsValidAfter)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please sign in again"})
+		c.Abort()
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// AnnouncementStatus is the lifecycle state of an Announcement
+type AnnouncementStatus string
+
+const (
+	AnnouncementDraft     AnnouncementStatus = "draft"
+	AnnouncementScheduled AnnouncementStatus = "scheduled" // waiting for SendAt
+	AnnouncementSending   AnnouncementStatus = "sending"   // notifying its segment in batches
+	AnnouncementSent      AnnouncementStatus = "sent"
+	AnnouncementCancelled AnnouncementStatus = "cancelled"
+)
+
+// Announcement is a message admins broadcast to a segment of users, delivered as a
+// NotificationAnnouncement to those who opted in to product updates. A ServiceNotice is instead delivered
+// as a NotificationServiceNotice to everyone in the segment.
+type Announcement struct {
+	ID      string              `bson:"_id"`
+	Title   string              `bson:"title"`
+	Body    string              `bson:"body"` // plain text, paragraphs separated by blank lines
+	Segment AnnouncementSegment `bson:"segment"`
+
+	// ServiceNotice marks a notice about the cards in Segment.CardIDs, e.g. changed terms, which their
+	// holders need whether or not they want product updates. Only segments of card holders can have one.
+	ServiceNotice bool `bson:"service_notice,omitempty"`
+
+	Status    AnnouncementStatus `bson:"status"`
+	SendAt    *time.Time         `bson:"send_at,omitempty"` // set once scheduled
+	CreatedBy string             `bson:"created_by"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+
+	StartedAt   *time.Time `bson:"started_at,omitempty"`
+	CompletedAt *time.Time `bson:"completed_at,omitempty"`
+	CancelledAt *time.Time `bson:"cancelled_at,omitempty"`
+
+	// Recipients is how many users the segment matched when sending started, Processed how many of them
+	// were notified so far. Users are processed in _id order, Cursor is the last one processed.
+	Recipients int    `bson:"recipients"`
+	Processed  int    `bson:"processed"`
+	Cursor     string `bson:"cursor,omitempty"`
+
+	LeaseOwner     string     `bson:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `bson:"lease_expires_at,omitempty"` // another instance may take over sending after
+}
+
+// AnnouncementSegment selects the users an Announcement is for. Every condition that is set must hold,
+// an empty segment is every active user.
+type AnnouncementSegment struct {
+	CardIDs  []string `bson:"card_ids,omitempty"` // holders of any of these catalogue cards
+	Verified *bool    `bson:"verified,omitempty"` // verified, or unverified, users only
+
+	// Timezones select a region: users have no region or country of their own, so a region is the
+	// IANA timezones in it, matched against the timezone users chose in their Preferences
+	Timezones []string `bson:"timezones,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog is an append-only record of a sensitive action taken on a user's account
+type AuditLog struct {
+	ID       string            `bson:"_id"`
+	Action   string            `bson:"action"`
+	ActorID  string            `bson:"actor_id"` // user or admin ID, or "system" for background jobs
+	TargetID string            `bson:"target_id"`
+	Metadata map[string]string `bson:"metadata,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+// Audit actions
+const (
+	AuditUserPurged           = "user.purged"
+	AuditUnverifiedUserPurged = "user.unverified_purged"
+	AuditUserForceVerified    = "user.force_verified"
+	AuditUserDisabled         = "user.disabled"
+	AuditUserEnabled          = "user.enabled"
+	AuditPasswordResetSent    = "user.password_reset_sent"
+	AuditUserImpersonated     = "user.impersonated"
+	AuditPolicyPublished      = "policy.published"
+
+	AuditAnnouncementScheduled = "announcement.scheduled"
+	AuditAnnouncementCancelled = "announcement.cancelled"
+
+	// AuditActorSystem is the ActorID of actions taken by background jobs
+	AuditActorSystem = "system"
+)
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 // Card represents a credit/debit card that a user might have
 type Card struct {
 	ID         string                 `bson:"_id"`
@@ -7,20 +11,58 @@ type Card struct {
 	IssuerBank string                 `bson:"issuer_bank"`
 	Network    string                 `bson:"network"`
 	Miles      Miles                  `bson:"miles"`
+	AnnualFee  float64                `bson:"annual_fee"` // charged yearly, a card without one is free
 	Image      string                 `bson:"image"`
 	Other      map[string]interface{} `bson:"other"` // property bag
+
+	// StatementDay and AnnualFeeDue are only set on a user's own copy of a card, see User.Cards
+	StatementDay int        `bson:"statement_day,omitempty"` // day of the month statements are cut
+	AnnualFeeDue *time.Time `bson:"annual_fee_due,omitempty"`
 }
 
 // Miles refer to miles related info that a Card can have
 type Miles struct {
 	BonusMultiplier    float64         `bson:"bonus_multiplier"`
-	BonusMultiplierCap float64         `bson:"bonus_multiplier_cap"`
+	BonusMultiplierCap float64         `bson:"bonus_multiplier_cap"` // bonus spend per reward cycle, 0 if uncapped
 	LocalMultiplier    float32         `bson:"multiplier"`
 	OverseasMultiplier float32         `bson:"overseas_multiplier"`
 	MinimumSpend       float32         `bson:"minimum_spend"`
 	SpendCategories    []SpendCategory `bson:"spend_categories"`
 }
 
+// IsBonus reports whether spending in category earns the bonus multiplier
+func (m Miles) IsBonus(category SpendCategory) bool {
+	for _, bonus := range m.SpendCategories {
+		if bonus == category {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatementDate returns the first statement date on or after from, in from's location. Statements are
+// cut on the last day of months shorter than StatementDay.
+func (c Card) NextStatementDate(from time.Time) time.Time {
+	for months := 0; ; months++ {
+		first := time.Date(from.Year(), from.Month()+time.Month(months), 1, 0, 0, 0, 0, from.Location())
+		last := first.AddDate(0, 1, -1).Day()
+		on := first.AddDate(0, 0, min(c.StatementDay, last)-1)
+		if !on.Before(from) {
+			return on
+		}
+	}
+}
+
+// NextAnnualFeeDue returns the first anniversary of AnnualFeeDue on or after from, at the start of its day in
+// the user's timezone
+func (c Card) NextAnnualFeeDue(prefs Preferences, from time.Time) time.Time {
+	due := prefs.StartOfDay(*c.AnnualFeeDue)
+	for due.Before(from) {
+		due = due.AddDate(1, 0, 0)
+	}
+	return due
+}
+
 // SpendCategory is an int representation of the spend category
 type SpendCategory int
 
@@ -77,3 +119,13 @@ func (s SpendCategory) String() string {
 
 	return "Unknown"
 }
+
+// ParseSpendCategory returns the SpendCategory with the given String() name
+func ParseSpendCategory(name string) (SpendCategory, bool) {
+	for s := Dining; s <= InsurancePremiums; s++ {
+		if s.String() == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// ExportStatus is the lifecycle state of a DataExport
+type ExportStatus string
+
+const (
+	ExportPending    ExportStatus = "pending"
+	ExportReady      ExportStatus = "ready"
+	ExportFailed     ExportStatus = "failed"
+	ExportDownloaded ExportStatus = "downloaded"
+	ExportExpired    ExportStatus = "expired"
+)
+
+// DataExport is an archive of everything stored for a user, generated on request
+type DataExport struct {
+	ID          string       `bson:"_id"`
+	UserID      string       `bson:"user_id"`
+	Status      ExportStatus `bson:"status"`
+	BlobKey     string       `bson:"blob_key,omitempty"` // key of the archive in the storage.BlobStore
+	SizeBytes   int64        `bson:"size_bytes,omitempty"`
+	Error       string       `bson:"error,omitempty"`
+	DownloadID  string       `bson:"download_id,omitempty"` // jti of the outstanding download link
+	CreatedAt   time.Time    `bson:"created_at"`
+	CompletedAt *time.Time   `bson:"completed_at,omitempty"`
+	ExpiresAt   time.Time    `bson:"expires_at"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HouseholdRole is what a member may do in a household
+type HouseholdRole string
+
+const (
+	// HouseholdRoleOwner manages members and invitations, on top of everything a member can do
+	HouseholdRoleOwner HouseholdRole = "owner"
+	// HouseholdRoleMember adds and removes shared budgets and transactions
+	HouseholdRoleMember HouseholdRole = "member"
+	// HouseholdRoleViewer only sees the household's budgets and transactions
+	HouseholdRoleViewer HouseholdRole = "viewer"
+)
+
+// AtLeast reports whether r grants everything required does
+func (r HouseholdRole) AtLeast(required HouseholdRole) bool {
+	return r.rank() >= required.rank()
+}
+
+func (r HouseholdRole) rank() int {
+	switch r {
+	case HouseholdRoleOwner:
+		return 3
+	case HouseholdRoleMember:
+		return 2
+	case HouseholdRoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Household is a group of users sharing budgets and transactions
+type Household struct {
+	ID           string                 `bson:"_id"`
+	Name         string                 `bson:"name"`
+	Members      []HouseholdMember      `bson:"members"`
+	Invitations  []HouseholdInvitation  `bson:"invitations"`
+	Budgets      []HouseholdBudget      `bson:"budgets"`
+	Transactions []HouseholdTransaction `bson:"transactions"`
+	CreatedAt    time.Time              `bson:"created_at"`
+	UpdatedAt    time.Time              `bson:"updated_at"`
+}
+
+// Member returns the membership of userID, if any
+func (h *Household) Member(userID string) (HouseholdMember, bool) {
+	for _, member := range h.Members {
+		if member.UserID == userID {
+			return member, true
+		}
+	}
+	return HouseholdMember{}, false
+}
+
+// Owners counts the household's owners
+func (h *Household) Owners() int {
+	var owners int
+	for _, member := range h.Members {
+		if member.Role == HouseholdRoleOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+// HouseholdMember is a user belonging to a household
+type HouseholdMember struct {
+	UserID   string        `bson:"user_id"`
+	Role     HouseholdRole `bson:"role"`
+	JoinedAt time.Time     `bson:"joined_at"`
+}
+
+// HouseholdInvitation is an emailed invitation to join a household, accepted with a signed link
+type HouseholdInvitation struct {
+	ID        string        `bson:"id"` // jti of the invitation link
+	Email     string        `bson:"email"`
+	Role      HouseholdRole `bson:"role"`
+	InvitedBy string        `bson:"invited_by"`
+	CreatedAt time.Time     `bson:"created_at"`
+	ExpiresAt time.Time     `bson:"expires_at"`
+}
+
+// HouseholdBudget is a budget shared by a household, attributed to the member who created it
+type HouseholdBudget struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Category  string             `bson:"category"`
+	Amount    float64            `bson:"amount"`
+	StartDate time.Time          `bson:"start_date"`
+	EndDate   time.Time          `bson:"end_date"`
+	CreatedBy string             `bson:"created_by"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// HouseholdTransaction is a transaction shared by a household.
+// SpentBy is the member who spent, which may differ from CreatedBy, the member who recorded it.
+type HouseholdTransaction struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Type        string             `bson:"type"`
+	Category    string             `bson:"category"`
+	Amount      float64            `bson:"amount"`
+	Date        time.Time          `bson:"date"`
+	Description string             `bson:"description"`
+	SpentBy     string             `bson:"spent_by"`
+	CreatedBy   string             `bson:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// MailboxMessage is an email captured by the development mailbox instead of being sent
+type MailboxMessage struct {
+	ID        string    `bson:"_id"`
+	From      string    `bson:"from"`
+	To        string    `bson:"to"`
+	Subject   string    `bson:"subject"`
+	Text      string    `bson:"text"`
+	HTML      string    `bson:"html"`
+	CreatedAt time.Time `bson:"created_at"`
+}
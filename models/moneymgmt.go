@@ -27,6 +27,7 @@ type Transaction struct {
 	Amount      float64            `bson:"amount"`
 	Date        time.Time          `bson:"date"`
 	Description string             `bson:"description"`
+	CardID      string             `bson:"card_id,omitempty"` // the card in User.Cards it was paid with
 	CreatedAt   time.Time          `bson:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at"`
 }
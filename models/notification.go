@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationCategory groups notification types for the user's opt-ins and channel choices
+type NotificationCategory string
+
+const (
+	SecurityAlertsCategory NotificationCategory = "security_alerts"
+	BudgetAlertsCategory   NotificationCategory = "budget_alerts"
+	FeeRemindersCategory   NotificationCategory = "fee_reminders"
+	ProductUpdatesCategory NotificationCategory = "product_updates"
+	ServiceNoticesCategory NotificationCategory = "service_notices" // always on, only the channels can be chosen
+)
+
+// NotificationCategories are all notification categories, in the order they are shown
+var NotificationCategories = []NotificationCategory{
+	SecurityAlertsCategory, BudgetAlertsCategory, FeeRemindersCategory, ProductUpdatesCategory,
+	ServiceNoticesCategory,
+}
+
+// ParseNotificationCategory returns the NotificationCategory with the given name
+func ParseNotificationCategory(name string) (NotificationCategory, bool) {
+	for _, category := range NotificationCategories {
+		if string(category) == name {
+			return category, true
+		}
+	}
+	return "", false
+}
+
+// NotificationType is the event a Notification is about
+type NotificationType string
+
+const (
+	NotificationSecurityAlert  NotificationType = "security_alert"
+	NotificationBudgetWarning  NotificationType = "budget_warning"  // spending reached the warning share of a budget
+	NotificationBudgetExceeded NotificationType = "budget_exceeded" // spending went over a budget
+	NotificationCapWarning     NotificationType = "cap_warning"     // spending neared a card's bonus cap
+	NotificationFeeReminder    NotificationType = "fee_reminder"
+	NotificationAnnouncement   NotificationType = "announcement"   // an admin's Announcement
+	NotificationServiceNotice  NotificationType = "service_notice" // an admin's Announcement.ServiceNotice
+)
+
+// Category returns the NotificationCategory whose preferences decide how the type is delivered
+func (t NotificationType) Category() NotificationCategory {
+	switch t {
+	case NotificationSecurityAlert:
+		return SecurityAlertsCategory
+	case NotificationBudgetWarning, NotificationBudgetExceeded, NotificationCapWarning:
+		return BudgetAlertsCategory
+	case NotificationFeeReminder:
+		return FeeRemindersCategory
+	case NotificationServiceNotice:
+		return ServiceNoticesCategory
+	}
+	return ProductUpdatesCategory
+}
+
+// Notification is an entry in a user's notification centre. One is recorded for every notification sent,
+// InApp says whether the user chose to see it in the app or only through other channels.
+type Notification struct {
+	ID        string            `bson:"_id"`
+	UserID    string            `bson:"user_id"`
+	Type      NotificationType  `bson:"type"`
+	Title     string            `bson:"title"`
+	Body      string            `bson:"body"`
+	Data      map[string]string `bson:"data,omitempty"` // identifies what it is about, e.g. household_id
+	InApp     bool              `bson:"in_app"`
+	Channels  []string          `bson:"channels"` // channels it was delivered through
+	DedupKey  string            `bson:"dedup_key,omitempty"`
+	ReadAt    *time.Time        `bson:"read_at,omitempty"`
+	CreatedAt time.Time         `bson:"created_at"`
+}
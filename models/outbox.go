@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxKind is the kind of side-effect an OutboxEntry performs
+type OutboxKind string
+
+const (
+	OutboxEmail          OutboxKind = "email"
+	OutboxGenerateExport OutboxKind = "generate_export"
+	OutboxWebhook        OutboxKind = "webhook"
+	OutboxPush           OutboxKind = "push"
+)
+
+// OutboxStatus is the lifecycle state of an OutboxEntry
+type OutboxStatus string
+
+const (
+	OutboxPending    OutboxStatus = "pending"    // waiting for its next attempt
+	OutboxProcessing OutboxStatus = "processing" // leased by a worker
+	OutboxDone       OutboxStatus = "done"
+	OutboxDead       OutboxStatus = "dead" // gave up, kept for inspection and manual retry
+)
+
+// OutboxEntry is a side-effect recorded alongside the change that caused it and performed by a worker,
+// at least once, with retries. Exactly one of the kind specific fields is set.
+type OutboxEntry struct {
+	ID             string       `bson:"_id"`
+	Kind           OutboxKind   `bson:"kind"`
+	Status         OutboxStatus `bson:"status"`
+	Attempts       int          `bson:"attempts"`
+	LastError      string       `bson:"last_error,omitempty"`
+	AvailableAt    time.Time    `bson:"available_at"` // not attempted before this
+	LeaseOwner     string       `bson:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time   `bson:"lease_expires_at,omitempty"` // another worker may reclaim it after
+	CreatedAt      time.Time    `bson:"created_at"`
+	UpdatedAt      time.Time    `bson:"updated_at"`
+	CompletedAt    *time.Time   `bson:"completed_at,omitempty"`
+
+	Email             *OutboxEmailMessage `bson:"email,omitempty"`
+	ExportID          string              `bson:"export_id,omitempty"`
+	WebhookDeliveryID string              `bson:"webhook_delivery_id,omitempty"`
+	Push              *OutboxPushMessage  `bson:"push,omitempty"`
+}
+
+// OutboxEmailMessage is an email rendered when it was enqueued.
+// The bodies can hold codes and sign-in links, so they are removed once the email is delivered.
+type OutboxEmailMessage struct {
+	To       string `bson:"to"`
+	Template string `bson:"template"`
+	Subject  string `bson:"subject"`
+	Text     string `bson:"text,omitempty"`
+	HTML     string `bson:"html,omitempty"`
+}
+
+// OutboxPushMessage is a notification pushed to one of its user's devices
+type OutboxPushMessage struct {
+	SubscriptionID string `bson:"subscription_id"`
+	NotificationID string `bson:"notification_id"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user registered to sign in without a password
+type Passkey struct {
+	ID              []byte    `bson:"id"`
+	Name            string    `bson:"name"`
+	PublicKey       []byte    `bson:"public_key"`
+	AttestationType string    `bson:"attestation_type"`
+	Transports      []string  `bson:"transports"`
+	AAGUID          []byte    `bson:"aaguid"`
+	SignCount       uint32    `bson:"sign_count"`
+	BackupEligible  bool      `bson:"backup_eligible"`
+	BackupState     bool      `bson:"backup_state"`
+	CreatedAt       time.Time `bson:"created_at"`
+	LastUsedAt      time.Time `bson:"last_used_at"`
+}
+
+// PasskeyChallenge is an outstanding WebAuthn registration or login ceremony
+type PasskeyChallenge struct {
+	Ceremony  string    `bson:"ceremony"` // "registration" or "login"
+	Session   []byte    `bson:"session"`  // JSON encoded webauthn.SessionData
+	ExpiresAt time.Time `bson:"expires_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// PolicyDocument is a legal document users must accept to use the service
+type PolicyDocument string
+
+const (
+	TermsOfService PolicyDocument = "terms"
+	PrivacyPolicy  PolicyDocument = "privacy"
+)
+
+// PolicyDocuments are all documents that need the user's consent
+var PolicyDocuments = []PolicyDocument{TermsOfService, PrivacyPolicy}
+
+// PolicyVersion is a published version of a PolicyDocument, the latest published is the current one
+type PolicyVersion struct {
+	ID          string         `bson:"_id"`
+	Document    PolicyDocument `bson:"document"`
+	Version     string         `bson:"version"`
+	URL         string         `bson:"url"`
+	Summary     string         `bson:"summary,omitempty"` // what changed, shown when asking users to re-accept
+	PublishedBy string         `bson:"published_by"`
+	PublishedAt time.Time      `bson:"published_at"`
+}
+
+// Consent records a user accepting a version of a PolicyDocument
+type Consent struct {
+	Document   PolicyDocument `bson:"document"`
+	Version    string         `bson:"version"`
+	AcceptedAt time.Time      `bson:"accepted_at"`
+	IP         string         `bson:"ip"`
+	UserAgent  string         `bson:"user_agent"`
+}
+
+// HasConsented reports whether the user accepted version of document
+func (u *User) HasConsented(document PolicyDocument, version string) bool {
+	for _, consent := range u.Consents {
+		if consent.Document == document && consent.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingPolicies returns the current versions the user has not accepted yet
+func (u *User) PendingPolicies(current map[PolicyDocument]PolicyVersion) []PolicyVersion {
+	var pending []PolicyVersion
+	for _, document := range PolicyDocuments {
+		if version, ok := current[document]; ok && !u.HasConsented(document, version.Version) {
+			pending = append(pending, version)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"time"
+)
+
+// Preferences are a user's regional settings and defaults. Anything that depends on "today" or
+// "this week/month" for a user should go through Preferences rather than the server's local time.
+type Preferences struct {
+	HomeCurrency    string                  `bson:"home_currency"` // ISO 4217, e.g. SGD
+	Timezone        string                  `bson:"timezone"`      // IANA, e.g. Asia/Singapore
+	Locale          string                  `bson:"locale"`        // BCP 47, e.g. en-SG
+	FirstDayOfWeek  time.Weekday            `bson:"first_day_of_week"`
+	DefaultCardID   string                  `bson:"default_card_id,omitempty"`
+	DefaultCategory string                  `bson:"default_category,omitempty"`
+	Notifications   NotificationPreferences `bson:"notifications"`
+	Digests         DigestPreferences       `bson:"digests"`
+}
+
+// DigestPreferences are the spending digest emails a user opted in to
+type DigestPreferences struct {
+	Weekly  bool `bson:"weekly"`  // on FirstDayOfWeek, about the week before
+	Monthly bool `bson:"monthly"` // on the 1st, about the month before
+}
+
+// NotificationPreferences are the kinds of notifications a user opted in to, and how each is delivered
+type NotificationPreferences struct {
+	SecurityAlerts bool `bson:"security_alerts"`
+	BudgetAlerts   bool `bson:"budget_alerts"`
+	FeeReminders   bool `bson:"fee_reminders"`
+	ProductUpdates bool `bson:"product_updates"`
+
+	// Channels overrides DefaultNotificationChannels for the categories the user changed
+	Channels map[NotificationCategory]NotificationChannels `bson:"channels,omitempty"`
+}
+
+// NotificationChannels are the channels a category of notifications is delivered through
+type NotificationChannels struct {
+	InApp bool `bson:"in_app"`
+	Email bool `bson:"email"`
+	Push  bool `bson:"push"` // to every device with a PushSubscription
+}
+
+// DefaultNotificationChannels are the channels used for a category the user never chose channels for
+func DefaultNotificationChannels(category NotificationCategory) NotificationChannels {
+	switch category {
+	case SecurityAlertsCategory:
+		return NotificationChannels{InApp: true, Email: true, Push: true}
+	case FeeRemindersCategory:
+		return NotificationChannels{InApp: true, Email: true}
+	case ProductUpdatesCategory, ServiceNoticesCategory:
+		return NotificationChannels{InApp: true, Email: true}
+	}
+	return NotificationChannels{InApp: true, Push: true}
+}
+
+// Enabled reports whether the user opted in to notifications of category. Service notices are about cards
+// the user holds, so there is no opting out of them.
+func (n NotificationPreferences) Enabled(category NotificationCategory) bool {
+	switch category {
+	case SecurityAlertsCategory:
+		return n.SecurityAlerts
+	case BudgetAlertsCategory:
+		return n.BudgetAlerts
+	case FeeRemindersCategory:
+		return n.FeeReminders
+	case ProductUpdatesCategory:
+		return n.ProductUpdates
+	case ServiceNoticesCategory:
+		return true
+	}
+	return false
+}
+
+// ChannelsFor returns the channels notifications of category go out on, none if the user opted out
+func (n NotificationPreferences) ChannelsFor(category NotificationCategory) NotificationChannels {
+	if !n.Enabled(category) {
+		return NotificationChannels{}
+	}
+	if channels, ok := n.Channels[category]; ok {
+		return channels
+	}
+	return DefaultNotificationChannels(category)
+}
+
+// DefaultPreferences are used for users who never saved any preferences
+func DefaultPreferences() Preferences {
+	return Preferences{
+		HomeCurrency:   "SGD",
+		Timezone:       "Asia/Singapore",
+		Locale:         "en-SG",
+		FirstDayOfWeek: time.Monday,
+		Notifications: NotificationPreferences{
+			SecurityAlerts: true,
+			BudgetAlerts:   true,
+			FeeReminders:   true,
+			ProductUpdates: false,
+		},
+	}
+}
+
+// EffectivePreferences returns the user's saved preferences, or the defaults if they never saved any
+func (u *User) EffectivePreferences() Preferences {
+	if u.Preferences == nil {
+		return DefaultPreferences()
+	}
+	return *u.Preferences
+}
+
+// Location returns the user's timezone, falling back to UTC if it cannot be loaded
+func (p Preferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Now returns the current time in the user's timezone
+func (p Preferences) Now() time.Time {
+	return time.Now().In(p.Location())
+}
+
+// StartOfDay returns midnight of t's day in the user's timezone
+func (p Preferences) StartOfDay(t time.Time) time.Time {
+	t = t.In(p.Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns midnight of the user's first day of the week containing t
+func (p Preferences) StartOfWeek(t time.Time) time.Time {
+	day := p.StartOfDay(t)
+	offset := (int(day.Weekday()) - int(p.FirstDayOfWeek) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// StartOfMonth returns midnight of the first of t's month in the user's timezone
+func (p Preferences) StartOfMonth(t time.Time) time.Time {
+	t = t.In(p.Location())
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Period is a calendar period that budgets, reports and reward cycles are counted over. Reward cycles, over
+// which Miles.BonusMultiplierCap applies, are Monthly.
+type Period string
+
+const (
+	Weekly  Period = "weekly"
+	Monthly Period = "monthly"
+)
+
+// PeriodBounds returns the [start, end) of the period containing t, in the user's timezone
+func (p Preferences) PeriodBounds(period Period, t time.Time) (time.Time, time.Time) {
+	if period == Weekly {
+		start := p.StartOfWeek(t)
+		return start, start.AddDate(0, 0, 7)
+	}
+	start := p.StartOfMonth(t)
+	return start, start.AddDate(0, 1, 0)
+}
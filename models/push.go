@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PushSubscription is a browser or installed PWA that receives Web Push notifications for a user.
+// Its fields come from the browser's PushSubscription, the keys encrypt every push, see RFC 8291.
+type PushSubscription struct {
+	ID         string     `bson:"_id"`
+	UserID     string     `bson:"user_id"`
+	Endpoint   string     `bson:"endpoint"`  // the push service URL, unique per device
+	P256dh     string     `bson:"p256dh"`    // the device's P-256 public key, base64url
+	Auth       string     `bson:"auth"`      // the device's authentication secret, base64url
+	VAPIDKey   string     `bson:"vapid_key"` // config.VAPIDPublicKey when it subscribed
+	UserAgent  string     `bson:"user_agent,omitempty"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty"` // set by some push services, MongoDB deletes it after
+	CreatedAt  time.Time  `bson:"created_at"`
+	UpdatedAt  time.Time  `bson:"updated_at"`
+	LastPushAt *time.Time `bson:"last_push_at,omitempty"`
+}
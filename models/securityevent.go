@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// SecurityEventType is the kind of account activity recorded in a user's security history
+type SecurityEventType string
+
+const (
+	NewSignIn          SecurityEventType = "new_sign_in"
+	PasswordChanged    SecurityEventType = "password_changed"
+	EmailChanged       SecurityEventType = "email_changed"
+	UsernameChanged    SecurityEventType = "username_changed"
+	TwoFactorChanged   SecurityEventType = "two_factor_changed" // a passkey was added or removed
+	PasskeyCloneWarned SecurityEventType = "passkey_clone_warning"
+)
+
+// String returns a human-readable description of the SecurityEventType
+func (t SecurityEventType) String() string {
+	switch t {
+	case NewSignIn:
+		return "New sign-in from an unrecognised device or location"
+	case PasswordChanged:
+		return "Password changed"
+	case EmailChanged:
+		return "Email address changed"
+	case UsernameChanged:
+		return "Username changed"
+	case TwoFactorChanged:
+		return "Two-factor authentication settings changed"
+	case PasskeyCloneWarned:
+		return "Passkey sign-in blocked, the passkey may have been copied"
+	}
+
+	return "Unknown"
+}
+
+// SecurityEvent is an entry in a user's security event history
+type SecurityEvent struct {
+	ID        string            `bson:"_id"`
+	UserID    string            `bson:"user_id"`
+	Type      SecurityEventType `bson:"type"`
+	IP        string            `bson:"ip"`
+	UserAgent string            `bson:"user_agent"`
+	Metadata  map[string]string `bson:"metadata,omitempty"`
+	CreatedAt time.Time         `bson:"created_at"`
+}
+
+// KnownDevice is a device and network a user has signed in from before
+type KnownDevice struct {
+	Fingerprint string    `bson:"fingerprint" json:"fingerprint"` // hash of the user agent
+	IPRange     string    `bson:"ip_range" json:"ipRange"`        // /24 for IPv4, /48 for IPv6
+	UserAgent   string    `bson:"user_agent" json:"userAgent"`
+	FirstSeenAt time.Time `bson:"first_seen_at" json:"firstSeenAt"`
+	LastSeenAt  time.Time `bson:"last_seen_at" json:"lastSeenAt"`
+}
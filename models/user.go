@@ -4,23 +4,85 @@ import (
 	"time"
 )
 
+// RoleAdmin is the Role of support staff allowed to use the /admin user management API
+const RoleAdmin = "admin"
+
 // User represents a user onboarded or undergoing onboarding
 type User struct {
 	ID             string    `bson:"_id"`
 	FirstName      string    `bson:"first_name"`
 	LastName       string    `bson:"last_name"`
-	Password       string    `bson:"password"`
+	Password       string    `bson:"password" json:"-"`
+	Username       string    `bson:"username,omitempty"`                // optional handle, as the user typed it
+	UsernameLower  string    `bson:"username_lower,omitempty" json:"-"` // common.NormalizeUsername, unique
 	CreatedAt      time.Time `bson:"created_at"`
 	UpdatedAt      time.Time `bson:"updated_at"`
-	ProfilePicture string    `bson:"profile_picture"`
+	ProfilePicture string    `bson:"profile_picture"` // storage.BlobStore key
+
+	ProfilePictureThumbnail string `bson:"profile_picture_thumbnail,omitempty"`
+
+	Role string `bson:"role,omitempty"` // empty for regular users
+
+	// DisabledAt is set when an admin disables the account, which blocks sign-in and existing sessions
+	DisabledAt     *time.Time `bson:"disabled_at,omitempty"`
+	DisabledReason string     `bson:"disabled_reason,omitempty"`
+
+	// DeletedAt is set when the user deletes their account, it can be restored until PurgeAfter
+	DeletedAt  *time.Time `bson:"deleted_at,omitempty"`
+	PurgeAfter *time.Time `bson:"purge_after,omitempty"`
+
+	// SessionsValidAfter ends every session issued before it, it is set when the password is reset. The iat
+	// claim only has whole seconds, so sessions issued in the same second as it end as well.
+	SessionsValidAfter *time.Time `bson:"sessions_valid_after,omitempty"`
+
+	// PurgingAt is set when a purge of the account starts, it blocks sign-in until the purge removes the user
+	PurgingAt *time.Time `bson:"purging_at,omitempty"`
 
 	Email              string    `bson:"email"`
 	Verified           bool      `bson:"verified"`
-	VerificationCode   string    `bson:"verification_code"`
+	VerificationCode   string    `bson:"verification_code" json:"-"`
 	VerificationSentAt time.Time `bson:"verification_sent_at"`
 
+	// VerificationReminderSentAt is set once the unverified account reminder has gone out
+	VerificationReminderSentAt *time.Time `bson:"verification_reminder_sent_at,omitempty"`
+
+	MagicLinkID        string    `bson:"magic_link_id,omitempty" json:"-"` // jti of the outstanding sign-in link
+	MagicLinkExpiresAt time.Time `bson:"magic_link_expires_at,omitempty"`
+
+	PasswordResetID        string    `bson:"password_reset_id,omitempty" json:"-"` // jti of the outstanding reset link
+	PasswordResetExpiresAt time.Time `bson:"password_reset_expires_at,omitempty"`
+
+	// PendingEmail is the address the user asked to change to, until they confirm it with PendingEmailCode
+	PendingEmail          string    `bson:"pending_email,omitempty"`
+	PendingEmailCode      string    `bson:"pending_email_code,omitempty" json:"-"`
+	PendingEmailExpiresAt time.Time `bson:"pending_email_expires_at,omitempty"`
+
+	// AccountCode confirms deleting or restoring the account instead of the password, which passkey-only
+	// accounts do not have
+	AccountCode          string    `bson:"account_code,omitempty" json:"-"`
+	AccountCodeExpiresAt time.Time `bson:"account_code_expires_at,omitempty"`
+
+	Passkeys         []Passkey         `bson:"passkeys"`
+	PasskeyChallenge *PasskeyChallenge `bson:"passkey_challenge,omitempty" json:"-"`
+	KnownDevices     []KnownDevice     `bson:"known_devices"`
+
+	// Consents is every policy version the user accepted, oldest first
+	Consents []Consent `bson:"consents"`
+
+	// Preferences is nil until the user saves any, see EffectivePreferences
+	Preferences *Preferences `bson:"preferences,omitempty"`
+
+	// DigestsSent records the last period each digest was sent for, see digest.SendDue
+	DigestsSent DigestsSent `bson:"digests_sent"`
+
 	Cards        []Card        `bson:"cards"`
 	Budgets      []Budget      `bson:"budgets"`
 	Transactions []Transaction `bson:"transactions"`
 	Savings      []Savings     `bson:"savings"`
 }
+
+// DigestsSent are the periods the last digests were sent for, empty if never sent
+type DigestsSent struct {
+	Weekly  string `bson:"weekly,omitempty"`  // first day of the week, 2006-01-02
+	Monthly string `bson:"monthly,omitempty"` // 2006-01
+}
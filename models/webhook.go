@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEvent is a domain event webhooks can subscribe to
+type WebhookEvent string
+
+const (
+	WebhookUserVerified       WebhookEvent = "user.verified"
+	WebhookTransactionCreated WebhookEvent = "transaction.created"
+	WebhookBudgetExceeded     WebhookEvent = "budget.exceeded"
+	WebhookCardAdded          WebhookEvent = "card.added"
+)
+
+// WebhookEvents are all events webhooks can subscribe to
+var WebhookEvents = []WebhookEvent{
+	WebhookUserVerified, WebhookTransactionCreated, WebhookBudgetExceeded, WebhookCardAdded,
+}
+
+// ParseWebhookEvent returns the WebhookEvent with the given name
+func ParseWebhookEvent(name string) (WebhookEvent, bool) {
+	for _, event := range WebhookEvents {
+		if string(event) == name {
+			return event, true
+		}
+	}
+	return "", false
+}
+
+// Webhook is an endpoint that is sent the events it subscribed to. A user's webhook receives the events about
+// them, a Global webhook is registered by an admin and receives the events about every user.
+type Webhook struct {
+	ID                  string         `bson:"_id"`
+	OwnerID             string         `bson:"owner_id"` // the user or admin who registered it
+	Global              bool           `bson:"global"`
+	URL                 string         `bson:"url"`
+	Description         string         `bson:"description,omitempty"`
+	Events              []WebhookEvent `bson:"events"`
+	Secret              string         `bson:"secret"` // signs deliveries, see webhook.Sign
+	Enabled             bool           `bson:"enabled"`
+	ConsecutiveFailures int            `bson:"consecutive_failures"` // failed attempts since the last success
+	DisabledReason      string         `bson:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time     `bson:"disabled_at,omitempty"`
+	CreatedAt           time.Time      `bson:"created_at"`
+	UpdatedAt           time.Time      `bson:"updated_at"`
+}
+
+// Subscribed reports whether the webhook receives event
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of a WebhookDelivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending" // waiting for its first attempt or a retry
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed" // out of retries, or the webhook was disabled
+)
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook. Redelivering sends the same Payload again
+// as a new delivery.
+type WebhookDelivery struct {
+	ID           string                `bson:"_id"`
+	WebhookID    string                `bson:"webhook_id"`
+	EventID      string                `bson:"event_id"` // shared by the deliveries of the same event
+	Event        WebhookEvent          `bson:"event"`
+	Payload      string                `bson:"payload"` // the JSON request body
+	Status       WebhookDeliveryStatus `bson:"status"`
+	Attempts     []WebhookAttempt      `bson:"attempts"`
+	RedeliveryOf string                `bson:"redelivery_of,omitempty"`
+	CreatedAt    time.Time             `bson:"created_at"`
+	CompletedAt  *time.Time            `bson:"completed_at,omitempty"`
+}
+
+// WebhookAttempt is one request of a WebhookDelivery
+type WebhookAttempt struct {
+	At         time.Time     `bson:"at"`
+	StatusCode int           `bson:"status_code,omitempty"` // zero if no response was received
+	Response   string        `bson:"response,omitempty"`    // the start of the response body
+	Error      string        `bson:"error,omitempty"`
+	Duration   time.Duration `bson:"duration"`
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+// Channel names recorded in models.Notification.Channels
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// Message is an event to notify a user about. Title and Body are shown in the notification centre and
+// pushed to the user's devices, the email is rendered from Template with TemplateData.
+type Message struct {
+	Type         models.NotificationType
+	Title        string
+	Body         string
+	Data         map[string]string
+	Template     string
+	TemplateData interface{}
+
+	// DedupKey makes notifying a user about the same event more than once a no-op, leave empty to always notify
+	DedupKey string
+}
+
+// Notifier records notifications in each user's notification centre and delivers them through the
+// channels the user chose for the notification's category
+type Notifier struct {
+	notifications *mongo.Collection
+	users         *mongo.Collection
+	subscriptions *mongo.Collection
+	outbox        *outbox.Outbox
+}
+
+// New returns a Notifier recording notifications in the given collection, and emailing and pushing to the
+// devices with push subscriptions through box
+func New(notifications, users, subscriptions *mongo.Collection, box *outbox.Outbox) *Notifier {
+	return &Notifier{notifications: notifications, users: users, subscriptions: subscriptions, outbox: box}
+}
+
+// Notify notifies user about msg, unless they opted out of its category or were already notified about it.
+// A notification is recorded even when the user turned off in-app delivery, so DedupKey still applies.
+func (n *Notifier) Notify(ctx context.Context, user models.User, msg Message) error {
+	channels := user.EffectivePreferences().Notifications.ChannelsFor(msg.Type.Category())
+	if !channels.InApp && !channels.Email && !channels.Push {
+		return nil
+	}
+
+	var subscriptions []models.PushSubscription
+	if channels.Push {
+		cursor, err := n.subscriptions.Find(ctx, bson.M{"user_id": user.ID},
+			options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		if err = cursor.All(ctx, &subscriptions); err != nil {
+			return err
+		}
+	}
+
+	notification := models.Notification{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Type:      msg.Type,
+		Title:     msg.Title,
+		Body:      msg.Body,
+		Data:      msg.Data,
+		InApp:     channels.InApp,
+		Channels:  []string{},
+		DedupKey:  msg.DedupKey,
+		CreatedAt: time.Now(),
+	}
+	if channels.InApp {
+		notification.Channels = append(notification.Channels, ChannelInApp)
+	}
+	if channels.Email && msg.Template != "" {
+		notification.Channels = append(notification.Channels, ChannelEmail)
+	}
+	if len(subscriptions) > 0 {
+		notification.Channels = append(notification.Channels, ChannelPush)
+	}
+
+	_, err := n.notifications.InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if channels.Email && msg.Template != "" {
+		err = n.outbox.EnqueueEmail(ctx, user.Email, user.EffectivePreferences().Locale, msg.Template, msg.TemplateData)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, subscription := range subscriptions {
+		err = n.outbox.Enqueue(ctx, models.OutboxEntry{Kind: models.OutboxPush, Push: &models.OutboxPushMessage{
+			SubscriptionID: subscription.ID,
+			NotificationID: notification.ID,
+		}})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyUsers notifies each of the users with the given IDs about msg, see Notify.
+// Every user is attempted, the errors of those that failed are returned together.
+func (n *Notifier) NotifyUsers(ctx context.Context, userIDs []string, msg Message) error {
+	cursor, err := n.users.Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, user := range users {
+		if err = n.Notify(ctx, user, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+)
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the entry that failed with it is dead-lettered straight away instead of retried
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// EmailHandler delivers models.OutboxEmail entries through mailer
+func EmailHandler(mailer email.Mailer) Handler {
+	return func(ctx context.Context, entry models.OutboxEntry) error {
+		if entry.Email == nil {
+			return Permanent(errors.New("email entry has no message"))
+		}
+		err := mailer.Send(ctx, email.Message{
+			To:      entry.Email.To,
+			Subject: entry.Email.Subject,
+			Text:    entry.Email.Text,
+			HTML:    entry.Email.HTML,
+		})
+		if errors.Is(err, email.ErrInvalidRecipient) {
+			return Permanent(err)
+		}
+		return err
+	}
+}
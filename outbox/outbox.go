@@ -0,0 +1,255 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+)
+
+// leaseMargin is added to a handler's timeout for its lease, so a slow but live worker keeps its entry
+const leaseMargin = time.Minute
+
+// Handler performs the side-effect of an entry. Returning an error retries the entry with backoff,
+// unless the error is Permanent. Handlers must be idempotent: an entry may be performed more than once.
+type Handler func(ctx context.Context, entry models.OutboxEntry) error
+
+type handler struct {
+	fn      Handler
+	timeout time.Duration
+}
+
+// Outbox records side-effects in MongoDB and performs them with registered handlers. Any number of
+// instances can process the same collection: entries are claimed with leases that expire if a worker dies.
+type Outbox struct {
+	entries  *mongo.Collection
+	owner    string
+	handlers map[models.OutboxKind]handler
+}
+
+// New returns an Outbox storing entries in the given collection
+func New(entries *mongo.Collection) *Outbox {
+	host, _ := os.Hostname()
+	return &Outbox{
+		entries:  entries,
+		owner:    host + "-" + uuid.New().String()[:8],
+		handlers: make(map[models.OutboxKind]handler),
+	}
+}
+
+// Handle registers fn to perform entries of kind, each attempt running for at most timeout
+func (o *Outbox) Handle(kind models.OutboxKind, timeout time.Duration, fn Handler) {
+	o.handlers[kind] = handler{fn: fn, timeout: timeout}
+}
+
+// Enqueue records entry to be performed as soon as a worker picks it up.
+// Callers enqueue right after the change that causes the side-effect and treat failing to do so as
+// failing the change itself.
+func (o *Outbox) Enqueue(ctx context.Context, entry models.OutboxEntry) error {
+	now := time.Now()
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	entry.Status = models.OutboxPending
+	entry.Attempts = 0
+	entry.AvailableAt = now
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	_, err := o.entries.InsertOne(ctx, entry)
+	return err
+}
+
+// EnqueueEmail renders the named template in locale and enqueues it for delivery to the recipient.
+// Rendering happens now, so template errors are returned to the caller instead of being retried.
+func (o *Outbox) EnqueueEmail(ctx context.Context, to, locale, template string, data interface{}) error {
+	msg, err := email.Render(template, locale, data)
+	if err != nil {
+		return err
+	}
+	return o.Enqueue(ctx, models.OutboxEntry{
+		Kind: models.OutboxEmail,
+		Email: &models.OutboxEmailMessage{
+			To:       to,
+			Template: template,
+			Subject:  msg.Subject,
+			Text:     msg.Text,
+			HTML:     msg.HTML,
+		},
+	})
+}
+
+// Process performs up to config.OutboxBatchSize entries of kind that are due, one at a time.
+// Schedule it with jobs.Every, once per kind so a slow kind does not hold up the others.
+func (o *Outbox) Process(ctx context.Context, kind models.OutboxKind) {
+	log := slog.With("func", "Outbox.Process", "kind", kind)
+
+	h, ok := o.handlers[kind]
+	if !ok {
+		log.Error("No handler registered for outbox kind")
+		return
+	}
+	for i := 0; i < config.OutboxBatchSize && ctx.Err() == nil; i++ {
+		entry, err := o.claim(ctx, kind, h.timeout+leaseMargin)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Error("Failed to claim outbox entry", "err", err)
+			return
+		}
+		o.perform(ctx, log.With("entryID", entry.ID, "attempt", entry.Attempts), entry, h)
+	}
+}
+
+// claim leases the oldest due entry of kind: a pending entry whose backoff has passed, or one whose
+// previous worker's lease expired. Claiming counts as an attempt, so entries that crash workers still die:
+// due entries that used up config.OutboxMaxAttempts are dead-lettered instead of leased.
+func (o *Outbox) claim(ctx context.Context, kind models.OutboxKind, lease time.Duration) (models.OutboxEntry, error) {
+	now := time.Now()
+	due := bson.M{
+		"kind": kind,
+		"$or": []bson.M{
+			{"status": models.OutboxPending, "available_at": bson.M{"$lte": now}},
+			{"status": models.OutboxProcessing, "lease_expires_at": bson.M{"$lte": now}},
+		},
+	}
+
+	exhausted := bson.M{"$and": []bson.M{due, {"attempts": bson.M{"$gte": config.OutboxMaxAttempts}}}}
+	dead, err := o.entries.UpdateMany(ctx, exhausted, bson.M{
+		"$set": bson.M{
+			"status":       models.OutboxDead,
+			"last_error":   "no attempts left, the last worker did not finish",
+			"completed_at": now,
+			"updated_at":   now,
+		},
+		"$unset": bson.M{"lease_owner": "", "lease_expires_at": ""},
+	})
+	if err != nil {
+		return models.OutboxEntry{}, err
+	}
+	if dead.ModifiedCount > 0 {
+		slog.Error("Outbox entries dead-lettered without a final result", "kind", kind, "count", dead.ModifiedCount)
+	}
+
+	filter := bson.M{"$and": []bson.M{due, {"attempts": bson.M{"$lt": config.OutboxMaxAttempts}}}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           models.OutboxProcessing,
+			"lease_owner":      o.owner,
+			"lease_expires_at": now.Add(lease),
+			"updated_at":       now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "available_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var entry models.OutboxEntry
+	err = o.entries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	return entry, err
+}
+
+func (o *Outbox) perform(ctx context.Context, log *slog.Logger, entry models.OutboxEntry, h handler) {
+	runCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	err := run(runCtx, entry, h.fn)
+	if err == nil {
+		o.complete(ctx, log, entry)
+		return
+	}
+	o.fail(ctx, log, entry, err)
+}
+
+// run calls fn, turning a panic into an error so the entry is retried rather than left leased
+func run(ctx context.Context, entry models.OutboxEntry, fn Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return fn(ctx, entry)
+}
+
+func (o *Outbox) complete(ctx context.Context, log *slog.Logger, entry models.OutboxEntry) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"status": models.OutboxDone, "completed_at": now, "updated_at": now},
+		"$unset": bson.M{
+			"lease_owner":      "",
+			"lease_expires_at": "",
+			"last_error":       "",
+			"email.text":       "",
+			"email.html":       "",
+		},
+	}
+	if err := o.release(ctx, entry, update); err != nil {
+		log.Error("Failed to mark outbox entry done, it will be performed again", "err", err)
+		return
+	}
+	log.Info("Performed outbox entry")
+}
+
+func (o *Outbox) fail(ctx context.Context, log *slog.Logger, entry models.OutboxEntry, cause error) {
+	var (
+		now       = time.Now()
+		permanent = IsPermanent(cause)
+		set       = bson.M{"last_error": cause.Error(), "updated_at": now}
+	)
+	if permanent || entry.Attempts >= config.OutboxMaxAttempts {
+		set["status"] = models.OutboxDead
+		set["completed_at"] = now
+	} else {
+		set["status"] = models.OutboxPending
+		set["available_at"] = now.Add(backoff(entry.Attempts))
+	}
+
+	update := bson.M{"$set": set, "$unset": bson.M{"lease_owner": "", "lease_expires_at": ""}}
+	if err := o.release(ctx, entry, update); err != nil {
+		log.Error("Failed to record outbox entry failure", "err", err, "cause", cause)
+		return
+	}
+	if set["status"] == models.OutboxDead {
+		log.Error("Outbox entry dead-lettered", "err", cause, "permanent", permanent)
+		return
+	}
+	log.Warn("Outbox entry failed, will retry", "err", cause, "retryAt", set["available_at"])
+}
+
+// release applies update to an entry this worker still holds the lease on. A worker that overran its lease
+// has lost the entry to another worker and leaves it alone.
+func (o *Outbox) release(ctx context.Context, entry models.OutboxEntry, update bson.M) error {
+	result, err := o.entries.UpdateOne(ctx,
+		bson.M{"_id": entry.ID, "status": models.OutboxProcessing, "lease_owner": o.owner}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("lease lost to another worker")
+	}
+	return nil
+}
+
+// backoff is the delay before the attempt after the given one: config.OutboxBackoffBase doubling per attempt,
+// capped at config.OutboxBackoffMax, with up to 20% jitter so entries failing together spread out
+func backoff(attempts int) time.Duration {
+	delay := config.OutboxBackoffBase
+	for i := 1; i < attempts && delay < config.OutboxBackoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, config.OutboxBackoffMax)
+	return delay - time.Duration(rand.Int63n(int64(delay)/5+1))
+}
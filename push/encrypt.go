@@ -0,0 +1,140 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// recordSize is the single aes128gcm record every push is sent in
+	recordSize = 4096
+
+	// headerSize is the aes128gcm header: salt, record size, key ID length and the sender's public key
+	headerSize = saltSize + 4 + 1 + publicKeySize
+
+	// MaxPayload is the largest payload that fits a push: push services accept 4096 bytes of body,
+	// which also holds the header, the GCM tag and the padding delimiter
+	MaxPayload = recordSize - headerSize - tagSize - 1
+
+	saltSize      = 16
+	tagSize       = 16
+	publicKeySize = 65 // uncompressed P-256 point
+	authSize      = 16
+
+	// lastRecord pads the only record, see RFC 8188 section 2
+	lastRecord = 0x02
+)
+
+var (
+	keyInfoPrefix = []byte("WebPush: info\x00")
+	cekInfo       = []byte("Content-Encoding: aes128gcm\x00")
+	nonceInfo     = []byte("Content-Encoding: nonce\x00")
+)
+
+// ErrInvalidKeys is returned for a subscription whose p256dh or auth key is malformed
+var ErrInvalidKeys = errors.New("push subscription keys are invalid")
+
+// Encrypt encrypts payload for the device with the p256dh public key and auth secret of its subscription,
+// as RFC 8291 describes: an ephemeral ECDH key agreement with the device's key, combined with the auth
+// secret into an aes128gcm content encoding key, see RFC 8188. It returns the request body of the push.
+func Encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, errors.New("push payload is too large")
+	}
+	uaPublic, authSecret, err := ParseKeys(p256dh, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(payload, uaPublic, authSecret, asPrivate, salt)
+}
+
+// encrypt is Encrypt with the ephemeral key and salt given
+func encrypt(payload []byte, uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey,
+	salt []byte) ([]byte, error) {
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	keyInfo := append(append(append([]byte{}, keyInfoPrefix...), uaPublic.Bytes()...), asPublic...)
+	ikm, err := derive(authSecret, sharedSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := derive(salt, ikm, cekInfo, 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := derive(salt, ikm, nonceInfo, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 0, headerSize+len(payload)+1+tagSize)
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, publicKeySize)
+	body = append(body, asPublic...)
+	record := append(append(make([]byte, 0, len(payload)+1), payload...), lastRecord)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
+
+// ParseKeys decodes the p256dh public key and auth secret of a subscription, as browsers encode them
+func ParseKeys(p256dh, auth string) (*ecdh.PublicKey, []byte, error) {
+	publicBytes, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, nil, ErrInvalidKeys
+	}
+	public, err := ecdh.P256().NewPublicKey(publicBytes)
+	if err != nil {
+		return nil, nil, ErrInvalidKeys
+	}
+	secret, err := decodeBase64URL(auth)
+	if err != nil || len(secret) != authSize {
+		return nil, nil, ErrInvalidKeys
+	}
+	return public, secret, nil
+}
+
+// derive is HKDF-SHA256 of secret with salt and info, see RFC 5869
+func derive(salt, secret, info []byte, length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// decodeBase64URL decodes base64url with or without padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
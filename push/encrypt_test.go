@@ -0,0 +1,55 @@
+package push
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"testing"
+)
+
+// TestEncryptRFC8291 checks the example of RFC 8291 Appendix A
+func TestEncryptRFC8291(t *testing.T) {
+	var (
+		plaintext = "When I grow up, I want to be a watermelon"
+		asPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+		uaPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+		auth      = "BTBZMqHH6r4Tts7J_aSIgg"
+		salt      = "DGv6ra1nlYgDCS1FRnbzlw"
+		want      = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6Tlz" +
+			"AC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	)
+
+	public, secret, err := ParseKeys(uaPublic, auth)
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	private, err := ecdh.P256().NewPrivateKey(mustDecode(t, asPrivate))
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+
+	body, err := encrypt([]byte(plaintext), public, secret, private, mustDecode(t, salt))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !bytes.Equal(body, mustDecode(t, want)) {
+		t.Errorf("body = %x, want %x", body, mustDecode(t, want))
+	}
+}
+
+func TestEncryptRejectsOversizedPayloads(t *testing.T) {
+	_, err := Encrypt(make([]byte, MaxPayload+1),
+		"BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		"BTBZMqHH6r4Tts7J_aSIgg")
+	if err == nil {
+		t.Error("Encrypt accepted a payload larger than MaxPayload")
+	}
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := decodeBase64URL(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
@@ -0,0 +1,233 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+const (
+	// maxResponseExcerpt is how much of a push service's error response is kept in the outbox
+	maxResponseExcerpt = 256
+
+	// bookkeepingTime is left to the DeliveryHandler after its request times out
+	bookkeepingTime = 10 * time.Second
+)
+
+// Urgency tells push services how soon a device should wake up for a push, see RFC 8030 section 5.3
+type Urgency string
+
+const (
+	UrgencyVeryLow Urgency = "very-low"
+	UrgencyLow     Urgency = "low"
+	UrgencyNormal  Urgency = "normal"
+	UrgencyHigh    Urgency = "high"
+)
+
+// UrgencyOf is the urgency notifications of type t are pushed with
+func UrgencyOf(t models.NotificationType) Urgency {
+	switch t {
+	case models.NotificationSecurityAlert:
+		return UrgencyHigh
+	case models.NotificationBudgetWarning, models.NotificationCapWarning, models.NotificationFeeReminder:
+		return UrgencyLow
+	}
+	return UrgencyNormal
+}
+
+// Payload is the JSON a device's service worker receives. It is the notification as the notification
+// centre lists it, the worker can mark it read with its ID.
+type Payload struct {
+	ID        string                  `json:"id"`
+	Type      models.NotificationType `json:"type"`
+	Title     string                  `json:"title"`
+	Body      string                  `json:"body,omitempty"`
+	Data      map[string]string       `json:"data,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+}
+
+// newPayload encodes notification, leaving out its body and data if they do not fit a push.
+// The worker can still fetch those from the notification centre.
+func newPayload(notification models.Notification) ([]byte, error) {
+	payload := Payload{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		CreatedAt: notification.CreatedAt,
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil || len(encoded) <= MaxPayload {
+		return encoded, err
+	}
+	payload.Body, payload.Data = "", nil
+	return json.Marshal(payload)
+}
+
+// HandlerTimeout is the most the DeliveryHandler may run for
+func HandlerTimeout() time.Duration {
+	return config.PushTimeout + bookkeepingTime
+}
+
+// ErrInsecureEndpoint is returned for push endpoints that are not https, see config.PushAllowInsecure
+var ErrInsecureEndpoint = errors.New("push endpoint must use https")
+
+// ValidateEndpoint checks raw is an absolute https URL, or http if config.PushAllowInsecure
+func ValidateEndpoint(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("push endpoint must be absolute")
+	}
+	if u.Scheme == "https" || (u.Scheme == "http" && config.PushAllowInsecure) {
+		return nil
+	}
+	return ErrInsecureEndpoint
+}
+
+// NewClient returns the client pushes are sent with. Endpoints come from browsers, so like webhooks it does not
+// follow redirects and refuses private network addresses, unless config.PushAllowInsecure.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: config.PushTimeout, Control: controlDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.PushTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func controlDial(network, address string, conn syscall.RawConn) error {
+	if config.PushAllowInsecure {
+		return nil
+	}
+	return common.RefusePrivateAddresses(network, address, conn)
+}
+
+// DeliveryHandler pushes models.OutboxPush entries to their device with client. Subscriptions the push service
+// reports gone, and those made with a previous VAPID key, are deleted. A push that is still undelivered
+// config.PushTTL after its notification is dropped, as is a notification the user deleted meanwhile.
+func DeliveryHandler(subscriptions, notifications *mongo.Collection, client *http.Client) outbox.Handler {
+	return func(ctx context.Context, entry models.OutboxEntry) error {
+		if entry.Push == nil {
+			return outbox.Permanent(errors.New("push entry has no message"))
+		}
+
+		var subscription models.PushSubscription
+		err := subscriptions.FindOne(ctx, bson.M{"_id": entry.Push.SubscriptionID}).Decode(&subscription)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return outbox.Permanent(errors.New("push subscription no longer exists"))
+		}
+		if err != nil {
+			return err
+		}
+		if subscription.VAPIDKey != config.VAPIDPublicKey {
+			return expire(ctx, subscriptions, subscription, errors.New("subscribed with a previous VAPID key"))
+		}
+
+		var notification models.Notification
+		err = notifications.FindOne(ctx, bson.M{"_id": entry.Push.NotificationID}).Decode(&notification)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return outbox.Permanent(errors.New("notification no longer exists"))
+		}
+		if err != nil {
+			return err
+		}
+		ttl := config.PushTTL - time.Since(notification.CreatedAt)
+		if ttl < time.Second {
+			return outbox.Permanent(errors.New("notification expired before it could be pushed"))
+		}
+
+		payload, err := newPayload(notification)
+		if err != nil {
+			return outbox.Permanent(err)
+		}
+		body, err := Encrypt(payload, subscription.P256dh, subscription.Auth)
+		if errors.Is(err, ErrInvalidKeys) {
+			return expire(ctx, subscriptions, subscription, err)
+		}
+		if err != nil {
+			return err
+		}
+		return send(ctx, client, subscriptions, subscription, body, ttl, UrgencyOf(notification.Type))
+	}
+}
+
+// subscriptionStore is the part of the push subscriptions collection that send and expire write to
+type subscriptionStore interface {
+	UpdateOne(ctx context.Context, filter interface{}, update interface{},
+		opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
+// send posts the encrypted body to the subscription's push service
+func send(ctx context.Context, client *http.Client, subscriptions subscriptionStore,
+	subscription models.PushSubscription, body []byte, ttl time.Duration, urgency Urgency) error {
+	now := time.Now()
+	authorization, err := vapidAuthorization(subscription.Endpoint, now)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return outbox.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", string(urgency))
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		_, err = subscriptions.UpdateOne(ctx, bson.M{"_id": subscription.ID},
+			bson.M{"$set": bson.M{"last_push_at": now}})
+		return err
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return expire(ctx, subscriptions, subscription,
+			fmt.Errorf("push service responded %d, the subscription expired", resp.StatusCode))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("push service responded %d: %s", resp.StatusCode, excerpt)
+	}
+	// a malformed push, an oversized payload or a rejected VAPID token, which trying again will not fix
+	return outbox.Permanent(fmt.Errorf("push service responded %d: %s", resp.StatusCode, excerpt))
+}
+
+// expire deletes a subscription that can no longer be pushed to and stops the outbox retrying, returning cause
+func expire(ctx context.Context, subscriptions subscriptionStore, subscription models.PushSubscription,
+	cause error) error {
+	_, err := subscriptions.DeleteOne(ctx, bson.M{"_id": subscription.ID, "endpoint": subscription.Endpoint})
+	if err != nil {
+		return err
+	}
+	return outbox.Permanent(cause)
+}
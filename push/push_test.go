@@ -0,0 +1,150 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+// fakeSubscriptions records the writes send makes to the push subscriptions collection
+type fakeSubscriptions struct {
+	updated []interface{}
+	deleted []interface{}
+}
+
+func (f *fakeSubscriptions) UpdateOne(_ context.Context, filter interface{}, _ interface{},
+	_ ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	f.updated = append(f.updated, filter)
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+func (f *fakeSubscriptions) DeleteOne(_ context.Context, filter interface{},
+	_ ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	f.deleted = append(f.deleted, filter)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func useTestVAPIDKey(t *testing.T) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := key.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.VAPIDKey = key
+	config.VAPIDPublicKey = base64.RawURLEncoding.EncodeToString(public.Bytes())
+	config.VAPIDSubject = "mailto:push@example.com"
+}
+
+func TestSendHeaders(t *testing.T) {
+	useTestVAPIDKey(t)
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var (
+		store        = &fakeSubscriptions{}
+		subscription = models.PushSubscription{ID: "subscription-1", Endpoint: server.URL + "/push/abc"}
+	)
+	err := send(context.Background(), server.Client(), store, subscription, []byte("body"), 90*time.Second,
+		UrgencyHigh)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if ttl := got.Header.Get("TTL"); ttl != "90" {
+		t.Errorf("TTL = %q, want 90", ttl)
+	}
+	if urgency := got.Header.Get("Urgency"); urgency != "high" {
+		t.Errorf("Urgency = %q, want high", urgency)
+	}
+	if encoding := got.Header.Get("Content-Encoding"); encoding != "aes128gcm" {
+		t.Errorf("Content-Encoding = %q, want aes128gcm", encoding)
+	}
+
+	authorization := got.Header.Get("Authorization")
+	signed, publicKey, ok := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	if !strings.HasPrefix(authorization, "vapid t=") || !ok || publicKey != config.VAPIDPublicKey {
+		t.Fatalf("Authorization = %q, want a vapid token and the VAPID public key", authorization)
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(signed, claims, func(*jwt.Token) (interface{}, error) {
+		return &config.VAPIDKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
+	if err != nil {
+		t.Fatalf("VAPID token does not verify: %v", err)
+	}
+	if claims["aud"] != server.URL || claims["sub"] != config.VAPIDSubject {
+		t.Errorf("VAPID claims = %v, want aud %s and sub %s", claims, server.URL, config.VAPIDSubject)
+	}
+
+	if len(store.updated) != 1 || len(store.deleted) != 0 {
+		t.Errorf("updated %v and deleted %v, want last_push_at recorded only", store.updated, store.deleted)
+	}
+}
+
+func TestSendResponses(t *testing.T) {
+	useTestVAPIDKey(t)
+	for _, tc := range []struct {
+		status    int
+		deleted   bool
+		permanent bool
+		ok        bool
+	}{
+		{status: http.StatusCreated, ok: true},
+		{status: http.StatusNotFound, deleted: true, permanent: true},
+		{status: http.StatusGone, deleted: true, permanent: true},
+		{status: http.StatusTooManyRequests},
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusBadRequest, permanent: true},
+		{status: http.StatusForbidden, permanent: true},
+	} {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			var (
+				store        = &fakeSubscriptions{}
+				subscription = models.PushSubscription{ID: "subscription-1", Endpoint: server.URL}
+			)
+			err := send(context.Background(), server.Client(), store, subscription, []byte("body"), time.Minute,
+				UrgencyNormal)
+			if (err == nil) != tc.ok || outbox.IsPermanent(err) != tc.permanent {
+				t.Errorf("send = %v, want ok %v and permanent %v", err, tc.ok, tc.permanent)
+			}
+
+			var want []interface{}
+			if tc.deleted {
+				want = []interface{}{bson.M{"_id": subscription.ID, "endpoint": subscription.Endpoint}}
+			}
+			if !reflect.DeepEqual(store.deleted, want) {
+				t.Errorf("deleted %v, want %v", store.deleted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package push
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// vapidTokenTTL is how long a VAPID token is valid, push services reject tokens valid for more than 24 hours
+const vapidTokenTTL = 12 * time.Hour
+
+// vapidAuthorization returns the Authorization header identifying this service to the push service of
+// endpoint, a token signed with config.VAPIDKey for the endpoint's origin, see RFC 8292
+func vapidAuthorization(endpoint string, now time.Time) (string, error) {
+	if config.VAPIDKey == nil {
+		return "", errors.New("no VAPID key is loaded")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// MapClaims, as push services expect aud to be a string and RegisteredClaims encodes it as an array
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"sub": config.VAPIDSubject,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+	})
+	signed, err := token.SignedString(config.VAPIDKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + config.VAPIDPublicKey, nil
+}
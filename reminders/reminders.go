@@ -0,0 +1,252 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/data"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/notify"
+)
+
+const (
+	transactionTypeExpense = "expense"
+
+	// dateLayout formats the dates of a reminder
+	dateLayout = "2 Jan 2006"
+)
+
+// SendDue notifies users about the bonus caps of their cards nearing or reaching config.CapWarningThreshold,
+// their cards' upcoming statement dates and annual fees, and their own budgets reaching
+// config.BudgetWarningThreshold. Each notification has a DedupKey for its reward cycle, date or budget, so
+// running SendDue again, on any number of instances, notifies about each at most once.
+func SendDue(ctx context.Context, collections *data.Collections, notifier *notify.Notifier) {
+	var (
+		log = slog.With("func", "SendDue")
+		now = time.Now()
+	)
+
+	filter := bson.M{
+		"verified":    true,
+		"deleted_at":  bson.M{"$exists": false},
+		"disabled_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"cards.miles.bonus_multiplier_cap": bson.M{"$gt": 0}},
+			{"cards.statement_day": bson.M{"$gt": 0}},
+			{"cards.annual_fee_due": bson.M{"$exists": true}},
+			{"budgets.0": bson.M{"$exists": true}},
+		},
+	}
+	opts := options.Find().SetProjection(bson.M{
+		"email": 1, "preferences": 1, "cards": 1, "transactions": 1, "budgets": 1,
+	})
+	cursor, err := collections.Users.Find(ctx, filter, opts)
+	if err != nil {
+		log.Error("Failed to find users with cards or budgets", "err", err)
+		return
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		log.Error("Failed to decode users with cards or budgets", "err", err)
+		return
+	}
+
+	for _, user := range users {
+		for _, msg := range due(user, now) {
+			if err = notifier.Notify(ctx, user, msg); err != nil {
+				log.Error("Failed to notify reminder", "err", err, "userID", user.ID, "type", msg.Type,
+					"dedupKey", msg.DedupKey)
+			}
+		}
+	}
+}
+
+// due returns the notifications user is due at now
+func due(user models.User, now time.Time) []notify.Message {
+	var (
+		prefs    = user.EffectivePreferences()
+		messages []notify.Message
+	)
+	for _, card := range user.Cards {
+		if msg, ok := capWarning(card, user.Transactions, prefs, now); ok {
+			messages = append(messages, msg)
+		}
+		messages = append(messages, feeReminders(card, prefs, now)...)
+	}
+	return append(messages, budgetAlerts(user.Budgets, user.Transactions, prefs, now)...)
+}
+
+// capWarning is the warning about card's bonus category spending in the current reward cycle reaching
+// config.CapWarningThreshold of its cap, or the cap itself. Each level warns once per cycle.
+func capWarning(card models.Card, transactions []models.Transaction, prefs models.Preferences,
+	now time.Time) (notify.Message, bool) {
+	limit := card.Miles.BonusMultiplierCap
+	if limit <= 0 {
+		return notify.Message{}, false
+	}
+
+	start, end := prefs.PeriodBounds(models.Monthly, now)
+	var spent float64
+	for _, transaction := range transactions {
+		category, known := models.ParseSpendCategory(transaction.Category)
+		if transaction.Type == transactionTypeExpense && transaction.CardID == card.ID && known &&
+			card.Miles.IsBonus(category) && within(transaction.Date, start, end) {
+			spent += transaction.Amount
+		}
+	}
+	percent := int(spent / limit * 100)
+	if percent < config.CapWarningThreshold {
+		return notify.Message{}, false
+	}
+
+	var (
+		reached        = spent >= limit
+		level          = "near"
+		title          = fmt.Sprintf("%s bonus cap almost reached", card.Name)
+		spentFormatted = fmt.Sprintf("%.2f", spent)
+		capFormatted   = fmt.Sprintf("%.2f", limit)
+	)
+	if reached {
+		level = "reached"
+		title = fmt.Sprintf("%s bonus cap reached", card.Name)
+	}
+	return notify.Message{
+		Type:  models.NotificationCapWarning,
+		Title: title,
+		Body: fmt.Sprintf("You have spent %s of the %s bonus cap on your %s this reward cycle (%d%%).",
+			spentFormatted, capFormatted, card.Name, percent),
+		Data:     map[string]string{"card_id": card.ID},
+		Template: email.TemplateCapWarning,
+		TemplateData: email.CapWarningData{
+			Card:    card.Name,
+			Spent:   spentFormatted,
+			Cap:     capFormatted,
+			Percent: percent,
+			Reached: reached,
+			ResetOn: end.Format(dateLayout),
+		},
+		DedupKey: fmt.Sprintf("%s:%s:%s:%s", models.NotificationCapWarning, level, card.ID, start.Format("2006-01")),
+	}, true
+}
+
+// feeReminders are the reminders about card's next statement date within config.StatementReminderDays and
+// its next annual fee within config.FeeReminderDays, each date reminded of once
+func feeReminders(card models.Card, prefs models.Preferences, now time.Time) []notify.Message {
+	var (
+		today    = prefs.StartOfDay(now)
+		messages []notify.Message
+	)
+	if card.StatementDay > 0 {
+		on := card.NextStatementDate(today)
+		if days := daysBetween(today, on); days <= config.StatementReminderDays {
+			messages = append(messages, feeReminder(card, true, on, days))
+		}
+	}
+	if card.AnnualFeeDue != nil {
+		on := card.NextAnnualFeeDue(prefs, today)
+		if days := daysBetween(today, on); days <= config.FeeReminderDays {
+			messages = append(messages, feeReminder(card, false, on, days))
+		}
+	}
+	return messages
+}
+
+// feeReminder is the reminder about card's statement date or annual fee on, days from today
+func feeReminder(card models.Card, statement bool, on time.Time, days int) notify.Message {
+	var (
+		data = email.FeeReminderData{Card: card.Name, Statement: statement, On: on.Format(dateLayout), Days: days}
+		kind = "statement"
+		msg  = notify.Message{
+			Type:     models.NotificationFeeReminder,
+			Title:    fmt.Sprintf("%s statement date coming up", card.Name),
+			Body:     fmt.Sprintf("The statement for your %s is cut on %s.", card.Name, data.On),
+			Template: email.TemplateFeeReminder,
+		}
+	)
+	if !statement {
+		kind = "annual_fee"
+		msg.Title = fmt.Sprintf("%s annual fee due soon", card.Name)
+		msg.Body = fmt.Sprintf("The annual fee on your %s is due on %s.", card.Name, data.On)
+		if card.AnnualFee > 0 {
+			data.Fee = fmt.Sprintf("%.2f", card.AnnualFee)
+			msg.Body = fmt.Sprintf("The annual fee of %s on your %s is due on %s.", data.Fee, card.Name, data.On)
+		}
+	}
+	msg.Data = map[string]string{"card_id": card.ID, "kind": kind, "date": on.Format(time.DateOnly)}
+	msg.TemplateData = data
+	msg.DedupKey = fmt.Sprintf("%s:%s:%s:%s", models.NotificationFeeReminder, kind, card.ID, on.Format(time.DateOnly))
+	return msg
+}
+
+// budgetAlerts are the alerts about the user's own budgets running at now that reached
+// config.BudgetWarningThreshold or went over their amount, each budget alerting once per level. Household
+// budgets alert as their transactions are added instead.
+func budgetAlerts(budgets []models.Budget, transactions []models.Transaction, prefs models.Preferences,
+	now time.Time) []notify.Message {
+	var messages []notify.Message
+	for _, budget := range budgets {
+		start := prefs.StartOfDay(budget.StartDate)
+		end := prefs.StartOfDay(budget.EndDate).AddDate(0, 0, 1)
+		if budget.Amount <= 0 || !within(now, start, end) {
+			continue
+		}
+		var spent float64
+		for _, transaction := range transactions {
+			if transaction.Type == transactionTypeExpense && transaction.Category == budget.Category &&
+				within(transaction.Date, start, end) {
+				spent += transaction.Amount
+			}
+		}
+		percent := int(spent / budget.Amount * 100)
+		if percent < config.BudgetWarningThreshold {
+			continue
+		}
+
+		var (
+			exceeded         = spent > budget.Amount
+			spentFormatted   = fmt.Sprintf("%.2f", spent)
+			amountFormatted  = fmt.Sprintf("%.2f", budget.Amount)
+			notificationType = models.NotificationBudgetWarning
+			title            = fmt.Sprintf("%s budget almost used", budget.Category)
+		)
+		if exceeded {
+			notificationType = models.NotificationBudgetExceeded
+			title = fmt.Sprintf("Over %s budget", budget.Category)
+		}
+		messages = append(messages, notify.Message{
+			Type:  notificationType,
+			Title: title,
+			Body: fmt.Sprintf("You have spent %s of your %s %s budget (%d%%).",
+				spentFormatted, amountFormatted, budget.Category, percent),
+			Data:     map[string]string{"budget_id": budget.ID.Hex()},
+			Template: email.TemplateBudgetAlert,
+			TemplateData: email.BudgetAlertData{
+				Category: budget.Category,
+				Spent:    spentFormatted,
+				Amount:   amountFormatted,
+				Percent:  percent,
+				Exceeded: exceeded,
+			},
+			DedupKey: string(notificationType) + ":" + budget.ID.Hex(),
+		})
+	}
+	return messages
+}
+
+// daysBetween is the number of calendar days from the start of one day to the start of another
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// within reports whether t is in [start, end)
+func within(t, start, end time.Time) bool {
+	return !t.Before(start) && t.Before(end)
+}
@@ -0,0 +1,103 @@
+package reminders
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/email"
+	"github.com/harisnkr/expense/models"
+)
+
+func testUser(now time.Time) models.User {
+	feeDue := time.Date(2023, time.June, 20, 0, 0, 0, 0, time.UTC)
+	budgetID, _ := primitive.ObjectIDFromHex("65f000000000000000000001")
+	return models.User{
+		ID:    "user-1",
+		Email: "ada@example.com",
+		Preferences: &models.Preferences{
+			Timezone: "UTC",
+			Locale:   "en",
+		},
+		Cards: []models.Card{{
+			ID:           "card-1",
+			Name:         "Travel Card",
+			AnnualFee:    196.2,
+			StatementDay: 12,
+			AnnualFeeDue: &feeDue,
+			Miles: models.Miles{
+				BonusMultiplier:    4,
+				BonusMultiplierCap: 1000,
+				LocalMultiplier:    1.2,
+				SpendCategories:    []models.SpendCategory{models.Dining},
+			},
+		}},
+		Transactions: []models.Transaction{
+			{Type: "expense", Category: "Dining", Amount: 600, CardID: "card-1", Date: now.AddDate(0, 0, -3)},
+			{Type: "expense", Category: "Dining", Amount: 250, CardID: "card-1", Date: now.AddDate(0, 0, -1)},
+			{Type: "expense", Category: "Gas", Amount: 400, CardID: "card-1", Date: now.AddDate(0, 0, -1)},
+			// last reward cycle, counts towards neither the cap nor the budget
+			{Type: "expense", Category: "Dining", Amount: 900, CardID: "card-1", Date: now.AddDate(0, -1, 0)},
+		},
+		Budgets: []models.Budget{{
+			ID:        budgetID,
+			Category:  "Dining",
+			Amount:    800,
+			StartDate: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, time.June, 30, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+}
+
+func TestDue(t *testing.T) {
+	config.CapWarningThreshold, config.BudgetWarningThreshold = 80, 80
+	config.FeeReminderDays, config.StatementReminderDays = 14, 3
+
+	now := time.Date(2024, time.June, 10, 9, 0, 0, 0, time.UTC)
+	messages := due(testUser(now), now)
+
+	want := map[string]models.NotificationType{
+		"cap_warning:near:card-1:2024-06":           models.NotificationCapWarning,
+		"fee_reminder:statement:card-1:2024-06-12":  models.NotificationFeeReminder,
+		"fee_reminder:annual_fee:card-1:2024-06-20": models.NotificationFeeReminder,
+		"budget_exceeded:65f000000000000000000001":  models.NotificationBudgetExceeded,
+	}
+	if len(messages) != len(want) {
+		t.Errorf("got %d messages, want %d", len(messages), len(want))
+	}
+	for _, msg := range messages {
+		if want[msg.DedupKey] != msg.Type {
+			t.Errorf("unexpected %s notification with DedupKey %q", msg.Type, msg.DedupKey)
+		}
+		for _, locale := range []string{"en", "zh"} {
+			rendered, err := email.Render(msg.Template, locale, msg.TemplateData)
+			if err != nil {
+				t.Errorf("render %s %s: %v", msg.Template, locale, err)
+				continue
+			}
+			if strings.Contains(rendered.Text+rendered.HTML, "<no value>") {
+				t.Errorf("%s %s renders a missing field: %s", msg.Template, locale, rendered.Text)
+			}
+		}
+	}
+}
+
+func TestDueAgainInTheNextPeriod(t *testing.T) {
+	config.CapWarningThreshold, config.BudgetWarningThreshold = 80, 80
+	config.FeeReminderDays, config.StatementReminderDays = 14, 3
+
+	// a month on, the same spending is a new reward cycle and the next statement date
+	now := time.Date(2024, time.July, 10, 9, 0, 0, 0, time.UTC)
+	keys := make(map[string]bool)
+	for _, msg := range due(testUser(now), now) {
+		keys[msg.DedupKey] = true
+	}
+	for _, key := range []string{"cap_warning:near:card-1:2024-07", "fee_reminder:statement:card-1:2024-07-12"} {
+		if !keys[key] {
+			t.Errorf("no notification with DedupKey %q, got %v", key, keys)
+		}
+	}
+}
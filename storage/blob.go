@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty or try to escape the store
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores opaque files by key, e.g. "profile-pictures/<userID>/<id>.jpg".
+// Keys are slash separated and their extension determines the content type they are served with.
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob under key, callers must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// ContentType returns the content type a blob is served with, based on its key's extension
+func ContentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// validKey reports whether key is a clean, relative, slash separated path inside the store
+func validKey(key string) bool {
+	return key != "" && key != "." && key != ".." && !path.IsAbs(key) && path.Clean(key) == key &&
+		!strings.HasPrefix(key, "../")
+}
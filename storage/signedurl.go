@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/harisnkr/expense/config"
+)
+
+// SignedURL returns a URL that serves the blob under key until config.BlobURLTTL has passed
+func SignedURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	expires := time.Now().Add(config.BlobURLTTL).Unix()
+	query := url.Values{
+		"exp": {strconv.FormatInt(expires, 10)},
+		"sig": {sign(key, expires)},
+	}
+	return config.APIBaseURL + "/blobs/" + key + "?" + query.Encode()
+}
+
+// VerifySignedURL reports whether exp and sig from a SignedURL are authentic for key and not expired
+func VerifySignedURL(key, exp, sig string) bool {
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sign(key, expires)), []byte(sig))
+}
+
+func sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, config.BlobURLSecret)
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
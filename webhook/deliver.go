@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/harisnkr/expense/common"
+	"github.com/harisnkr/expense/config"
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+const (
+	userAgent = "MoneyFly-Webhooks/1.0"
+
+	// maxResponseExcerpt is how much of a response body is kept in the delivery log
+	maxResponseExcerpt = 1024
+
+	// bookkeepingTime is left to a DeliveryHandler after its request times out, to log the attempt
+	bookkeepingTime = 10 * time.Second
+)
+
+// HandlerTimeout is the most a DeliveryHandler may run for
+func HandlerTimeout() time.Duration {
+	return config.WebhookTimeout + bookkeepingTime
+}
+
+// ErrInsecureURL is returned for webhook URLs that are not https, see config.WebhookAllowInsecure
+var ErrInsecureURL = errors.New("webhook URL must use https")
+
+// ValidateURL checks raw is an absolute https URL, or http if config.WebhookAllowInsecure
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("webhook URL must be absolute")
+	}
+	if u.Scheme == "https" || (u.Scheme == "http" && config.WebhookAllowInsecure) {
+		return nil
+	}
+	return ErrInsecureURL
+}
+
+// NewClient returns the client deliveries are sent with. It does not follow redirects, and refuses to
+// connect to loopback, private and link-local addresses unless config.WebhookAllowInsecure. Checking
+// when connecting, rather than when the URL is registered, also covers hosts whose DNS changes later.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: config.WebhookTimeout, Control: controlDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.WebhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func controlDial(network, address string, conn syscall.RawConn) error {
+	if config.WebhookAllowInsecure {
+		return nil
+	}
+	return common.RefusePrivateAddresses(network, address, conn)
+}
+
+// DeliveryHandler sends models.OutboxWebhook entries with client. Every attempt is logged on the delivery,
+// and a webhook whose attempts fail config.WebhookMaxFailures times in a row is disabled.
+func DeliveryHandler(webhooks, deliveries *mongo.Collection, client *http.Client) outbox.Handler {
+	return func(ctx context.Context, entry models.OutboxEntry) error {
+		var delivery models.WebhookDelivery
+		err := deliveries.FindOne(ctx, bson.M{"_id": entry.WebhookDeliveryID}).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return outbox.Permanent(errors.New("webhook delivery no longer exists"))
+		}
+		if err != nil {
+			return err
+		}
+
+		var webhook models.Webhook
+		err = webhooks.FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return giveUp(ctx, deliveries, delivery, nil, errors.New("webhook was deleted"))
+		}
+		if err != nil {
+			return err
+		}
+		if !webhook.Enabled {
+			return giveUp(ctx, deliveries, delivery, nil, errors.New("webhook is disabled"))
+		}
+
+		attempt := send(ctx, client, webhook, delivery)
+		if attempt.Error == "" {
+			return succeed(ctx, webhooks, deliveries, webhook, delivery, attempt)
+		}
+		cause := errors.New(attempt.Error)
+
+		disabled, err := recordFailure(ctx, webhooks, webhook)
+		if err != nil {
+			return err
+		}
+		if disabled {
+			return giveUp(ctx, deliveries, delivery, &attempt, cause)
+		}
+		// the outbox dead-letters the entry once it is out of attempts, so the delivery has failed for good
+		if entry.Attempts >= config.OutboxMaxAttempts {
+			return giveUp(ctx, deliveries, delivery, &attempt, cause)
+		}
+		if _, err = deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID},
+			bson.M{"$push": bson.M{"attempts": attempt}}); err != nil {
+			return err
+		}
+		return cause
+	}
+}
+
+// send makes one attempt of delivery, signed with a fresh timestamp
+func send(ctx context.Context, client *http.Client, webhook models.Webhook,
+	delivery models.WebhookDelivery) (attempt models.WebhookAttempt) {
+	var (
+		start = time.Now()
+		body  = []byte(delivery.Payload)
+	)
+	attempt.At = start
+	defer func() { attempt.Duration = time.Since(start) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, start.Unix(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+	attempt.StatusCode = resp.StatusCode
+	attempt.Response = string(excerpt)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("endpoint responded %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// succeed marks delivery succeeded and resets the webhook's run of failures
+func succeed(ctx context.Context, webhooks, deliveries *mongo.Collection, webhook models.Webhook,
+	delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	now := time.Now()
+	_, err := deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{
+		"$set":  bson.M{"status": models.WebhookDeliverySucceeded, "completed_at": now},
+		"$push": bson.M{"attempts": attempt},
+	})
+	if err != nil {
+		return err
+	}
+	if webhook.ConsecutiveFailures > 0 {
+		_, err = webhooks.UpdateOne(ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": bson.M{"consecutive_failures": 0}})
+	}
+	return err
+}
+
+// recordFailure counts a failed attempt against webhook, disabling it once config.WebhookMaxFailures
+// attempts in a row have failed. It reports whether the webhook is now disabled.
+func recordFailure(ctx context.Context, webhooks *mongo.Collection, webhook models.Webhook) (bool, error) {
+	var updated models.Webhook
+	err := webhooks.FindOneAndUpdate(ctx, bson.M{"_id": webhook.ID},
+		bson.M{"$inc": bson.M{"consecutive_failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		return false, err
+	}
+	if updated.ConsecutiveFailures < config.WebhookMaxFailures {
+		return false, nil
+	}
+
+	now := time.Now()
+	_, err = webhooks.UpdateOne(ctx, bson.M{"_id": webhook.ID, "enabled": true}, bson.M{"$set": bson.M{
+		"enabled":         false,
+		"disabled_reason": fmt.Sprintf("%d delivery attempts in a row failed", updated.ConsecutiveFailures),
+		"disabled_at":     now,
+		"updated_at":      now,
+	}})
+	return err == nil, err
+}
+
+// giveUp marks delivery failed, logging attempt if one was made, and stops the outbox retrying it
+func giveUp(ctx context.Context, deliveries *mongo.Collection, delivery models.WebhookDelivery,
+	attempt *models.WebhookAttempt, cause error) error {
+	update := bson.M{"$set": bson.M{"status": models.WebhookDeliveryFailed, "completed_at": time.Now()}}
+	if attempt != nil {
+		update["$push"] = bson.M{"attempts": *attempt}
+	}
+	if _, err := deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update); err != nil {
+		return err
+	}
+	return outbox.Permanent(cause)
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/harisnkr/expense/models"
+	"github.com/harisnkr/expense/outbox"
+)
+
+// Request headers of every delivery. Receivers verify SignatureHeader, see Sign, and should reject
+// requests whose TimestampHeader is more than a few minutes old to prevent replays.
+const (
+	EventHeader     = "X-MoneyFly-Event"
+	DeliveryHeader  = "X-MoneyFly-Delivery"
+	TimestampHeader = "X-MoneyFly-Timestamp"
+	SignatureHeader = "X-MoneyFly-Signature"
+)
+
+// secretPrefix marks webhook secrets, so leaked ones are easy to recognise
+const secretPrefix = "whsec_"
+
+// Payload is the JSON body of a delivery. ID identifies the event, it is the same in every delivery of it,
+// including redeliveries, so receivers can ignore events they already handled.
+type Payload struct {
+	ID        string              `json:"id"`
+	Type      models.WebhookEvent `json:"type"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Data      interface{}         `json:"data"`
+}
+
+// Publisher sends domain events to the webhooks subscribed to them, through the outbox
+type Publisher struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+	outbox     *outbox.Outbox
+}
+
+// New returns a Publisher for the webhooks in the given collection, logging deliveries in deliveries
+func New(webhooks, deliveries *mongo.Collection, box *outbox.Outbox) *Publisher {
+	return &Publisher{webhooks: webhooks, deliveries: deliveries, outbox: box}
+}
+
+// Publish sends event with data to the enabled webhooks subscribed to it: those of the users the event is
+// about and every global webhook. Each webhook is attempted, the errors of those that failed are returned
+// together.
+func (p *Publisher) Publish(ctx context.Context, event models.WebhookEvent, userIDs []string,
+	data interface{}) error {
+	id := uuid.New().String()
+	payload, err := json.Marshal(Payload{
+		ID:        id,
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := p.webhooks.Find(ctx, bson.M{
+		"enabled": true,
+		"events":  event,
+		"$or": []bson.M{
+			{"global": true},
+			{"global": false, "owner_id": bson.M{"$in": userIDs}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	var webhooks []models.Webhook
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		delivery := models.WebhookDelivery{
+			ID:        uuid.New().String(),
+			WebhookID: webhook.ID,
+			EventID:   id,
+			Event:     event,
+			Payload:   string(payload),
+		}
+		if _, err = p.deliver(ctx, delivery); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redeliver sends the payload of delivery again as a new delivery
+func (p *Publisher) Redeliver(ctx context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	return p.deliver(ctx, models.WebhookDelivery{
+		ID:           uuid.New().String(),
+		WebhookID:    delivery.WebhookID,
+		EventID:      delivery.EventID,
+		Event:        delivery.Event,
+		Payload:      delivery.Payload,
+		RedeliveryOf: delivery.ID,
+	})
+}
+
+// deliver logs delivery as pending and enqueues it for the DeliveryHandler
+func (p *Publisher) deliver(ctx context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = []models.WebhookAttempt{}
+	delivery.CreatedAt = time.Now()
+	if _, err := p.deliveries.InsertOne(ctx, delivery); err != nil {
+		return delivery, err
+	}
+
+	err := p.outbox.Enqueue(ctx, models.OutboxEntry{Kind: models.OutboxWebhook, WebhookDeliveryID: delivery.ID})
+	if err != nil {
+		_, _ = p.deliveries.DeleteOne(ctx, bson.M{"_id": delivery.ID})
+	}
+	return delivery, err
+}
+
+// NewSecret returns a random secret for signing a webhook's deliveries
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Sign returns the SignatureHeader of a delivery: "v1=" followed by the hex HMAC-SHA256, keyed with the
+// webhook's secret, of the TimestampHeader value, a full stop and the request body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}